@@ -0,0 +1,266 @@
+// Package analysis builds control-flow graphs of CHIP-8 ROMs.
+//
+// A Graph splits the code found by the disassembler into basic blocks and
+// groups them into subroutines, one for the entry point and one for every
+// CALL target, linked by a call graph. Jumps through JPV (Bnnn) depend on V0
+// at runtime and are kept as unresolved edges.
+package analysis
+
+import (
+	"slices"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// EdgeKind describes how control passes from one block to another.
+type EdgeKind string
+
+const (
+	// Execution runs off the end of the block into the next
+	EdgeNext EdgeKind = "next"
+
+	// A skip instruction jumped over the next instruction
+	EdgeSkip EdgeKind = "skip"
+
+	// An unconditional jump
+	EdgeJump EdgeKind = "jump"
+
+	// Execution resumes after a subroutine call returns
+	EdgeReturn EdgeKind = "return"
+)
+
+// Edge is a control-flow edge between two blocks.
+type Edge struct {
+	To   uint16   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// Block is a basic block: a straight run of instructions that is only
+// entered at the top and only left at the bottom.
+type Block struct {
+	// Address of the first instruction, and the address just past the last
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+
+	Instructions []disasm.Instruction `json:"-"`
+
+	// Control-flow successors within the same subroutine
+	Succs []Edge `json:"successors"`
+
+	// Whether the block ends with a JPV whose target is unknown
+	Unresolved bool `json:"unresolved"`
+
+	// Times the block was entered, when annotated with Annotate
+	Count uint64 `json:"count"`
+}
+
+// Last returns the address of the block's final instruction.
+func (b *Block) Last() uint16 {
+	return b.End - 2
+}
+
+// Call is a CALL instruction, linking two subroutines.
+type Call struct {
+	Site   uint16 `json:"site"`
+	Caller uint16 `json:"caller"`
+	Callee uint16 `json:"callee"`
+}
+
+// Subroutine is the set of blocks reachable from an entry point without
+// following calls.
+type Subroutine struct {
+	Entry  uint16   `json:"entry"`
+	Name   string   `json:"name"`
+	Blocks []uint16 `json:"blocks"`
+}
+
+// Graph is the control-flow graph and call graph of a ROM.
+type Graph struct {
+	Program     *disasm.Program
+	Blocks      []*Block
+	Subroutines []*Subroutine
+	Calls       []Call
+
+	byStart map[uint16]*Block
+}
+
+// Build disassembles rom and builds its graphs.
+func Build(rom []byte) *Graph {
+	g := &Graph{
+		Program: disasm.Disassemble(rom),
+		byStart: map[uint16]*Block{},
+	}
+	p := g.Program
+
+	code := func(addr uint16) bool {
+		return p.Kind(addr) == disasm.Code
+	}
+
+	// Every branch target and every instruction after a branch starts a block
+	leaders := map[uint16]bool{}
+	if code(emulator.ROM_START) {
+		leaders[emulator.ROM_START] = true
+	}
+	for off := range p.ROM {
+		addr := emulator.ROM_START + uint16(off)
+		in, ok := p.Instruction(addr)
+		if !ok {
+			continue
+		}
+
+		switch in.Flow() {
+		case disasm.FlowJump, disasm.FlowCall:
+			if code(in.NNN) {
+				leaders[in.NNN] = true
+			}
+		}
+
+		switch in.Flow() {
+		case disasm.FlowSkip:
+			leaders[addr+4] = code(addr + 4)
+			fallthrough
+		case disasm.FlowJump, disasm.FlowCall, disasm.FlowReturn, disasm.FlowIndirect:
+			leaders[addr+2] = code(addr + 2)
+		}
+	}
+
+	starts := make([]uint16, 0, len(leaders))
+	for addr, ok := range leaders {
+		if ok {
+			starts = append(starts, addr)
+		}
+	}
+	slices.Sort(starts)
+
+	for _, start := range starts {
+		g.addBlock(start, leaders)
+	}
+
+	g.buildSubroutines()
+
+	return g
+}
+
+func (g *Graph) addBlock(start uint16, leaders map[uint16]bool) {
+	p := g.Program
+	b := &Block{Start: start, Succs: []Edge{}}
+
+	edge := func(to uint16, kind EdgeKind) {
+		if p.Kind(to) == disasm.Code {
+			b.Succs = append(b.Succs, Edge{to, kind})
+		}
+	}
+
+	addr := start
+	for {
+		in, ok := p.Instruction(addr)
+		if !ok {
+			break
+		}
+		b.Instructions = append(b.Instructions, in)
+		addr += 2
+
+		flow := in.Flow()
+		if flow == disasm.FlowNext && !leaders[addr] {
+			continue
+		}
+
+		switch flow {
+		case disasm.FlowNext:
+			edge(addr, EdgeNext)
+		case disasm.FlowSkip:
+			edge(addr, EdgeNext)
+			edge(addr+2, EdgeSkip)
+		case disasm.FlowJump:
+			edge(in.NNN, EdgeJump)
+		case disasm.FlowCall:
+			edge(addr, EdgeReturn)
+		case disasm.FlowIndirect:
+			b.Unresolved = true
+		}
+		break
+	}
+
+	b.End = addr
+	g.Blocks = append(g.Blocks, b)
+	g.byStart[start] = b
+}
+
+func (g *Graph) buildSubroutines() {
+	entries := []uint16{}
+	if _, ok := g.byStart[emulator.ROM_START]; ok {
+		entries = append(entries, emulator.ROM_START)
+	}
+
+	for _, b := range g.Blocks {
+		in := b.Instructions[len(b.Instructions)-1]
+		if in.Flow() == disasm.FlowCall {
+			if _, ok := g.byStart[in.NNN]; ok && !slices.Contains(entries, in.NNN) {
+				entries = append(entries, in.NNN)
+			}
+		}
+	}
+	slices.Sort(entries)
+
+	for _, entry := range entries {
+		sub := &Subroutine{Entry: entry, Name: g.Program.Label(entry)}
+
+		seen := map[uint16]bool{entry: true}
+		work := []uint16{entry}
+		for len(work) > 0 {
+			b := g.byStart[work[len(work)-1]]
+			work = work[:len(work)-1]
+			sub.Blocks = append(sub.Blocks, b.Start)
+
+			in := b.Instructions[len(b.Instructions)-1]
+			if in.Flow() == disasm.FlowCall {
+				g.Calls = append(g.Calls, Call{Site: b.Last(), Caller: entry, Callee: in.NNN})
+			}
+
+			for _, e := range b.Succs {
+				if !seen[e.To] {
+					seen[e.To] = true
+					work = append(work, e.To)
+				}
+			}
+		}
+
+		slices.Sort(sub.Blocks)
+		g.Subroutines = append(g.Subroutines, sub)
+	}
+
+	slices.SortFunc(g.Calls, func(a, b Call) int {
+		if a.Site != b.Site {
+			return int(a.Site) - int(b.Site)
+		}
+		return int(a.Caller) - int(b.Caller)
+	})
+	g.Calls = slices.Compact(g.Calls)
+}
+
+// Block returns the block starting at addr.
+func (g *Graph) Block(addr uint16) (*Block, bool) {
+	b, ok := g.byStart[addr]
+	return b, ok
+}
+
+// Unresolved returns the addresses of indirect jumps whose targets are unknown.
+func (g *Graph) Unresolved() []uint16 {
+	var sites []uint16
+	for _, b := range g.Blocks {
+		if b.Unresolved {
+			sites = append(sites, b.Last())
+		}
+	}
+
+	return sites
+}
+
+// Annotate sets the execution count of each block from per-address
+// instruction counts, such as those collected by Record.
+func (g *Graph) Annotate(counts map[uint16]uint64) {
+	for _, b := range g.Blocks {
+		b.Count = counts[b.Start]
+	}
+}
@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// main calls sub twice, then loops forever on a key check
+var program = []byte{
+	0x22, 0x0C, // 200: call sub
+	0x22, 0x0C, // 202: call sub
+	0xE0, 0x9E, // 204: if v0 -key then
+	0x12, 0x04, // 206:   jump 204
+	0xB2, 0x04, // 208: jump0 204
+	0x00, 0x00, // 20A: data
+	0x70, 0x01, // 20C: sub: v0 += 1
+	0x00, 0xEE, // 20E: return
+}
+
+func TestBlocks(t *testing.T) {
+	assert := assert.New(t)
+	g := Build(program)
+
+	var starts []uint16
+	for _, b := range g.Blocks {
+		starts = append(starts, b.Start)
+	}
+	assert.Equal([]uint16{0x200, 0x202, 0x204, 0x206, 0x208, 0x20C}, starts)
+
+	b, ok := g.Block(0x204)
+	assert.True(ok)
+	assert.Equal([]Edge{{0x206, EdgeNext}, {0x208, EdgeSkip}}, b.Succs)
+
+	b, _ = g.Block(0x200)
+	assert.Equal([]Edge{{0x202, EdgeReturn}}, b.Succs)
+
+	b, _ = g.Block(0x20C)
+	assert.Equal(uint16(0x210), b.End)
+	assert.Len(b.Instructions, 2)
+	assert.Empty(b.Succs)
+}
+
+func TestSubroutinesAndCalls(t *testing.T) {
+	assert := assert.New(t)
+	g := Build(program)
+
+	assert.Len(g.Subroutines, 2)
+	assert.Equal("main", g.Subroutines[0].Name)
+	assert.Equal([]uint16{0x200, 0x202, 0x204, 0x206, 0x208}, g.Subroutines[0].Blocks)
+	assert.Equal("sub_20C", g.Subroutines[1].Name)
+	assert.Equal([]uint16{0x20C}, g.Subroutines[1].Blocks)
+
+	assert.Equal([]Call{
+		{Site: 0x200, Caller: 0x200, Callee: 0x20C},
+		{Site: 0x202, Caller: 0x200, Callee: 0x20C},
+	}, g.Calls)
+}
+
+func TestUnresolved(t *testing.T) {
+	assert := assert.New(t)
+	g := Build(program)
+
+	assert.Equal([]uint16{0x208}, g.Unresolved())
+}
+
+func TestAnnotate(t *testing.T) {
+	assert := assert.New(t)
+	g := Build(program)
+
+	g.Annotate(map[uint16]uint64{0x200: 1, 0x20C: 2, 0x20E: 2})
+
+	b, _ := g.Block(0x20C)
+	assert.Equal(uint64(2), b.Count)
+	b, _ = g.Block(0x204)
+	assert.Equal(uint64(0), b.Count)
+}
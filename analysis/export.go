@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+func (g *Graph) name(addr uint16) string {
+	if name := g.Program.Label(addr); name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("0x%03X", addr)
+}
+
+// heat returns a fill colour for a block, from white for blocks that never
+// ran to red for the hottest block.
+func heat(count, hottest uint64) string {
+	if hottest == 0 || count == 0 {
+		return "#ffffff"
+	}
+
+	level := math.Log1p(float64(count)) / math.Log1p(float64(hottest))
+	shade := 255 - int(level*191)
+
+	return fmt.Sprintf("#ff%02x%02x", shade, shade)
+}
+
+// WriteDOT writes the basic blocks as a Graphviz digraph, with one cluster
+// per subroutine. Blocks shared by several subroutines are drawn in the
+// first one.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var hottest uint64
+	for _, b := range g.Blocks {
+		hottest = max(hottest, b.Count)
+	}
+
+	var out strings.Builder
+	out.WriteString("digraph cfg {\n")
+	out.WriteString("\tnode [shape=box fontname=\"monospace\" style=filled fillcolor=\"#ffffff\"];\n")
+
+	drawn := map[uint16]bool{}
+	for _, sub := range g.Subroutines {
+		fmt.Fprintf(&out, "\tsubgraph cluster_%03X {\n", sub.Entry)
+		fmt.Fprintf(&out, "\t\tlabel=%q;\n", g.name(sub.Entry))
+
+		for _, start := range sub.Blocks {
+			if drawn[start] {
+				continue
+			}
+			drawn[start] = true
+
+			b, _ := g.Block(start)
+			var label strings.Builder
+			if name := g.Program.Label(b.Start); name != "" {
+				label.WriteString(name + ":\\l")
+			}
+			for idx, in := range b.Instructions {
+				fmt.Fprintf(&label, "0x%03X  %s\\l", b.Start+uint16(idx*2), in.Format(g.Program.Label))
+			}
+			if hottest > 0 {
+				fmt.Fprintf(&label, "executed %d times\\l", b.Count)
+			}
+
+			fmt.Fprintf(&out, "\t\tb%03X [label=\"%s\" fillcolor=%q];\n", b.Start, label.String(), heat(b.Count, hottest))
+		}
+
+		out.WriteString("\t}\n")
+	}
+
+	for _, b := range g.Blocks {
+		for _, e := range b.Succs {
+			fmt.Fprintf(&out, "\tb%03X -> b%03X [label=%q];\n", b.Start, e.To, e.Kind)
+		}
+
+		if b.Unresolved {
+			fmt.Fprintf(&out, "\tunresolved%03X [label=\"?\" shape=circle];\n", b.Last())
+			fmt.Fprintf(&out, "\tb%03X -> unresolved%03X [label=\"jump0\" style=dashed];\n", b.Start, b.Last())
+		}
+	}
+
+	for _, b := range g.Blocks {
+		in := b.Instructions[len(b.Instructions)-1]
+		if _, ok := g.Block(in.NNN); ok && in.Mnemonic == "CALL" {
+			fmt.Fprintf(&out, "\tb%03X -> b%03X [label=\"call\" style=dotted constraint=false];\n", b.Start, in.NNN)
+		}
+	}
+
+	out.WriteString("}\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// WriteCallsDOT writes the subroutine call graph as a Graphviz digraph.
+func (g *Graph) WriteCallsDOT(w io.Writer) error {
+	var out strings.Builder
+	out.WriteString("digraph calls {\n")
+	out.WriteString("\tnode [shape=box fontname=\"monospace\"];\n")
+
+	for _, sub := range g.Subroutines {
+		fmt.Fprintf(&out, "\ts%03X [label=%q];\n", sub.Entry, g.name(sub.Entry))
+	}
+
+	type pair struct{ caller, callee uint16 }
+	sites := map[pair]int{}
+	var pairs []pair
+	for _, call := range g.Calls {
+		key := pair{call.Caller, call.Callee}
+		if sites[key] == 0 {
+			pairs = append(pairs, key)
+		}
+		sites[key]++
+	}
+
+	for _, key := range pairs {
+		fmt.Fprintf(&out, "\ts%03X -> s%03X", key.caller, key.callee)
+		if n := sites[key]; n > 1 {
+			fmt.Fprintf(&out, " [label=\"%d sites\"]", n)
+		}
+		out.WriteString(";\n")
+	}
+
+	out.WriteString("}\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+type jsonBlock struct {
+	*Block
+	Label        string   `json:"label,omitempty"`
+	Instructions []string `json:"instructions"`
+}
+
+type jsonGraph struct {
+	Blocks      []jsonBlock   `json:"blocks"`
+	Subroutines []*Subroutine `json:"subroutines"`
+	Calls       []Call        `json:"calls"`
+	Unresolved  []uint16      `json:"unresolved"`
+}
+
+// WriteJSON writes the blocks, subroutines and call graph as a JSON document.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	out := jsonGraph{
+		Blocks:      []jsonBlock{},
+		Subroutines: g.Subroutines,
+		Calls:       g.Calls,
+		Unresolved:  g.Unresolved(),
+	}
+
+	if out.Subroutines == nil {
+		out.Subroutines = []*Subroutine{}
+	}
+	if out.Calls == nil {
+		out.Calls = []Call{}
+	}
+	if out.Unresolved == nil {
+		out.Unresolved = []uint16{}
+	}
+
+	for _, b := range g.Blocks {
+		jb := jsonBlock{Block: b, Label: g.Program.Label(b.Start)}
+		for _, in := range b.Instructions {
+			jb.Instructions = append(jb.Instructions, in.Format(g.Program.Label))
+		}
+
+		out.Blocks = append(out.Blocks, jb)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDOT(t *testing.T) {
+	assert := assert.New(t)
+	g := Build(program)
+	g.Annotate(map[uint16]uint64{0x20C: 2})
+
+	var out bytes.Buffer
+	assert.NoError(g.WriteDOT(&out))
+	dot := out.String()
+
+	assert.Contains(dot, "digraph cfg {")
+	assert.Contains(dot, "subgraph cluster_20C {")
+	assert.Contains(dot, `b204 -> b208 [label="skip"];`)
+	assert.Contains(dot, `b200 -> b20C [label="call" style=dotted constraint=false];`)
+	assert.Contains(dot, `b208 -> unresolved208 [label="jump0" style=dashed];`)
+	assert.Contains(dot, `executed 2 times\l`)
+}
+
+func TestWriteCallsDOT(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(Build(program).WriteCallsDOT(&out))
+
+	assert.Equal("digraph calls {\n"+
+		"\tnode [shape=box fontname=\"monospace\"];\n"+
+		"\ts200 [label=\"main\"];\n"+
+		"\ts20C [label=\"sub_20C\"];\n"+
+		"\ts200 -> s20C [label=\"2 sites\"];\n"+
+		"}\n", out.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(Build(program).WriteJSON(&out))
+
+	var doc struct {
+		Blocks []struct {
+			Start        uint16
+			Label        string
+			Instructions []string
+			Successors   []Edge
+		}
+		Subroutines []Subroutine
+		Calls       []Call
+		Unresolved  []uint16
+	}
+	assert.NoError(json.Unmarshal(out.Bytes(), &doc))
+
+	assert.Len(doc.Blocks, 6)
+	assert.Equal("main", doc.Blocks[0].Label)
+	assert.Equal([]string{"CALL sub_20C"}, doc.Blocks[0].Instructions)
+	assert.Equal([]Edge{{0x202, EdgeReturn}}, doc.Blocks[0].Successors)
+	assert.Len(doc.Subroutines, 2)
+	assert.Len(doc.Calls, 2)
+	assert.Equal([]uint16{0x208}, doc.Unresolved)
+}
@@ -0,0 +1,22 @@
+package analysis
+
+import (
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Record runs emu for the given number of frames and counts how many times
+// the instruction at each address executed.
+func Record(emu emulator.Emulator, frames int) (map[uint16]uint64, error) {
+	counts := map[uint16]uint64{}
+	emu.OnExecute(func(pc, _ uint16) {
+		counts[pc]++
+	})
+
+	for range frames {
+		if err := emu.RunFrame(); err != nil {
+			return counts, err
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,23 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	// v0 += 1 ; jump 200
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader([]byte{0x70, 0x01, 0x12, 0x00}), emulator.DEFAULT_CLOCK_SPEED)
+	assert.NoError(err)
+
+	counts, err := Record(emu, 2)
+	assert.NoError(err)
+	assert.Equal(uint64(2*emulator.DEFAULT_IPF), counts[0x200]+counts[0x202])
+	assert.Equal(counts[0x200], counts[0x202])
+}
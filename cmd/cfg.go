@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aricodes-oss/gr8/analysis"
+
+	"github.com/spf13/cobra"
+)
+
+var cfgFormat string
+var cfgOutput string
+var cfgCalls bool
+var cfgFrames int
+
+// cfgCmd represents the cfg command
+var cfgCmd = &cobra.Command{
+	Use:   "cfg rom",
+	Short: "Export a ROM's control-flow graph",
+	Long: `Export a ROM's basic blocks and subroutine call graph as Graphviz DOT or
+JSON. DOT output shows the basic blocks, or the call graph with --calls.
+
+Indirect jumps through JPV (Bnnn) can't be followed statically and are shown
+as unresolved edges. With --frames the ROM is run headlessly first, and each
+block is annotated with how many times it executed.
+
+  gr8 cfg rom.ch8 | dot -Tsvg > rom.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[0])
+		if err != nil {
+			return err
+		}
+
+		g := analysis.Build(rom)
+
+		// The format is checked before running the ROM or truncating the
+		// output file
+		var write func(w io.Writer) error
+		switch {
+		case cfgFormat == "json":
+			write = g.WriteJSON
+		case cfgFormat == "dot" && cfgCalls:
+			write = g.WriteCallsDOT
+		case cfgFormat == "dot":
+			write = g.WriteDOT
+		default:
+			return fmt.Errorf("unknown format %q", cfgFormat)
+		}
+
+		if cfgFrames > 0 {
+			emu, err := newEmulator(rom)
+			if err != nil {
+				return err
+			}
+
+			counts, err := analysis.Record(emu, cfgFrames)
+			if err != nil {
+				return err
+			}
+			g.Annotate(counts)
+		}
+
+		var out io.Writer = os.Stdout
+		if cfgOutput != "" {
+			fd, err := os.Create(cfgOutput)
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			out = fd
+		}
+
+		return write(out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cfgCmd)
+
+	cfgCmd.Flags().StringVarP(&cfgFormat, "format", "f", "dot", "output format (dot or json)")
+	cfgCmd.Flags().StringVarP(&cfgOutput, "output", "o", "", "write to a file instead of stdout")
+	cfgCmd.Flags().BoolVar(&cfgCalls, "calls", false, "export the call graph instead of basic blocks")
+	cfgCmd.Flags().IntVar(&cfgFrames, "frames", 0, "run the ROM for this many frames and annotate blocks with execution counts")
+}
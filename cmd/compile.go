@@ -0,0 +1,81 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/octo"
+
+	"github.com/spf13/cobra"
+)
+
+var compileOutput string
+var compileSymbols string
+
+// compileCmd represents the compile command
+var compileCmd = &cobra.Command{
+	Use:   "compile source.8o",
+	Short: "Compile Octo source into a ROM",
+	Long: `Compile Octo source into a ROM. The ROM is written next to the source with a
+.ch8 extension unless -o is given.
+
+With --symbols, the labels and the source line of every instruction are also
+written as JSON, for source-level debugging with gr8 dap.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := args[0]
+		prog, err := octo.CompileFile(src)
+		if err != nil {
+			return err
+		}
+
+		out := compileOutput
+		if out == "" {
+			out = strings.TrimSuffix(src, filepath.Ext(src)) + ".ch8"
+		}
+		if err := os.WriteFile(out, prog.ROM, 0o644); err != nil {
+			return err
+		}
+
+		if compileSymbols == "" {
+			return nil
+		}
+
+		fd, err := os.Create(compileSymbols)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+
+		return prog.WriteSymbols(fd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compileCmd)
+
+	compileCmd.Flags().StringVarP(&compileOutput, "output", "o", "", "write the ROM to this file")
+	compileCmd.Flags().StringVar(&compileSymbols, "symbols", "", "write a symbol file for debuggers")
+}
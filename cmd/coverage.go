@@ -0,0 +1,145 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aricodes-oss/gr8/coverage"
+	"github.com/aricodes-oss/gr8/movie"
+
+	"github.com/spf13/cobra"
+)
+
+var coverageFrames int
+var coverageMovies []string
+var coverageListing string
+var coverageHTML string
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage rom",
+	Short: "Record which bytes of a ROM are executed, read and written",
+	Long: `Run a ROM headlessly and record which bytes of memory it executes, reads as
+data (DRW, Fx65) and writes (Fx33, Fx55). Bytes that are executed and also
+written at runtime are flagged as self-modifying code.
+
+Without --movie the ROM runs once with no input. Each --movie runs the ROM
+again from the start with that input, for --frames frames or until the movie
+ends, and the coverage of all runs is combined:
+
+  gr8 coverage --movie menu.txt --movie level1.txt --html rom.html rom.ch8
+
+A movie has a line per frame that changes the keypad, with the frame number
+followed by keys pressed (+) or released (-), in hexadecimal:
+
+  60 +5
+  64 -5`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[0])
+		if err != nil {
+			return err
+		}
+
+		// A run without input stands in for the movies
+		movies := []*movie.Movie{{}}
+		if len(coverageMovies) > 0 {
+			movies = nil
+		}
+		for _, path := range coverageMovies {
+			m, err := movie.Load(path)
+			if err != nil {
+				return err
+			}
+			movies = append(movies, m)
+		}
+
+		total := &coverage.Coverage{}
+		for idx, m := range movies {
+			emu, err := newEmulator(rom)
+			if err != nil {
+				return err
+			}
+
+			c := coverage.Attach(emu)
+			frames := max(coverageFrames, int(m.Frames()))
+			if err := m.Play(emu, frames); err != nil {
+				if len(coverageMovies) > 0 {
+					return fmt.Errorf("%s: %w", coverageMovies[idx], err)
+				}
+				return err
+			}
+			total.Merge(c)
+		}
+
+		if err := total.WriteSummary(os.Stdout, rom); err != nil {
+			return err
+		}
+
+		if coverageListing != "" {
+			if err := writeFile(coverageListing, func(fd *os.File) error {
+				return total.WriteListing(fd, rom)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if coverageHTML != "" {
+			return writeFile(coverageHTML, func(fd *os.File) error {
+				return total.WriteHTML(fd, filepath.Base(args[0]), rom)
+			})
+		}
+
+		return nil
+	},
+}
+
+// writeFile creates path and writes it with write, or writes to stdout if
+// path is "-".
+func writeFile(path string, write func(fd *os.File) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(fd)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(coverageCmd)
+
+	coverageCmd.Flags().IntVar(&coverageFrames, "frames", 600, "number of frames to run the ROM for")
+	coverageCmd.Flags().StringArrayVarP(&coverageMovies, "movie", "m", nil, "input movie to play back, can be repeated")
+	coverageCmd.Flags().StringVarP(&coverageListing, "listing", "l", "", "write an annotated listing to a file, or - for stdout")
+	coverageCmd.Flags().StringVar(&coverageHTML, "html", "", "write an HTML heatmap to a file")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aricodes-oss/gr8/crash"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+var crashDir string
+
+// reportCrash writes a crash report for emu if err is a fault, and prints a
+// summary of it. Other errors are returned unchanged.
+func reportCrash(name string, rom []byte, emu emulator.Emulator, err error) error {
+	var fault *emulator.Fault
+	if !errors.As(err, &fault) {
+		return err
+	}
+
+	r := crash.New(emu, name, rom, err)
+	r.Config["clock"] = emulator.DEFAULT_CLOCK_SPEED.String()
+	r.Config["ipf"] = strconv.Itoa(emulator.DEFAULT_IPF)
+	r.Config["scale"] = strconv.Itoa(Scale)
+	r.Config["quirks"] = quirksPreset
+	r.Config["engine"] = engineName
+	if traceFile != "" {
+		r.Config["trace"] = traceFile
+	}
+
+	fmt.Fprint(os.Stderr, r.Summary())
+
+	path, saveErr := r.Save(crashDir)
+	if saveErr != nil {
+		return fmt.Errorf("writing crash report: %w", saveErr)
+	}
+	fmt.Fprintf(os.Stderr, "crash report written to %s\n", path)
+
+	return err
+}
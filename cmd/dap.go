@@ -0,0 +1,113 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/aricodes-oss/gr8/debugger"
+	"github.com/aricodes-oss/gr8/emulator"
+
+	"github.com/spf13/cobra"
+)
+
+var dapListen string
+
+// dapCmd represents the dap command
+var dapCmd = &cobra.Command{
+	Use:   "dap",
+	Short: "Serve the Debug Adapter Protocol",
+	Long: `Serve the Debug Adapter Protocol, so that editors such as VS Code can debug
+CHIP-8 programs. By default the adapter talks over stdin and stdout; with
+--listen it accepts TCP connections instead, one session per connection.
+
+Launch requests take the program to run, which can be a ROM or an Octo source
+file. Source breakpoints in a ROM need the symbol file written by
+gr8 compile --symbols:
+
+  {
+    "type": "gr8",
+    "request": "launch",
+    "program": "${workspaceFolder}/game.ch8",
+    "symbols": "${workspaceFolder}/game.sym",
+    "cwd": "${workspaceFolder}",
+    "stopOnEntry": true
+  }
+
+Programs run with --quirks and --engine unless the launch request sets
+"quirks" or "engine".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		quirks, err := emulator.ParseQuirks(quirksPreset)
+		if err != nil {
+			return err
+		}
+
+		engine, err := emulator.ParseEngine(engineName)
+		if err != nil {
+			return err
+		}
+
+		// Sessions start with the emulator flags
+		serve := func(r io.Reader, w io.Writer) error {
+			s := debugger.NewDAPSession(r, w)
+			s.SetQuirks(quirks)
+			s.SetEngine(engine)
+
+			return s.Serve()
+		}
+
+		if dapListen == "" {
+			return serve(os.Stdin, os.Stdout)
+		}
+
+		ln, err := net.Listen("tcp", dapListen)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		fmt.Fprintf(os.Stderr, "listening on %s\n", ln.Addr())
+
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				defer conn.Close()
+				if err := serve(conn, conn); err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", conn.RemoteAddr(), err)
+				}
+			}()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dapCmd)
+
+	dapCmd.Flags().StringVarP(&dapListen, "listen", "l", "", "listen for TCP connections on this address, such as :4711")
+}
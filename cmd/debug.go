@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aricodes-oss/gr8/debugger"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/spf13/cobra"
+)
+
+var debugBreakpoints []string
+var debugWatchpoints []string
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug rom",
+	Short: "Debug a ROM in the terminal",
+	Long: `Run a ROM under an interactive terminal debugger. The display is mirrored
+in the terminal, so no window is needed.
+
+The ROM starts paused. Use c to continue, space to pause, s to step, n to
+step over a call, o to step out of a subroutine, b to toggle a
+breakpoint and w to toggle a watchpoint. While paused, r edits a register (v3=0x12) and m edits memory
+(0x300=FF 00). Tab switches the keyboard to the CHIP-8 keypad.
+
+  gr8 debug rom.ch8 --break 2a4
+  gr8 debug rom.ch8 --break "if pc == 0x2a4 && v3 > 5 && frame > 600"
+  gr8 debug rom.ch8 --break "2a4 log score={v3}"
+  gr8 debug rom.ch8 --watch "300-301 w" --watch "i r"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[0])
+		if err != nil {
+			return err
+		}
+
+		emu, err := newEmulator(rom)
+		if err != nil {
+			return err
+		}
+
+		dbg := debugger.New(emu)
+		for _, s := range debugBreakpoints {
+			bp, err := debugger.ParseBreakpoint(s)
+			if err != nil {
+				return fmt.Errorf("breakpoint %q: %w", s, err)
+			}
+			dbg.AddBreakpoint(bp)
+		}
+		for _, s := range debugWatchpoints {
+			w, err := debugger.ParseWatchpoint(s)
+			if err != nil {
+				return err
+			}
+			dbg.Watch(w)
+		}
+
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return err
+		}
+
+		return debugger.NewTUI(dbg, screen).Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+
+	debugCmd.Flags().StringArrayVarP(&debugBreakpoints, "break", "b", nil, "set a breakpoint such as \"2a4\" or \"2a4 if v3 > 5\" (repeatable)")
+	debugCmd.Flags().StringArrayVarP(&debugWatchpoints, "watch", "w", nil, "set a watchpoint such as \"300-30F rw\" or \"v3 w\" (repeatable)")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aricodes-oss/gr8/disasm"
+
+	"github.com/spf13/cobra"
+)
+
+var disasmFormat string
+var disasmOutput string
+
+// disasmCmd represents the disasm command
+var disasmCmd = &cobra.Command{
+	Use:   "disasm rom",
+	Short: "Disassemble a ROM",
+	Long: `Disassemble a ROM by tracing the code reachable from its entry point.
+
+Bytes that no traced path reaches are printed as data, and labels are
+synthesized for branch, call and I-load targets. Formats are:
+
+  listing  addresses, raw bytes and mnemonics
+  source   Octo source that reassembles to the same ROM
+  json     machine-readable output for other tools`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[0])
+		if err != nil {
+			return err
+		}
+
+		// The format is checked before the output file is truncated
+		prog := disasm.Disassemble(rom)
+		var write func(w io.Writer) error
+		switch disasmFormat {
+		case "listing":
+			write = prog.WriteListing
+		case "source":
+			write = prog.WriteSource
+		case "json":
+			write = prog.WriteJSON
+		default:
+			return fmt.Errorf("unknown format %q", disasmFormat)
+		}
+
+		var out io.Writer = os.Stdout
+		if disasmOutput != "" {
+			fd, err := os.Create(disasmOutput)
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			out = fd
+		}
+
+		return write(out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(disasmCmd)
+
+	disasmCmd.Flags().StringVarP(&disasmFormat, "format", "f", "listing", "output format (listing, source or json)")
+	disasmCmd.Flags().StringVarP(&disasmOutput, "output", "o", "", "write to a file instead of stdout")
+}
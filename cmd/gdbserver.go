@@ -0,0 +1,85 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/aricodes-oss/gr8/debugger"
+
+	"github.com/spf13/cobra"
+)
+
+// gdbserverCmd represents the gdbserver command
+var gdbserverCmd = &cobra.Command{
+	Use:   "gdbserver address rom",
+	Short: "Debug a ROM over GDB's remote serial protocol",
+	Long: `Serve a ROM over GDB's remote serial protocol, so that GDB and other RSP
+clients can attach to it. The ROM starts paused.
+
+Registers are numbered V0-VF (0-15), then I, PC, SP, DT and ST (16-20). SP is
+the stack depth. Software and hardware breakpoints, single stepping and
+continuing are supported. Clients are served one at a time, and the machine
+keeps its state between connections.
+
+  gr8 gdbserver :1234 rom.ch8
+  gdb -ex 'target remote :1234'`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[1])
+		if err != nil {
+			return err
+		}
+
+		emu, err := newEmulator(rom)
+		if err != nil {
+			return err
+		}
+		dbg := debugger.New(emu)
+
+		ln, err := net.Listen("tcp", args[0])
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		fmt.Fprintf(os.Stderr, "listening on %s\n", ln.Addr())
+
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+
+			err = debugger.NewGDBSession(dbg, conn, conn).Serve()
+			conn.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", conn.RemoteAddr(), err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gdbserverCmd)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+)
+
+var headlessFrames int
+
+// headless reports whether the ROM runs without a window.
+func headless() bool {
+	return headlessFrames > 0 || len(screenshotAt) > 0
+}
+
+// runHeadless runs a ROM without a window, as fast as it can, for --frames
+// frames or until the last of --screenshot-at, saving screenshots after
+// those frames.
+func runHeadless(emu emulator.Emulator, opts screenshot.Options) error {
+	shots := slices.Sorted(slices.Values(screenshotAt))
+	last := headlessFrames
+	for _, frame := range shots {
+		if frame < 0 {
+			return fmt.Errorf("invalid screenshot frame %d", frame)
+		}
+		last = max(last, frame)
+	}
+
+	for {
+		for len(shots) > 0 && emu.FrameCount() >= uint64(shots[0]) {
+			path, err := saveScreenshot(emu.Display(), emu.FrameCount(), opts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "screenshot of frame %d saved to %s\n", shots[0], path)
+			shots = shots[1:]
+		}
+
+		if emu.FrameCount() >= uint64(last) {
+			return nil
+		}
+		if err := emu.RunFrame(); err != nil {
+			return err
+		}
+	}
+}
+
+func init() {
+	rootCmd.Flags().IntVar(&headlessFrames, "frames", 0, "run headlessly instead of in a window, for this many frames")
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"golang.org/x/image/font/basicfont"
+)
+
+var showHUD bool
+
+// Key that shows and hides the HUD
+const HUD_KEY = pixel.KeyF1
+
+// How often the HUD's rates are recalculated
+const HUD_SAMPLE_INTERVAL = 500 * time.Millisecond
+
+// Layout of the CHIP-8 keypad, as printed on the COSMAC VIP
+var hudKeypad = [4][4]uint8{
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+var (
+	hudBackground = color.RGBA{0, 0, 0, 0xC0}
+	hudKeyUp      = color.RGBA{0x40, 0x40, 0x40, 0xFF}
+	hudKeyDown    = color.RGBA{0x30, 0xC0, 0x60, 0xFF}
+)
+
+// hud draws emulator statistics over the display. It only reads the stats
+// the emulator publishes, so it's safe to draw while the emulator runs.
+type hud struct {
+	visible bool
+
+	emu   emulator.Emulator
+	atlas *text.Atlas
+	txt   *text.Text
+	imd   *imdraw.IMDraw
+
+	// Rates calculated at the last sample
+	sampled   time.Time
+	frames    uint64
+	cycles    uint64
+	skipped   uint64
+	fps       float64
+	ipf       float64
+	idle      float64
+	lastDraw  time.Time
+	frameTime time.Duration
+}
+
+func newHUD(emu emulator.Emulator) *hud {
+	atlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+
+	return &hud{
+		visible: showHUD,
+		emu:     emu,
+		atlas:   atlas,
+		txt:     text.New(pixel.ZV, atlas),
+		imd:     imdraw.New(nil),
+	}
+}
+
+// update recalculates the rates shown by the HUD from the emulator's stats.
+// It is called once for every frame the window draws while the HUD is shown.
+func (h *hud) update(now time.Time, stats emulator.Stats) {
+	if !h.lastDraw.IsZero() {
+		// Smooth the frame time so that it can be read
+		h.frameTime = (h.frameTime*7 + now.Sub(h.lastDraw)) / 8
+	}
+	h.lastDraw = now
+
+	elapsed := now.Sub(h.sampled)
+	if elapsed < HUD_SAMPLE_INTERVAL {
+		return
+	}
+
+	frames, cycles, skipped := stats.Frame, stats.Cycles, stats.Skipped
+	if !h.sampled.IsZero() {
+		h.fps = float64(frames-h.frames) / elapsed.Seconds()
+		h.ipf, h.idle = 0, 0
+		if frames > h.frames {
+			h.ipf = float64(cycles-h.cycles) / float64(frames-h.frames)
+		}
+		if cycles > h.cycles {
+			h.idle = 100 * float64(skipped-h.skipped) / float64(cycles-h.cycles)
+		}
+	}
+	h.sampled, h.frames, h.cycles, h.skipped = now, frames, cycles, skipped
+}
+
+// draw draws the HUD in the top left corner of bounds. Everything it shows
+// comes from the stats the emulator goroutine publishes.
+func (h *hud) draw(t pixel.Target, bounds pixel.Rect) {
+	if !h.visible {
+		// Rates start over when the HUD is shown again
+		h.sampled, h.lastDraw = time.Time{}, time.Time{}
+		return
+	}
+
+	stats := h.emu.Stats()
+	h.update(time.Now(), stats)
+	s := stats.State
+
+	h.txt.Clear()
+	fmt.Fprintf(h.txt, "FPS %5.1f  IPF %5.1f  idle %3.0f%%  frame %4.1fms\n", h.fps, h.ipf, h.idle, float64(h.frameTime.Microseconds())/1000)
+	fmt.Fprintf(h.txt, "PC %03X  I %03X  SP %d\n", s.PC, s.I, len(s.Stack))
+	for row := range 2 {
+		for col := range 8 {
+			reg := row*8 + col
+			fmt.Fprintf(h.txt, "V%X %02X ", reg, s.V[reg])
+		}
+		fmt.Fprintln(h.txt)
+	}
+	fmt.Fprintf(h.txt, "DT %02X  ST %02X\n", s.DelayTimer, s.SoundTimer)
+
+	const margin, padding = 8.0, 6.0
+	lineHeight := h.atlas.LineHeight()
+	keySize := lineHeight + 2
+	keypadSize := 4 * keySize
+
+	textBounds := h.txt.Bounds()
+	width := textBounds.W() + padding + keypadSize
+	height := max(textBounds.H(), keypadSize)
+
+	// The panel hangs from the top left corner, and text grows downwards from
+	// its first baseline
+	top := bounds.Max.Y - margin
+	left := bounds.Min.X + margin
+	panel := pixel.R(left, top-height-2*padding, left+width+2*padding, top)
+
+	h.imd.Clear()
+	h.imd.Color = hudBackground
+	h.imd.Push(panel.Min, panel.Max)
+	h.imd.Rectangle(0)
+
+	keypadLeft := panel.Max.X - padding - keypadSize
+	for row, keys := range hudKeypad {
+		for col, key := range keys {
+			corner := pixel.V(keypadLeft+float64(col)*keySize, top-padding-float64(row+1)*keySize)
+			h.imd.Color = hudKeyUp
+			if stats.Keys&(1<<key) != 0 {
+				h.imd.Color = hudKeyDown
+			}
+			h.imd.Push(corner.Add(pixel.V(1, 1)), corner.Add(pixel.V(keySize-1, keySize-1)))
+			h.imd.Rectangle(0)
+		}
+	}
+	h.imd.Draw(t)
+
+	origin := pixel.V(left+padding, top-padding-h.atlas.Ascent())
+	h.txt.Draw(t, pixel.IM.Moved(origin.Sub(h.txt.Orig)))
+
+	// Key labels, centered in their squares
+	for row, keys := range hudKeypad {
+		for col, key := range keys {
+			h.txt.Clear()
+			fmt.Fprintf(h.txt, "%X", key)
+			center := pixel.V(
+				keypadLeft+(float64(col)+0.5)*keySize,
+				top-padding-(float64(row)+0.5)*keySize,
+			)
+			offset := h.txt.Bounds().Center()
+			h.txt.Draw(t, pixel.IM.Moved(center.Sub(offset)))
+		}
+	}
+}
+
+// toggle shows or hides the HUD.
+func (h *hud) toggle() {
+	h.visible = !h.visible
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/profile"
+
+	"github.com/spf13/cobra"
+)
+
+var profileFrames int
+var profileTop int
+var profileOutput string
+var profilePprof string
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile rom",
+	Short: "Measure where a ROM spends its cycles",
+	Long: `Run a ROM headlessly and report where it spends its cycles.
+
+The report counts executions per address and per instruction, attributes
+inclusive and exclusive cycles to subroutines by following CALL and RET, and
+measures the share of cycles spent busy-waiting on the delay timer (Fx07) or
+for a key (Fx0A). Subroutines are named after the disassembler's labels.
+
+With --pprof the profile is also written in pprof's format:
+
+  gr8 profile --frames 3600 --pprof rom.pb.gz rom.ch8
+  go tool pprof -http :8080 rom.pb.gz`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rom, err := readROM(args[0])
+		if err != nil {
+			return err
+		}
+
+		emu, err := newEmulator(rom)
+		if err != nil {
+			return err
+		}
+
+		p, err := profile.Run(emu, profileFrames)
+		if err != nil {
+			return err
+		}
+		p.Name = disasm.Disassemble(rom).Label
+
+		if profilePprof != "" {
+			fd, err := os.Create(profilePprof)
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+
+			if err := p.WritePprof(fd, filepath.Base(args[0])); err != nil {
+				return err
+			}
+		}
+
+		var out io.Writer = os.Stdout
+		if profileOutput != "" {
+			fd, err := os.Create(profileOutput)
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			out = fd
+		}
+
+		return p.WriteText(out, profileTop)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+
+	profileCmd.Flags().IntVar(&profileFrames, "frames", 600, "number of frames to run the ROM for")
+	profileCmd.Flags().IntVar(&profileTop, "top", 20, "number of addresses to list, or 0 for all")
+	profileCmd.Flags().StringVarP(&profileOutput, "output", "o", "", "write the report to a file instead of stdout")
+	profileCmd.Flags().StringVar(&profilePprof, "pprof", "", "also write a gzipped pprof profile to this file")
+}
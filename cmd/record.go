@@ -0,0 +1,173 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/record"
+	"github.com/aricodes-oss/gr8/screenshot"
+
+	"github.com/gopxl/pixel/v2"
+)
+
+var recordGIF string
+var recordStream string
+
+// Key that starts and stops recording a GIF
+const RECORD_KEY = pixel.KeyF10
+
+// recorder records every frame a ROM runs, as a GIF started and stopped with
+// RECORD_KEY or from the start with --record-gif, and as raw video on stdout
+// with --record-stream. Frames come in on the emulator's goroutine.
+type recorder struct {
+	mu   sync.Mutex
+	opts screenshot.Options
+
+	// The GIF being recorded, if any, and the file it's saved to
+	gif  *record.GIF
+	path string
+
+	// The last frame recorded, so that GIFs are named after the frame they
+	// start in
+	last uint64
+
+	// The first error writing the stream ends it
+	stream    *record.Stream
+	streamErr error
+}
+
+// newRecorder attaches a recorder to emu, which records as the flags say.
+func newRecorder(emu emulator.Emulator, opts screenshot.Options) (*recorder, error) {
+	r := &recorder{opts: opts}
+	if recordStream != "" {
+		format, err := record.ParseFormat(recordStream)
+		if err != nil {
+			return nil, err
+		}
+		r.stream = record.NewStream(os.Stdout, format, opts)
+	}
+	if recordGIF != "" {
+		r.start(recordGIF)
+	}
+
+	emu.OnFrame(r.frame)
+	return r, nil
+}
+
+// frame records the display at the end of a frame.
+func (r *recorder) frame(frame uint64, display emulator.Bitplane) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.last = frame
+	if r.gif != nil {
+		r.gif.Add(frame, display)
+	}
+	if r.stream != nil && r.streamErr == nil {
+		r.streamErr = r.stream.Write(display)
+	}
+}
+
+// recording reports whether a GIF is being recorded.
+func (r *recorder) recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.gif != nil
+}
+
+// start records a GIF from the next frame, to be saved to path or, if it's
+// empty, to --screenshot-dir named after the ROM and the time. It returns the
+// path.
+func (r *recorder) start(path string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if path == "" {
+		path = filepath.Join(screenshotDir, screenshot.Filename(r.opts.ROM, time.Now(), r.last+1, ".gif"))
+	}
+
+	r.gif, r.path = record.NewGIF(r.opts), path
+	return path
+}
+
+// stop stops recording and saves the GIF, and returns its path.
+func (r *recorder) stop() (string, error) {
+	r.mu.Lock()
+	gif, path := r.gif, r.path
+	r.gif = nil
+	r.mu.Unlock()
+
+	if gif == nil {
+		return "", nil
+	}
+	return path, writeFile(path, func(fd *os.File) error {
+		return gif.Encode(fd)
+	})
+}
+
+// toggle starts or stops recording a GIF from the window, and says so.
+// Failing to save one isn't fatal.
+func (r *recorder) toggle() {
+	if !r.recording() {
+		fmt.Fprintf(os.Stderr, "recording to %s\n", r.start(""))
+		return
+	}
+
+	path, err := r.stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't save recording: %s\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "recording saved to %s\n", path)
+}
+
+// close saves the GIF being recorded, if any, and returns any error writing
+// the stream.
+func (r *recorder) close() error {
+	if r.recording() {
+		path, err := r.stop()
+		if err != nil {
+			return fmt.Errorf("saving recording: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "recording saved to %s\n", path)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.streamErr != nil {
+		return fmt.Errorf("writing stream: %w", r.streamErr)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&recordGIF, "record-gif", "", "record a GIF to this file from the start, saved on exit or with F10, which also starts recordings")
+	rootCmd.Flags().StringVar(&recordStream, "record-stream", "", "write every frame to stdout as raw video for ffmpeg, in ppm or y4m format")
+}
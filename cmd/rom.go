@@ -0,0 +1,72 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/octo"
+)
+
+var quirksPreset, engineName string
+
+// readROM returns the ROM image at path. Octo source files (.8o) are compiled
+// in memory.
+func readROM(path string) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(path), ".8o") {
+		prog, err := octo.CompileFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return prog.ROM, nil
+	}
+
+	return os.ReadFile(path)
+}
+
+// newEmulator loads rom into an emulator running with the quirks preset
+// chosen by --quirks and the engine chosen by --engine.
+func newEmulator(rom []byte) (emulator.Debuggable, error) {
+	quirks, err := emulator.ParseQuirks(quirksPreset)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := emulator.ParseEngine(engineName)
+	if err != nil {
+		return nil, err
+	}
+
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		return nil, err
+	}
+	emu.SetQuirks(quirks)
+	emu.SetEngine(engine)
+
+	return emu, nil
+}
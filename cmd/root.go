@@ -22,13 +22,15 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
 
 	"github.com/spf13/cobra"
 
-	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/backends/opengl"
 )
 
@@ -43,58 +45,125 @@ var rootCmd = &cobra.Command{
 	// has an action associated with it:
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// The last session's window is restored unless a size is given on the
+		// command line
+		restore := !cmd.Flags().Changed("scale")
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+
 		file := args[0]
-		chip8, err := emulator.NewEmulator(file, emulator.DEFAULT_CLOCK_SPEED)
+		rom, err := readROM(file)
 		if err != nil {
 			return err
 		}
 
-		cfg := opengl.WindowConfig{
-			Title: file,
-			Bounds: pixel.R(
-				0,
-				0,
-				float64(emulator.DISPLAY_WIDTH*Scale),
-				float64(emulator.DISPLAY_HEIGHT*Scale),
-			),
-			VSync: true,
+		chip8, err := newEmulator(rom)
+		if err != nil {
+			return err
 		}
 
-		win, err := opengl.NewWindow(cfg)
+		shots, err := screenshotOptions(file, rom)
 		if err != nil {
 			return err
 		}
 
-		go chip8.Run()
-		defer chip8.Stop()
+		rec, err := newRecorder(chip8, shots)
+		if err != nil {
+			return err
+		}
 
-		for !win.Closed() {
-			for code, key := range emulator.Keybinds {
-				if win.JustPressed(key) {
-					chip8.Press(uint8(code))
-				} else if win.JustReleased(key) {
-					chip8.Release(uint8(code))
-				}
-			}
-			frame := chip8.Frame()
-			if frame == nil {
-				win.Update()
-				continue
-			}
+		finishTrace, err := startTrace(chip8)
+		if err != nil {
+			return err
+		}
+
+		if headless() {
+			err = runHeadless(chip8, shots)
+		} else {
+			// The window needs the main thread, but only the root command opens
+			// one, so subcommands still work without a display
+			opengl.Run(func() {
+				err = runWindow(file, chip8, restore, shots, rec)
+			})
+		}
 
-			pictureData := pixel.PictureDataFromImage(frame)
-			texture := pixel.NewSprite(pictureData, pictureData.Rect)
-			texture.Draw(win, pixel.IM.Scaled(pixel.ZV, float64(Scale)).Moved(win.Bounds().Center()))
+		if recErr := rec.close(); err == nil {
+			err = recErr
+		}
 
-			win.Update()
+		if traceErr := finishTrace(); err == nil {
+			err = traceErr
 		}
 
-		return nil
+		// Faults are explained by the crash report, not the usage
+		if err != nil {
+			cmd.SilenceUsage = true
+		}
+		return reportCrash(file, rom, chip8, err)
 	},
 }
 
+// runWindow shows the emulator in a window until it is closed.
+func runWindow(title string, chip8 emulator.Emulator, restore bool, shots screenshot.Options, rec *recorder) error {
+	win, err := newWindow(title, restore)
+	if err != nil {
+		return err
+	}
+	defer win.close()
+	win.palette = shots.Palette
+
+	hud := newHUD(chip8)
+
+	go chip8.Run()
+	defer chip8.Stop()
+
+	for !win.Closed() {
+		if err := chip8.Err(); err != nil {
+			return err
+		}
+
+		if win.JustPressed(HUD_KEY) {
+			hud.toggle()
+		}
+		if win.JustPressed(FULLSCREEN_KEY) {
+			win.toggleFullscreen()
+		}
+		if win.JustPressed(SCREENSHOT_KEY) {
+			// The emulator draws on its own goroutine, so screenshots are of
+			// the frame on screen
+			if path, err := saveScreenshot(win.display, win.frame, shots); err != nil {
+				fmt.Fprintf(os.Stderr, "couldn't save screenshot: %s\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "screenshot saved to %s\n", path)
+			}
+		}
+		if win.JustPressed(RECORD_KEY) {
+			rec.toggle()
+		}
+
+		for code, key := range emulator.Keybinds {
+			if win.JustPressed(key) {
+				chip8.Press(uint8(code))
+			} else if win.JustReleased(key) {
+				chip8.Release(uint8(code))
+			}
+		}
+		win.drawFrame(chip8.Frame(), chip8.FrameNumber())
+		hud.draw(win, win.Bounds())
+
+		win.Update()
+	}
+
+	return nil
+}
+
 func init() {
-	rootCmd.Flags().IntVarP(&Scale, "scale", "s", 16, "screen scaling factor")
+	rootCmd.PersistentFlags().StringVar(&engineName, "engine", "interpreter", "execution engine: interpreter, or blocks to compile basic blocks")
+	rootCmd.PersistentFlags().StringVar(&quirksPreset, "quirks", "gr8", "platform quirks to emulate: "+strings.Join(emulator.QuirksPresetNames(), ", "))
+	rootCmd.Flags().IntVarP(&Scale, "scale", "s", 16, "initial screen scaling factor, instead of the last window size")
+	rootCmd.Flags().BoolVar(&showHUD, "hud", false, "show the HUD on start, toggled with F1")
+	rootCmd.Flags().StringVar(&crashDir, "crash-dir", ".", "directory to write crash reports to when a ROM faults")
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+	"github.com/aricodes-oss/gr8/upscale"
+
+	"github.com/gopxl/pixel/v2"
+)
+
+var screenshotAt []int
+var screenshotDir string
+var exportScale int
+var exportFilter string
+
+// Key that saves a screenshot
+const SCREENSHOT_KEY = pixel.KeyF12
+
+// screenshotOptions returns the options screenshots of a ROM are taken with.
+func screenshotOptions(name string, rom []byte) (screenshot.Options, error) {
+	filter, err := upscale.Parse(exportFilter)
+	if err != nil {
+		return screenshot.Options{}, err
+	}
+	palette, err := screenshot.ParsePalette(paletteName)
+	if err != nil {
+		return screenshot.Options{}, err
+	}
+
+	hash := sha256.Sum256(rom)
+	return screenshot.Options{
+		Scale:   exportScale,
+		Filter:  filter,
+		Palette: palette,
+		ROM:     filepath.Base(name),
+		Hash:    hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// saveScreenshot writes a screenshot of a display taken in the given frame to
+// --screenshot-dir, and returns its path.
+func saveScreenshot(display emulator.Bitplane, frame uint64, opts screenshot.Options) (string, error) {
+	opts.Frame = frame
+	return screenshot.Save(screenshotDir, display, opts)
+}
+
+func init() {
+	rootCmd.Flags().IntSliceVar(&screenshotAt, "screenshot-at", nil, "run headlessly instead of in a window, saving screenshots after these frames, such as 60,600")
+	rootCmd.Flags().StringVar(&screenshotDir, "screenshot-dir", ".", "directory to save screenshots and recordings to")
+	rootCmd.Flags().IntVar(&exportScale, "export-scale", 8, "times to enlarge screenshots and recordings")
+	rootCmd.Flags().StringVar(&exportFilter, "export-filter", "nearest", "filter to enlarge screenshots and recordings with: nearest, epx or xbr")
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gopxl/glhf/v2"
+	"github.com/gopxl/mainthread/v2"
+)
+
+var shaderName string
+var shaderIntensity float64
+
+// Built-in post-processing shaders, named after their files
+//
+//go:embed shaders/*.glsl
+var builtinShaders embed.FS
+
+// shaderNames returns the names of the built-in shaders.
+func shaderNames() []string {
+	entries, _ := builtinShaders.ReadDir("shaders")
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".glsl"))
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// loadShader returns the source of the built-in shader with the given name,
+// or of the GLSL file at that path. There's no shader for "none".
+func loadShader(name string) (string, error) {
+	if name == "" || name == "none" {
+		return "", nil
+	}
+
+	if src, err := builtinShaders.ReadFile(path.Join("shaders", name+".glsl")); err == nil {
+		return string(src), nil
+	}
+
+	src, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("unknown shader %q, expected none, %s or a GLSL file", name, strings.Join(shaderNames(), ", "))
+	}
+	return string(src), err
+}
+
+// shaderVertexSource has the same outputs as the vertex shader canvases draw
+// with, for checking fragment shaders against.
+const shaderVertexSource = `
+#version 330 core
+
+in vec2 aPosition;
+
+out vec4  vColor;
+out vec2  vTexCoords;
+out float vIntensity;
+out vec2  vPosition;
+out vec4  vClipRect;
+
+void main() {
+	gl_Position = vec4(aPosition, 0.0, 1.0);
+
+	vColor = vec4(1.0);
+	vTexCoords = aPosition;
+	vIntensity = 1.0;
+	vPosition = aPosition;
+	vClipRect = vec4(0.0);
+}
+`
+
+// checkShader compiles and links a fragment shader, so that mistakes in it
+// are reported as errors. Canvases panic on the main thread instead, which
+// can't be recovered from.
+func checkShader(name, src string) error {
+	return mainthread.CallErr(func() error {
+		_, err := glhf.NewShader(glhf.AttrFormat{{Name: "aPosition", Type: glhf.Vec2}}, nil, shaderVertexSource, src)
+		if err != nil {
+			return fmt.Errorf("shader %s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&shaderName, "shader", "none", "post-processing shader: none, "+strings.Join(shaderNames(), ", ")+", or the path to a GLSL file")
+	rootCmd.Flags().Float64Var(&shaderIntensity, "shader-intensity", 1, "strength of the shader's effect, from 0 for none to 1")
+}
@@ -0,0 +1,45 @@
+#version 330 core
+
+// A curved CRT: the picture bulges out from the middle and darkens towards
+// the corners, lit pixels bloom into their neighbours, and the rows are split
+// into scanlines.
+
+in vec2 vTexCoords;
+
+out vec4 fragColor;
+
+uniform vec4 uTexBounds;
+uniform sampler2D uTexture;
+uniform float uIntensity;
+
+void main() {
+	vec2 uv = (vTexCoords - uTexBounds.xy) / uTexBounds.zw;
+
+	// Sampling further out the further from the middle shrinks the edges of
+	// the picture, as if seen through curved glass
+	vec2 centered = uv * 2.0 - 1.0;
+	centered *= 1.0 + 0.1 * uIntensity * dot(centered.yx, centered.yx);
+	uv = centered * 0.5 + 0.5;
+	if (any(lessThan(uv, vec2(0.0))) || any(greaterThan(uv, vec2(1.0)))) {
+		fragColor = vec4(0.0, 0.0, 0.0, 1.0);
+		return;
+	}
+
+	vec3 color = texture(uTexture, uv).rgb;
+
+	vec2 texel = 1.0 / uTexBounds.zw;
+	vec3 glow = vec3(0.0);
+	for (int dy = -2; dy <= 2; dy++) {
+		for (int dx = -2; dx <= 2; dx++) {
+			vec2 offset = vec2(dx, dy);
+			glow += texture(uTexture, uv + offset * texel * 0.75).rgb * exp(-dot(offset, offset) / 2.0);
+		}
+	}
+	glow /= 6.0;
+
+	float edge = abs(fract(uv.y * uTexBounds.w) - 0.5) * 2.0;
+	float scanline = 1.0 - 0.5 * uIntensity * edge * edge;
+	float vignette = 1.0 - 0.25 * uIntensity * dot(centered, centered);
+
+	fragColor = vec4((color * scanline + glow * 0.5 * uIntensity) * vignette, 1.0);
+}
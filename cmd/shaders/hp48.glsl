@@ -0,0 +1,34 @@
+#version 330 core
+
+// The greenish reflective LCD of the HP 48 calculators, which ran the first
+// SUPER-CHIP: lit pixels are dark cells on pale green, casting a faint shadow
+// on the glass behind them.
+
+in vec2 vTexCoords;
+
+out vec4 fragColor;
+
+uniform vec4 uTexBounds;
+uniform sampler2D uTexture;
+uniform float uIntensity;
+
+const vec3 PAPER = vec3(0.69, 0.75, 0.62);
+const vec3 INK = vec3(0.16, 0.20, 0.17);
+
+float brightness(vec2 coords) {
+	vec3 color = texture(uTexture, (coords - uTexBounds.xy) / uTexBounds.zw).rgb;
+	return dot(color, vec3(0.299, 0.587, 0.114));
+}
+
+void main() {
+	vec3 color = texture(uTexture, (vTexCoords - uTexBounds.xy) / uTexBounds.zw).rgb;
+	float lit = brightness(vTexCoords);
+	float shadow = brightness(vTexCoords + vec2(-0.2, 0.2));
+
+	vec2 cell = fract(vTexCoords);
+	vec2 edge = min(cell, 1.0 - cell);
+	vec2 grid = smoothstep(0.03, 0.1, edge);
+
+	vec3 lcd = mix(PAPER * (1.0 - 0.12 * shadow), INK, lit * grid.x * grid.y);
+	fragColor = vec4(mix(color, lcd, uIntensity), 1.0);
+}
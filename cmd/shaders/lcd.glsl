@@ -0,0 +1,23 @@
+#version 330 core
+
+// An LCD's dot grid: every emulated pixel is a separate square cell, with a
+// thin gap between it and its neighbours.
+
+in vec2 vTexCoords;
+
+out vec4 fragColor;
+
+uniform vec4 uTexBounds;
+uniform sampler2D uTexture;
+uniform float uIntensity;
+
+void main() {
+	vec3 color = texture(uTexture, (vTexCoords - uTexBounds.xy) / uTexBounds.zw).rgb;
+
+	// Distance to the nearest edge of the cell, in emulated pixels
+	vec2 cell = fract(vTexCoords);
+	vec2 edge = min(cell, 1.0 - cell);
+	vec2 grid = smoothstep(0.04, 0.12, edge);
+
+	fragColor = vec4(color * mix(1.0, grid.x * grid.y, 0.75 * uIntensity), 1.0);
+}
@@ -0,0 +1,20 @@
+#version 330 core
+
+// Darkens the top and bottom of every row of emulated pixels, like the gaps
+// between the scanlines of a CRT.
+
+in vec2 vTexCoords;
+
+out vec4 fragColor;
+
+uniform vec4 uTexBounds;
+uniform sampler2D uTexture;
+uniform float uIntensity;
+
+void main() {
+	vec3 color = texture(uTexture, (vTexCoords - uTexBounds.xy) / uTexBounds.zw).rgb;
+
+	// 0 in the middle of a row, 1 at its edges
+	float edge = abs(fract(vTexCoords.y) - 0.5) * 2.0;
+	fragColor = vec4(color * (1.0 - uIntensity * edge * edge), 1.0);
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/trace"
+)
+
+var traceFile string
+var traceFormat string
+var traceAddrs string
+var traceFrames string
+
+// startTrace traces emu to the file given with --trace, if any, and returns
+// a function that finishes the trace.
+func startTrace(emu emulator.Emulator) (func() error, error) {
+	if traceFile == "" {
+		return func() error { return nil }, nil
+	}
+
+	format := trace.FormatText
+	if traceFormat == "" && strings.HasSuffix(traceFile, ".jsonl") {
+		format = trace.FormatJSONL
+	} else if traceFormat != "" {
+		var err error
+		if format, err = trace.ParseFormat(traceFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	var filter trace.Filter
+	var err error
+	if traceAddrs != "" {
+		if filter.Addrs, err = trace.ParseRanges(traceAddrs, 16); err != nil {
+			return nil, fmt.Errorf("--trace-pc: %w", err)
+		}
+	}
+	if traceFrames != "" {
+		if filter.Frames, err = trace.ParseRanges(traceFrames, 10); err != nil {
+			return nil, fmt.Errorf("--trace-frames: %w", err)
+		}
+	}
+
+	fd, err := os.Create(traceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	w := trace.NewWriter(fd, format)
+	w.Filter = filter
+	trace.Attach(emu, w)
+
+	return func() error {
+		err := w.Close()
+		if closeErr := fd.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&traceFile, "trace", "", "log every executed instruction to a file")
+	rootCmd.Flags().StringVar(&traceFormat, "trace-format", "", "trace format, text or jsonl (default jsonl for .jsonl files, otherwise text)")
+	rootCmd.Flags().StringVar(&traceAddrs, "trace-pc", "", "only trace instructions in hexadecimal address ranges, such as 200-2ff,300")
+	rootCmd.Flags().StringVar(&traceFrames, "trace-frames", "", "only trace instructions in frame ranges, such as 600-700 or 600-")
+}
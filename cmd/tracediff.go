@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/trace"
+
+	"github.com/spf13/cobra"
+)
+
+var errTracesDiffer = errors.New("traces differ")
+
+var tracediffIgnore []string
+
+// tracediffCmd represents the tracediff command
+var tracediffCmd = &cobra.Command{
+	Use:   "tracediff a b",
+	Short: "Find where two instruction traces diverge",
+	Long: `Compare two traces written with --trace, in either format, and report the
+first instruction where they differ along with the one before it.
+
+Fields can be left out of the comparison with --ignore, such as frame and
+cycle when the other emulator counts them differently:
+
+  gr8 tracediff gr8.log other.log --ignore frame,cycle
+
+Exits with status 1 if the traces differ.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		b, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+
+		d, err := trace.Diff(a, b, tracediffIgnore...)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if d == nil {
+			fmt.Fprintln(out, "traces match")
+			return nil
+		}
+
+		fmt.Fprintf(out, "traces diverge at instruction %d\n", d.Index)
+		if d.Prev != nil {
+			fmt.Fprintf(out, "  last match: %s\n", d.Prev)
+		}
+		for _, side := range []struct {
+			name string
+			line int
+			rec  *trace.Record
+		}{{args[0], d.LineA, d.A}, {args[1], d.LineB, d.B}} {
+			if side.rec == nil {
+				fmt.Fprintf(out, "  %s: ended\n", side.name)
+			} else {
+				fmt.Fprintf(out, "  %s:%d: %s\n", side.name, side.line, side.rec)
+			}
+		}
+		if len(d.Fields) > 0 {
+			fmt.Fprintf(out, "  differs in: %s\n", strings.Join(d.Fields, ", "))
+		}
+
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return errTracesDiffer
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tracediffCmd)
+
+	tracediffCmd.Flags().StringSliceVar(&tracediffIgnore, "ignore", nil, "fields to leave out of the comparison, such as frame,cycle,dt")
+}
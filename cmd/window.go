@@ -0,0 +1,298 @@
+/*
+Copyright © 2025 Aria Taylor <ari@aricodes.net>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+)
+
+var integerScale bool
+var fullscreen bool
+var borderless bool
+var paletteName string
+
+// Key that toggles fullscreen
+const FULLSCREEN_KEY = pixel.KeyF11
+
+// geometry is the size and position of the window, saved between sessions.
+type geometry struct {
+	X, Y          float64
+	Width, Height float64
+	Fullscreen    bool
+}
+
+// loadGeometry returns the geometry saved by the last session, if any.
+func loadGeometry() (geometry, bool) {
+	var g geometry
+
+	path, err := configPath("window.json")
+	if err != nil {
+		return g, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return g, false
+	}
+	if json.Unmarshal(data, &g) != nil || g.Width <= 0 || g.Height <= 0 {
+		return g, false
+	}
+
+	return g, true
+}
+
+// saveGeometry saves the geometry for the next session.
+func saveGeometry(g geometry) error {
+	path, err := configPath("window.json")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// fitScale returns the largest scale the display can be drawn at in bounds
+// while keeping its aspect ratio, rounded down to a whole number if integer
+// is set.
+func fitScale(bounds, display pixel.Rect, integer bool) float64 {
+	scale := min(bounds.W()/display.W(), bounds.H()/display.H())
+	if integer {
+		scale = max(1, math.Floor(scale))
+	}
+
+	return scale
+}
+
+// window shows the emulated display, scaled to fit and letterboxed.
+type window struct {
+	*opengl.Window
+
+	// The display is copied into the canvas's texture in place, only when it
+	// changes, and kept for screenshots
+	canvas  *opengl.Canvas
+	pixels  []uint8
+	display emulator.Bitplane
+	palette screenshot.Palette
+
+	// Number of the frame in display
+	frame uint64
+
+	// With a shader, the display is drawn through it onto post, so that it
+	// doesn't apply to the HUD
+	post      *opengl.Canvas
+	intensity float32
+	time      float32
+	start     time.Time
+
+	// Geometry to save while the window is fullscreen
+	windowed geometry
+}
+
+// newWindow opens a window for a display of the default resolution, sized by
+// --scale or, if restore is set, with the geometry of the last session.
+func newWindow(title string, restore bool) (*window, error) {
+	display := pixel.R(0, 0, emulator.DISPLAY_WIDTH, emulator.DISPLAY_HEIGHT)
+	g := geometry{Width: display.W() * float64(Scale), Height: display.H() * float64(Scale)}
+	if saved, ok := loadGeometry(); ok && restore {
+		g = saved
+	}
+
+	win, err := opengl.NewWindow(opengl.WindowConfig{
+		Title:       title,
+		Bounds:      pixel.R(0, 0, g.Width, g.Height),
+		Position:    pixel.V(g.X, g.Y),
+		Resizable:   true,
+		Undecorated: borderless,
+		VSync:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &window{Window: win, windowed: g}
+	w.resize(display)
+	if err := w.setShader(shaderName, shaderIntensity); err != nil {
+		win.Destroy()
+		return nil, err
+	}
+	if fullscreen || g.Fullscreen {
+		w.toggleFullscreen()
+	}
+
+	return w, nil
+}
+
+// geometry returns the current size and position of the window.
+func (w *window) geometry() geometry {
+	if w.Monitor() != nil {
+		g := w.windowed
+		g.Fullscreen = true
+		return g
+	}
+
+	pos := w.GetPos()
+	return geometry{X: pos.X, Y: pos.Y, Width: w.Bounds().W(), Height: w.Bounds().H()}
+}
+
+// toggleFullscreen switches between fullscreen on the primary monitor and the
+// window's previous geometry.
+func (w *window) toggleFullscreen() {
+	if w.Monitor() != nil {
+		w.SetMonitor(nil)
+		return
+	}
+
+	w.windowed = w.geometry()
+	w.SetMonitor(opengl.PrimaryMonitor())
+}
+
+// resize makes a canvas for a display of a new resolution. A window that
+// isn't fullscreen is resized to keep the same scale.
+func (w *window) resize(display pixel.Rect) {
+	if w.canvas != nil && w.Monitor() == nil {
+		scale := fitScale(w.Bounds(), w.canvas.Bounds(), integerScale)
+		w.SetBounds(pixel.R(0, 0, display.W()*scale, display.H()*scale))
+	}
+
+	w.canvas = opengl.NewCanvas(display)
+	w.pixels = make([]uint8, 4*int(display.W())*int(display.H()))
+	w.display = emulator.NewBitplane(int(display.W()), int(display.H()))
+}
+
+// setShader loads a post-processing shader by name or path, and draws the
+// display through it with the given intensity.
+func (w *window) setShader(name string, intensity float64) error {
+	src, err := loadShader(name)
+	if err != nil || src == "" {
+		return err
+	}
+	if err := checkShader(name, src); err != nil {
+		return err
+	}
+
+	w.intensity, w.start = float32(intensity), time.Now()
+	w.post = opengl.NewCanvas(w.Bounds())
+	w.post.SetUniform("uIntensity", &w.intensity)
+	w.post.SetUniform("uTime", &w.time)
+	w.post.SetFragmentShader(src)
+
+	return nil
+}
+
+// drawFrame copies a new frame, drawn in frame number n, into the canvas if
+// there is one, and draws the canvas letterboxed in the middle of the window.
+func (w *window) drawFrame(frame *image.RGBA, n uint64) {
+	if frame != nil {
+		w.frame = n
+		size := frame.Bounds().Size()
+		if display := pixel.R(0, 0, float64(size.X), float64(size.Y)); display != w.canvas.Bounds() {
+			w.resize(display)
+		}
+
+		readFrame(w.display, frame)
+		paint(w.pixels, w.display, w.palette)
+		w.canvas.SetPixels(w.pixels)
+	}
+
+	var target pixel.Target = w
+	bounds := w.Bounds()
+	if w.post != nil {
+		if w.post.Bounds() != bounds {
+			w.post.SetBounds(bounds)
+		}
+		w.post.Clear(color.Black)
+		w.time = float32(time.Since(w.start).Seconds())
+		target = w.post
+	}
+
+	w.Clear(color.Black)
+	scale := fitScale(bounds, w.canvas.Bounds(), integerScale)
+	w.canvas.Draw(target, pixel.IM.Scaled(pixel.ZV, scale).Moved(bounds.Center()))
+	if w.post != nil {
+		w.post.Draw(w, pixel.IM.Moved(bounds.Center()))
+	}
+}
+
+// readFrame reads the lit pixels of a rendered frame into a bitplane of the
+// same size.
+func readFrame(display emulator.Bitplane, frame *image.RGBA) {
+	for y := range display.Height {
+		row := frame.Pix[y*frame.Stride:]
+		for x := range display.Width {
+			display.Set(x, y, row[x*4] != 0)
+		}
+	}
+}
+
+// paint colours a display into pixels with palette, bottom row first, the
+// order textures are stored in.
+func paint(pixels []uint8, display emulator.Bitplane, palette screenshot.Palette) {
+	stride := 4 * display.Width
+	for y := range display.Height {
+		row := pixels[(display.Height-1-y)*stride:]
+		for x := range display.Width {
+			c := palette.Off
+			if display.Pixel(x, y) {
+				c = palette.On
+			}
+
+			p := row[x*4 : x*4+4 : x*4+4]
+			p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// close saves the window's geometry for the next session and closes it. The
+// geometry is only a convenience, so failing to save it isn't an error.
+func (w *window) close() {
+	if err := saveGeometry(w.geometry()); err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't save window geometry: %s\n", err)
+	}
+	w.Destroy()
+}
+
+func init() {
+	rootCmd.Flags().BoolVar(&integerScale, "integer-scale", false, "only scale the display by whole numbers, for even pixels")
+	rootCmd.Flags().BoolVar(&fullscreen, "fullscreen", false, "start fullscreen, toggled with F11")
+	rootCmd.Flags().BoolVar(&borderless, "borderless", false, "open the window without borders or a title bar")
+	rootCmd.Flags().StringVar(&paletteName, "palette", "classic", "display colours: "+strings.Join(screenshot.PaletteNames(), ", ")+", or unlit and lit colours such as 000000,ffffff")
+}
@@ -0,0 +1,121 @@
+// Package conformance runs the Timendus CHIP-8 test suite headlessly.
+//
+// Each test runs one of the ROMs in the roms package for a fixed number of
+// frames under a quirks preset, playing back a movie to pick options from the
+// ROM's menus, and captures the final screen. Test ROMs that check the
+// emulator draw a pass or fail glyph next to each result, which Results
+// reads back from the screen.
+package conformance
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/movie"
+	"github.com/aricodes-oss/gr8/roms"
+)
+
+// Test runs a test ROM.
+type Test struct {
+	Name string
+	ROM  []byte
+
+	// Frames to run before capturing the screen
+	Frames int
+
+	// Keypad input, as a movie
+	Input string
+
+	// Presets to run the test under, or all of Presets if empty
+	Presets []string
+
+	// Whether the final screen shows pass and fail glyphs, and how many
+	// results are known to fail under each preset
+	Results  bool
+	Failures map[string]int
+}
+
+// Presets are the quirks presets the suite is checked under, one for each
+// platform it tests.
+var Presets = []string{"chip8", "schip", "xochip"}
+
+// Tests covers every ROM in the suite. Menu choices are made at frame 100,
+// once the menus are drawn even with display wait. The scrolling test needs
+// SUPER-CHIP and XO-CHIP instructions the emulator doesn't have, so it stops
+// at its menu.
+var Tests = []Test{
+	{Name: "chip8-logo", ROM: roms.Chip8Logo, Frames: 60},
+	{Name: "ibm-logo", ROM: roms.IBMLogo, Frames: 60},
+	{Name: "corax+", ROM: roms.Corax, Frames: 300, Results: true},
+	{Name: "flags", ROM: roms.Flags, Frames: 300, Results: true},
+
+	// The display wait test reports SLOW without display wait at the default
+	// 700 instructions per frame
+	{
+		Name: "quirks-chip8", ROM: roms.Quirks, Frames: 600, Presets: []string{"chip8"}, Results: true,
+		Input: "100 +1\n105 -1\n",
+	},
+	{
+		Name: "quirks-schip", ROM: roms.Quirks, Frames: 600, Presets: []string{"schip"}, Results: true,
+		Input:    "# modern SUPER-CHIP\n100 +2\n105 -2\n130 +1\n135 -1\n",
+		Failures: map[string]int{"schip": 1},
+	},
+	{
+		Name: "quirks-xochip", ROM: roms.Quirks, Frames: 600, Presets: []string{"xochip"}, Results: true,
+		Input:    "100 +3\n105 -3\n",
+		Failures: map[string]int{"xochip": 1},
+	},
+
+	{Name: "keypad-ex9e-down", ROM: roms.Keypad, Frames: 200, Input: "100 +1\n105 -1\n130 +5 +A\n"},
+	{Name: "keypad-ex9e-up", ROM: roms.Keypad, Frames: 200, Input: "100 +2\n105 -2\n130 +5 +A\n"},
+
+	// Fx0A finishes when a key is pressed, where the COSMAC VIP waits for it
+	// to be released
+	{
+		Name: "keypad-fx0a", ROM: roms.Keypad, Frames: 200, Results: true,
+		Input:    "100 +3\n105 -3\n130 +7\n135 -7\n",
+		Failures: map[string]int{"chip8": 1, "schip": 1, "xochip": 1},
+	},
+
+	{Name: "beep", ROM: roms.Beep, Frames: 60},
+	{Name: "scrolling", ROM: roms.Scrolling, Frames: 60},
+}
+
+// Result is the outcome of running a test.
+type Result struct {
+	Screen Screen
+
+	// Pass and fail glyphs on the final screen
+	Passed, Failed int
+}
+
+// RunsUnder reports whether the test runs under the named preset.
+func (t Test) RunsUnder(preset string) bool {
+	return len(t.Presets) == 0 || slices.Contains(t.Presets, preset)
+}
+
+// Run runs a test with the given quirks.
+func Run(test Test, quirks emulator.Quirks) (*Result, error) {
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(test.ROM), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		return nil, err
+	}
+	emu.SetQuirks(quirks)
+
+	m, err := movie.Parse(strings.NewReader(test.Input))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Play(emu, test.Frames); err != nil {
+		return nil, err
+	}
+
+	r := &Result{Screen: Capture(emu)}
+	if test.Results {
+		r.Passed, r.Failed = r.Screen.Results()
+	}
+
+	return r, nil
+}
@@ -0,0 +1,110 @@
+package conformance
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden screens")
+
+func golden(test Test, preset string) string {
+	return filepath.Join("testdata", fmt.Sprintf("%s.%s.txt", test.Name, preset))
+}
+
+func TestConformance(t *testing.T) {
+	for _, preset := range Presets {
+		quirks, _ := emulator.ParseQuirks(preset)
+
+		for _, test := range Tests {
+			if !test.RunsUnder(preset) {
+				continue
+			}
+
+			t.Run(preset+"/"+test.Name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				r, err := Run(test, quirks)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				path := golden(test, preset)
+				if *update {
+					if err := os.WriteFile(path, []byte(r.Screen.String()), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				text, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := ParseScreen(string(text))
+				if err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+				if diff := Diff(want, r.Screen); diff != "" {
+					t.Errorf("screen differs from %s:\n%s", path, diff)
+				}
+
+				if test.Results {
+					assert.Positive(r.Passed+r.Failed, "no results on screen:\n%s", r.Screen)
+					assert.Equal(test.Failures[preset], r.Failed, "failed results:\n%s", r.Screen)
+				}
+			})
+		}
+	}
+}
+
+func TestScreen(t *testing.T) {
+	assert := assert.New(t)
+
+	var s Screen
+	s[0][0], s[1][1], s[31][63] = true, true, true
+
+	parsed, err := ParseScreen(s.String())
+	assert.NoError(err)
+	assert.Equal(s, parsed)
+
+	_, err = ParseScreen("#.\n")
+	assert.Error(err)
+
+	moved := s
+	moved[1][1], moved[1][2] = false, true
+	assert.Equal(""+
+		"row  1 want .#..............................................................\n"+
+		"        got ..#.............................................................\n"+
+		"             -+\n",
+		Diff(s, moved))
+	assert.Empty(Diff(s, s))
+}
+
+func TestResults(t *testing.T) {
+	assert := assert.New(t)
+
+	var s Screen
+	draw := func(x, y int, glyph [3][3]bool) {
+		for dy := range 3 {
+			for dx := range 3 {
+				s[y+dy][x+dx] = glyph[dy][dx]
+			}
+		}
+	}
+	draw(0, 0, passGlyph)
+	draw(10, 10, passGlyph)
+	draw(61, 29, failGlyph)
+
+	// A glyph touching other pixels is part of something else
+	draw(20, 20, failGlyph)
+	s[20][23] = true
+
+	passed, failed := s.Results()
+	assert.Equal(2, passed)
+	assert.Equal(1, failed)
+}
@@ -0,0 +1,166 @@
+package conformance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Screen is a snapshot of the display.
+type Screen [emulator.DISPLAY_HEIGHT][emulator.DISPLAY_WIDTH]bool
+
+// Capture takes a snapshot of emu's display.
+func Capture(emu emulator.Emulator) Screen {
+	var s Screen
+	display := emu.Display()
+	for y := range s {
+		for x := range s[y] {
+			s[y][x] = display.Pixel(x, y)
+		}
+	}
+
+	return s
+}
+
+// String draws the screen as text, one line per row, with # for lit pixels
+// and . for unlit ones.
+func (s Screen) String() string {
+	var b strings.Builder
+	for _, row := range s {
+		for _, lit := range row {
+			if lit {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// ParseScreen reads a screen drawn by String.
+func ParseScreen(text string) (Screen, error) {
+	var s Screen
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) != emulator.DISPLAY_HEIGHT {
+		return s, fmt.Errorf("expected %d rows, got %d", emulator.DISPLAY_HEIGHT, len(lines))
+	}
+
+	for y, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if len(line) != emulator.DISPLAY_WIDTH {
+			return s, fmt.Errorf("row %d: expected %d pixels, got %d", y, emulator.DISPLAY_WIDTH, len(line))
+		}
+
+		for x, ch := range []byte(line) {
+			switch ch {
+			case '#':
+				s[y][x] = true
+			case '.':
+			default:
+				return s, fmt.Errorf("row %d: invalid pixel %q", y, ch)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Diff describes the differences between two screens, or returns "" if they
+// match. Rows that differ are shown as they were expected and as they are,
+// with a line underneath marking the pixels that changed: + for pixels that
+// are lit but shouldn't be, and - for pixels that should be lit but aren't.
+func Diff(want, got Screen) string {
+	var b strings.Builder
+	for y := range want {
+		if want[y] == got[y] {
+			continue
+		}
+
+		marks := make([]byte, emulator.DISPLAY_WIDTH)
+		for x := range marks {
+			switch {
+			case got[y][x] && !want[y][x]:
+				marks[x] = '+'
+			case want[y][x] && !got[y][x]:
+				marks[x] = '-'
+			default:
+				marks[x] = ' '
+			}
+		}
+
+		fmt.Fprintf(&b, "row %2d want %s\n", y, row(want[y]))
+		fmt.Fprintf(&b, "        got %s\n", row(got[y]))
+		fmt.Fprintf(&b, "            %s\n", strings.TrimRight(string(marks), " "))
+	}
+
+	return b.String()
+}
+
+func row(pixels [emulator.DISPLAY_WIDTH]bool) string {
+	b := make([]byte, len(pixels))
+	for x, lit := range pixels {
+		b[x] = '.'
+		if lit {
+			b[x] = '#'
+		}
+	}
+
+	return string(b)
+}
+
+// The glyphs the test suite draws next to each result
+var (
+	passGlyph = [3][3]bool{
+		{true, false, true},
+		{true, true, false},
+		{true, false, false},
+	}
+	failGlyph = [3][3]bool{
+		{true, false, true},
+		{false, true, false},
+		{true, false, true},
+	}
+)
+
+// Results counts the pass and fail glyphs on a result screen. Glyphs must be
+// surrounded by unlit pixels, so letters that contain the same shapes aren't
+// counted.
+func (s Screen) Results() (passed, failed int) {
+	for y := -1; y+3 <= emulator.DISPLAY_HEIGHT; y++ {
+		for x := -1; x+3 <= emulator.DISPLAY_WIDTH; x++ {
+			switch {
+			case s.matches(x, y, passGlyph):
+				passed++
+			case s.matches(x, y, failGlyph):
+				failed++
+			}
+		}
+	}
+
+	return passed, failed
+}
+
+// matches reports whether glyph is drawn with its top left corner at (x+1,
+// y+1) and an unlit border around it. The border may lie off the screen.
+func (s Screen) matches(x, y int, glyph [3][3]bool) bool {
+	for dy := range 5 {
+		for dx := range 5 {
+			px, py := x+dx, y+dy
+			lit := px >= 0 && py >= 0 && px < emulator.DISPLAY_WIDTH && py < emulator.DISPLAY_HEIGHT && s[py][px]
+
+			want := false
+			if dx >= 1 && dx <= 3 && dy >= 1 && dy <= 3 {
+				want = glyph[dy-1][dx-1]
+			}
+			if lit != want {
+				return false
+			}
+		}
+	}
+
+	return true
+}
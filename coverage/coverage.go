@@ -0,0 +1,192 @@
+// Package coverage records which bytes of memory a ROM executes, reads as
+// data and writes while it runs.
+//
+// Instructions read memory as data in DRW, which reads sprites, and in Fx65,
+// which loads registers. Fx33 (BCD) and Fx55 write it. Bytes that are both
+// executed and written were modified at runtime: self-modifying code breaks
+// static disassembly, so they are flagged.
+//
+// Coverage from several runs, such as one per input movie, can be merged.
+package coverage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Coverage counts the accesses to each byte of memory.
+type Coverage struct {
+	// Times each byte was executed as part of an instruction, read as data
+	// and written
+	Exec  [emulator.MEM_SIZE]uint64
+	Read  [emulator.MEM_SIZE]uint64
+	Write [emulator.MEM_SIZE]uint64
+}
+
+// Attach starts recording the coverage of emu. It adds a watchpoint on all of
+// memory, so attaching slows the emulator down.
+func Attach(emu emulator.Emulator) *Coverage {
+	c := &Coverage{}
+
+	emu.OnExecute(func(pc, _ uint16) {
+		c.Exec[pc]++
+		c.Exec[pc+1]++
+	})
+
+	id := emu.Watch(emulator.WatchMemory(0, emulator.MEM_SIZE, emulator.AccessRead|emulator.AccessWrite))
+	emu.OnWatch(func(hit emulator.WatchHit) {
+		if hit.ID != id {
+			return
+		}
+
+		switch hit.Access {
+		case emulator.AccessRead:
+			c.Read[hit.Addr]++
+		case emulator.AccessWrite:
+			c.Write[hit.Addr]++
+		}
+	})
+
+	return c
+}
+
+// Merge adds the counts of other to c.
+func (c *Coverage) Merge(other *Coverage) {
+	for addr := range c.Exec {
+		c.Exec[addr] += other.Exec[addr]
+		c.Read[addr] += other.Read[addr]
+		c.Write[addr] += other.Write[addr]
+	}
+}
+
+// Access returns the ways the byte at addr was accessed.
+func (c *Coverage) Access(addr uint16) emulator.Access {
+	var a emulator.Access
+	if c.Exec[addr] > 0 {
+		a |= emulator.AccessExecute
+	}
+	if c.Read[addr] > 0 {
+		a |= emulator.AccessRead
+	}
+	if c.Write[addr] > 0 {
+		a |= emulator.AccessWrite
+	}
+
+	return a
+}
+
+// Hits returns the total number of accesses to the byte at addr.
+func (c *Coverage) Hits(addr uint16) uint64 {
+	return c.Exec[addr] + c.Read[addr] + c.Write[addr]
+}
+
+// SelfModifying reports whether the byte at addr was both executed and
+// written.
+func (c *Coverage) SelfModifying(addr uint16) bool {
+	return c.Exec[addr] > 0 && c.Write[addr] > 0
+}
+
+// Range is a range of addresses, from Start up to but not including End.
+type Range struct {
+	Start, End uint16
+}
+
+func (r Range) String() string {
+	if r.End-r.Start == 1 {
+		return fmt.Sprintf("0x%03X", r.Start)
+	}
+	return fmt.Sprintf("0x%03X-0x%03X", r.Start, r.End-1)
+}
+
+// SelfModifyingRanges returns the runs of self-modifying bytes in memory.
+func (c *Coverage) SelfModifyingRanges() []Range {
+	var ranges []Range
+	for addr := uint16(0); addr < emulator.MEM_SIZE; addr++ {
+		if !c.SelfModifying(addr) {
+			continue
+		}
+
+		if n := len(ranges); n > 0 && ranges[n-1].End == addr {
+			ranges[n-1].End++
+		} else {
+			ranges = append(ranges, Range{addr, addr + 1})
+		}
+	}
+
+	return ranges
+}
+
+// Stats summarizes the coverage of a range of memory.
+type Stats struct {
+	Bytes     int
+	Executed  int
+	Read      int
+	Written   int
+	Untouched int
+
+	SelfModifying int
+}
+
+// Stats summarizes the coverage of r.
+func (c *Coverage) Stats(r Range) Stats {
+	s := Stats{Bytes: int(r.End - r.Start)}
+	for addr := r.Start; addr < r.End; addr++ {
+		a := c.Access(addr)
+		if a&emulator.AccessExecute != 0 {
+			s.Executed++
+		}
+		if a&emulator.AccessRead != 0 {
+			s.Read++
+		}
+		if a&emulator.AccessWrite != 0 {
+			s.Written++
+		}
+		if a == 0 {
+			s.Untouched++
+		}
+		if c.SelfModifying(addr) {
+			s.SelfModifying++
+		}
+	}
+
+	return s
+}
+
+// romRange returns the range of memory a ROM is loaded into.
+func romRange(rom []byte) Range {
+	return Range{emulator.ROM_START, emulator.ROM_START + uint16(len(rom))}
+}
+
+// WriteSummary writes the coverage of a ROM's bytes, and the self-modifying
+// code anywhere in memory.
+func (c *Coverage) WriteSummary(w io.Writer, rom []byte) error {
+	s := c.Stats(romRange(rom))
+	percent := func(n int) float64 {
+		if s.Bytes == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(s.Bytes)
+	}
+
+	fmt.Fprintf(w, "%d bytes of ROM\n", s.Bytes)
+	fmt.Fprintf(w, "  executed   %5d  %5.1f%%\n", s.Executed, percent(s.Executed))
+	fmt.Fprintf(w, "  read       %5d  %5.1f%%\n", s.Read, percent(s.Read))
+	fmt.Fprintf(w, "  written    %5d  %5.1f%%\n", s.Written, percent(s.Written))
+	fmt.Fprintf(w, "  untouched  %5d  %5.1f%%\n", s.Untouched, percent(s.Untouched))
+
+	ranges := c.SelfModifyingRanges()
+	if len(ranges) == 0 {
+		_, err := fmt.Fprintln(w, "no self-modifying code")
+		return err
+	}
+
+	fmt.Fprintln(w, "self-modifying code, executed and written at runtime:")
+	for _, r := range ranges {
+		if _, err := fmt.Fprintf(w, "  %s\n", r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/stretchr/testify/assert"
+)
+
+var rom = []byte{
+	0xA2, 0x0E, // 0x200 LD I, 0x20E
+	0xD0, 0x01, // 0x202 DRW V0, V0, 1
+	0x60, 0x12, // 0x204 LD V0, 0x12
+	0x61, 0x0C, // 0x206 LD V1, 0x0C
+	0xA2, 0x0C, // 0x208 LD I, 0x20C
+	0xF1, 0x55, // 0x20A LD [I], V0-V1, making the next instruction JMP 0x20C
+	0x00, 0xE0, // 0x20C CLS
+	0xFF, // 0x20E sprite
+}
+
+func run(t *testing.T) *Coverage {
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Attach(emu)
+	if err := emu.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCoverage(t *testing.T) {
+	assert := assert.New(t)
+	c := run(t)
+
+	assert.Equal(emulator.AccessExecute, c.Access(0x200))
+	assert.Equal(emulator.AccessExecute, c.Access(0x201))
+	assert.Equal(emulator.AccessRead, c.Access(0x20E))
+	assert.Equal(emulator.AccessWrite|emulator.AccessExecute, c.Access(0x20C))
+	assert.Equal(emulator.Access(0), c.Access(0x20F))
+	assert.Equal(uint64(1), c.Exec[0x200])
+	assert.Equal(uint64(emulator.DEFAULT_IPF-6), c.Exec[0x20C])
+
+	assert.Equal([]Range{{0x20C, 0x20E}}, c.SelfModifyingRanges())
+	assert.Equal("0x20C-0x20D", c.SelfModifyingRanges()[0].String())
+	assert.Equal(Stats{Bytes: 15, Executed: 14, Read: 1, Written: 2, SelfModifying: 2}, c.Stats(romRange(rom)))
+
+	merged := &Coverage{}
+	merged.Merge(c)
+	merged.Merge(c)
+	assert.Equal(2*c.Exec[0x20C], merged.Exec[0x20C])
+	assert.Equal(uint64(2), merged.Read[0x20E])
+}
+
+func TestWriteSummary(t *testing.T) {
+	assert := assert.New(t)
+	c := run(t)
+
+	var b strings.Builder
+	assert.NoError(c.WriteSummary(&b, rom))
+	assert.Equal(strings.Join([]string{
+		"15 bytes of ROM",
+		"  executed      14   93.3%",
+		"  read           1    6.7%",
+		"  written        2   13.3%",
+		"  untouched      0    0.0%",
+		"self-modifying code, executed and written at runtime:",
+		"  0x20C-0x20D",
+		"",
+	}, "\n"), b.String())
+}
+
+func TestWriteListing(t *testing.T) {
+	assert := assert.New(t)
+	c := run(t)
+
+	var b strings.Builder
+	assert.NoError(c.WriteListing(&b, rom))
+	listing := b.String()
+	assert.Contains(listing, "--x          1  0x200  A2 0E  LDI data_20E\n")
+	assert.Contains(listing, "-wx!       695  0x20C  00 E0  CLS\n")
+	assert.Contains(listing, "r--          1  0x20E  FF\n")
+}
+
+func TestWriteHTML(t *testing.T) {
+	assert := assert.New(t)
+	c := run(t)
+
+	var b strings.Builder
+	assert.NoError(c.WriteHTML(&b, "test.ch8", rom))
+	page := b.String()
+	assert.Contains(page, "<title>test.ch8 coverage</title>")
+	assert.Contains(page, "Self-modifying code: 0x20C-0x20D")
+	assert.Contains(page, `title="0x20C: executed 694, read 0, written 1 (self-modifying)">00</td>`)
+	assert.Contains(page, `<td style="background: #eee; color: #999" title="0x20F: executed 0, read 0, written 0">--</td>`)
+	assert.Equal(1, strings.Count(page, "<tr>"))
+}
@@ -0,0 +1,187 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// flags returns the accesses to a range of bytes as a fixed-width column,
+// such as "r-x" or "rwx!" for self-modifying code.
+func (c *Coverage) flags(addr uint16, size int) string {
+	var a emulator.Access
+	smc := false
+	for off := range uint16(size) {
+		a |= c.Access(addr + off)
+		smc = smc || c.SelfModifying(addr+off)
+	}
+
+	b := []byte("---")
+	if a&emulator.AccessRead != 0 {
+		b[0] = 'r'
+	}
+	if a&emulator.AccessWrite != 0 {
+		b[1] = 'w'
+	}
+	if a&emulator.AccessExecute != 0 {
+		b[2] = 'x'
+	}
+	if smc {
+		b = append(b, '!')
+	} else {
+		b = append(b, ' ')
+	}
+
+	return string(b)
+}
+
+// WriteListing writes the disassembly of rom, with the accesses to each
+// instruction or run of data and the most times any of its bytes was
+// accessed. Self-modifying code is marked with a !.
+func (c *Coverage) WriteListing(w io.Writer, rom []byte) error {
+	return disasm.Disassemble(rom).WriteAnnotatedListing(w, func(addr uint16, size int) string {
+		var hits uint64
+		for off := range uint16(size) {
+			hits = max(hits, c.Hits(addr+off))
+		}
+
+		return fmt.Sprintf("%s %9d", c.flags(addr, size), hits)
+	})
+}
+
+// Heatmap colors, as hues. Bytes are colored by the first of these accesses
+// that applies, so self-modifying code stands out.
+const (
+	hueSelfModifying = 300
+	hueExecute       = 120
+	hueWrite         = 25
+	hueRead          = 210
+)
+
+type heatmapCell struct {
+	Value string
+	Title string
+	Style template.CSS
+}
+
+type heatmapRow struct {
+	Addr  string
+	Cells []heatmapCell
+}
+
+type heatmap struct {
+	Title  string
+	Stats  Stats
+	Ranges []Range
+	Rows   []heatmapRow
+}
+
+// cell returns the heatmap cell for the byte at addr. Lighter shades are
+// accessed less, on a log scale up to the most accessed byte.
+func (c *Coverage) cell(addr uint16, value string, most uint64) heatmapCell {
+	cell := heatmapCell{
+		Value: value,
+		Title: fmt.Sprintf(
+			"0x%03X: executed %d, read %d, written %d",
+			addr, c.Exec[addr], c.Read[addr], c.Write[addr],
+		),
+		Style: "background: #eee; color: #999",
+	}
+
+	a := c.Access(addr)
+	var hue int
+	switch {
+	case c.SelfModifying(addr):
+		hue = hueSelfModifying
+		cell.Title += " (self-modifying)"
+	case a&emulator.AccessExecute != 0:
+		hue = hueExecute
+	case a&emulator.AccessWrite != 0:
+		hue = hueWrite
+	case a&emulator.AccessRead != 0:
+		hue = hueRead
+	default:
+		return cell
+	}
+
+	heat := math.Log1p(float64(c.Hits(addr))) / math.Log1p(float64(most))
+	lightness := 85 - int(50*heat)
+	cell.Style = template.CSS(fmt.Sprintf("background: hsl(%d, 70%%, %d%%); color: %s", hue, lightness, textColor(lightness)))
+
+	return cell
+}
+
+func textColor(lightness int) string {
+	if lightness < 55 {
+		return "#fff"
+	}
+	return "#000"
+}
+
+// WriteHTML writes a heatmap of the ROM's bytes, and of any memory past it
+// that was accessed, as a standalone HTML page.
+func (c *Coverage) WriteHTML(w io.Writer, title string, rom []byte) error {
+	r := romRange(rom)
+	var most uint64
+	for addr := range uint16(emulator.MEM_SIZE) {
+		if addr >= emulator.ROM_START && c.Access(addr) != 0 {
+			r.End = max(r.End, addr+1)
+		}
+		most = max(most, c.Hits(addr))
+	}
+
+	h := heatmap{Title: title, Stats: c.Stats(romRange(rom)), Ranges: c.SelfModifyingRanges()}
+	for row := r.Start &^ 0xF; row < r.End; row += 16 {
+		hr := heatmapRow{Addr: fmt.Sprintf("0x%03X", row)}
+		for addr := row; addr < row+16; addr++ {
+			// Bytes outside the ROM have no fixed value
+			value := "--"
+			if off := int(addr) - int(emulator.ROM_START); off >= 0 && off < len(rom) {
+				value = fmt.Sprintf("%02X", rom[off])
+			}
+			hr.Cells = append(hr.Cells, c.cell(addr, value, most))
+		}
+		h.Rows = append(h.Rows, hr)
+	}
+
+	return heatmapTemplate.Execute(w, h)
+}
+
+var heatmapTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} coverage</title>
+<style>
+body { font-family: monospace; }
+table { border-collapse: collapse; }
+td { padding: 2px 4px; text-align: center; }
+td.addr { color: #666; text-align: right; padding-right: 8px; }
+.legend span { padding: 2px 6px; margin-right: 4px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>
+{{.Stats.Bytes}} bytes of ROM: {{.Stats.Executed}} executed, {{.Stats.Read}} read,
+{{.Stats.Written}} written, {{.Stats.Untouched}} untouched.
+</p>
+{{if .Ranges}}<p>Self-modifying code: {{range $i, $r := .Ranges}}{{if $i}}, {{end}}{{$r}}{{end}}</p>{{end}}
+<p class="legend">
+<span style="background: hsl(120, 70%, 50%)">executed</span>
+<span style="background: hsl(210, 70%, 50%)">read</span>
+<span style="background: hsl(25, 70%, 50%)">written</span>
+<span style="background: hsl(300, 70%, 50%)">self-modifying</span>
+<span style="background: #eee">untouched</span>
+Darker bytes were accessed more.
+</p>
+<table>
+{{range .Rows}}<tr><td class="addr">{{.Addr}}</td>{{range .Cells}}<td style="{{.Style}}" title="{{.Title}}">{{.Value}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
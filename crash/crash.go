@@ -0,0 +1,216 @@
+// Package crash writes reports for ROMs that fault, with enough of the
+// machine's state to reproduce and debug the fault without rerunning it.
+//
+// A report is a text file holding the ROM's SHA-256 hash, the emulator's
+// configuration, the fault, the registers and stack, the instructions leading
+// up to the fault, the display and a dump of memory. The history uses the
+// trace package's text format, so it can be compared with traces from other
+// emulators.
+package crash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/trace"
+)
+
+// Instructions of history included in the summary
+const SUMMARY_HISTORY = 8
+
+// Report is a snapshot of an emulator that faulted.
+type Report struct {
+	Time time.Time
+
+	// Name of the ROM, and the SHA-256 hash of its contents
+	ROM  string
+	Hash string
+
+	// Settings the emulator ran with, such as the clock speed
+	Config map[string]string
+
+	Err error
+
+	State   emulator.State
+	Frame   uint64
+	History []emulator.HistoryEntry
+	Memory  [emulator.MEM_SIZE]byte
+	Display [emulator.DISPLAY_HEIGHT][emulator.DISPLAY_WIDTH]bool
+}
+
+// New takes a snapshot of emu after it faulted with err while running rom.
+func New(emu emulator.Emulator, name string, rom []byte, err error) *Report {
+	hash := sha256.Sum256(rom)
+	r := &Report{
+		Time:    time.Now(),
+		ROM:     name,
+		Hash:    hex.EncodeToString(hash[:]),
+		Config:  map[string]string{},
+		Err:     err,
+		State:   emu.State(),
+		Frame:   emu.FrameCount(),
+		History: emu.History(),
+	}
+
+	emu.ReadMemory(0, r.Memory[:])
+	for y := range emulator.DISPLAY_HEIGHT {
+		for x := range emulator.DISPLAY_WIDTH {
+			r.Display[y][x] = emu.Pixel(x, y)
+		}
+	}
+
+	return r
+}
+
+// record converts a history entry to a trace record.
+func record(h emulator.HistoryEntry) trace.Record {
+	return trace.Record{
+		Frame:    h.Frame,
+		Cycle:    h.Cycle,
+		PC:       h.PC,
+		Opcode:   h.Opcode,
+		Mnemonic: disasm.Decode(h.Opcode).String(),
+		V:        h.V,
+		I:        h.I,
+		SP:       h.SP,
+		DT:       h.DT,
+		ST:       h.ST,
+	}
+}
+
+// writeRegisters writes the registers and stack on two lines.
+func (r *Report) writeRegisters(w io.Writer) {
+	s := r.State
+
+	var v strings.Builder
+	for reg, b := range s.V {
+		fmt.Fprintf(&v, "V%X=%02X ", reg, b)
+	}
+	fmt.Fprintf(w, "  %sI=%03X DT=%02X ST=%02X\n", v.String(), s.I, s.DelayTimer, s.SoundTimer)
+
+	stack := make([]string, len(s.Stack))
+	for idx, addr := range s.Stack {
+		stack[idx] = fmt.Sprintf("0x%03X", addr)
+	}
+	if len(stack) == 0 {
+		stack = append(stack, "empty")
+	}
+	fmt.Fprintf(w, "  stack: %s\n", strings.Join(stack, " "))
+}
+
+// Summary returns a short description of the fault for printing to the
+// terminal, with the last few instructions disassembled.
+func (r *Report) Summary() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "%v\n", r.Err)
+	fmt.Fprintf(&w, "  in frame %d of %s (sha256 %s)\n", r.Frame, r.ROM, r.Hash[:12])
+
+	history := r.History[max(0, len(r.History)-SUMMARY_HISTORY):]
+	if len(history) > 0 {
+		fmt.Fprintf(&w, "last %d instructions:\n", len(history))
+	}
+	for idx, h := range history {
+		marker := " "
+		if idx == len(history)-1 {
+			marker = ">"
+		}
+		fmt.Fprintf(&w, "%s 0x%03X  %04X  %s\n", marker, h.PC, h.Opcode, disasm.Decode(h.Opcode))
+	}
+
+	fmt.Fprintln(&w, "registers:")
+	r.writeRegisters(&w)
+
+	return w.String()
+}
+
+// WriteTo writes the full report.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	fmt.Fprintln(&b, "gr8 crash report")
+	fmt.Fprintf(&b, "time: %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "rom: %s\n", r.ROM)
+	fmt.Fprintf(&b, "sha256: %s\n", r.Hash)
+	fmt.Fprintf(&b, "fault: %v\n", r.Err)
+	fmt.Fprintf(&b, "frame: %d\n", r.Frame)
+	fmt.Fprintf(&b, "pc: 0x%03X\n", r.State.PC)
+
+	fmt.Fprintln(&b, "\n[config]")
+	keys := make([]string, 0, len(r.Config))
+	for key := range r.Config {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, r.Config[key])
+	}
+
+	fmt.Fprintln(&b, "\n[registers]")
+	r.writeRegisters(&b)
+
+	fmt.Fprintln(&b, "\n[history]")
+	for _, h := range r.History {
+		fmt.Fprintln(&b, record(h))
+	}
+
+	fmt.Fprintln(&b, "\n[display]")
+	for _, row := range r.Display {
+		for _, lit := range row {
+			if lit {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprintln(&b, "\n[memory]")
+	for addr := 0; addr < len(r.Memory); addr += 16 {
+		row := r.Memory[addr : addr+16]
+		fmt.Fprintf(&b, "%03X: % X\n", addr, row)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Save writes the report to a new file in dir, named after the ROM and the
+// time of the fault, and returns its path. It never overwrites a file: if the
+// name is taken, a number is added to it.
+func (r *Report) Save(dir string) (string, error) {
+	rom := filepath.Base(r.ROM)
+	rom = strings.TrimSuffix(rom, filepath.Ext(rom))
+	name := fmt.Sprintf("gr8-crash-%s-%s", rom, r.Time.Format("20060102-150405.000"))
+
+	path := filepath.Join(dir, name+".txt")
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	for n := 2; errors.Is(err, fs.ErrExist); n++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.txt", name, n))
+		fd, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.WriteTo(fd)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,100 @@
+package crash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+// LD V0, 5; CALL 0x206; JMP 0x204; RET; RET
+var rom = []byte{0x60, 0x05, 0x22, 0x06, 0x12, 0x04, 0x00, 0xEE}
+
+func crash(t *testing.T) *Report {
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the JMP back into the subroutine so the second RET underflows
+	emu.WriteMemory(0x204, []byte{0x12, 0x06})
+	err = emu.RunFrame()
+	if err == nil {
+		t.Fatal("expected a fault")
+	}
+
+	r := New(emu, "test.ch8", rom, err)
+	r.Config["ipf"] = "700"
+	return r
+}
+
+func TestSummary(t *testing.T) {
+	assert := assert.New(t)
+	r := crash(t)
+
+	assert.Equal("2777e9c1603b", r.Hash[:12])
+	assert.ErrorIs(r.Err, emulator.ErrStackUnderflow)
+
+	summary := r.Summary()
+	assert.True(strings.HasPrefix(summary, "fault at 0x206 (00EE): return with an empty stack\n"), summary)
+	assert.Contains(summary, "in frame 1 of test.ch8 (sha256 2777e9c1603b)")
+	assert.Contains(summary, "last 5 instructions:\n  0x200  6005  LD V0, 0x05\n")
+	assert.Contains(summary, "> 0x206  00EE  RET\n")
+	assert.Contains(summary, "V0=05")
+	assert.Contains(summary, "stack: empty")
+}
+
+func TestReport(t *testing.T) {
+	assert := assert.New(t)
+	r := crash(t)
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	assert.NoError(err)
+
+	report := b.String()
+	assert.Contains(report, "sha256: "+r.Hash)
+	assert.Contains(report, "[config]\nipf: 700\n")
+	assert.Contains(report, "200: 60 05 22 06 12 06 00 EE")
+
+	// The history can be read back as a trace
+	_, history, _ := strings.Cut(report, "[history]\n")
+	history, _, _ = strings.Cut(history, "\n\n")
+	reader := trace.NewReader(strings.NewReader(history))
+	var last trace.Record
+	for {
+		rec, err := reader.Next()
+		if err != nil {
+			break
+		}
+		last = rec
+	}
+	assert.Equal(uint16(0x206), last.PC)
+	assert.Equal(uint16(0x00EE), last.Opcode)
+}
+
+func TestSave(t *testing.T) {
+	assert := assert.New(t)
+	r := crash(t)
+
+	dir := t.TempDir()
+	path, err := r.Save(dir)
+	assert.NoError(err)
+	assert.Equal("gr8-crash-test-"+r.Time.Format("20060102-150405.000")+".txt", filepath.Base(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.True(bytes.HasPrefix(data, []byte("gr8 crash report\n")))
+
+	// Faults at the same moment don't overwrite each other's reports
+	again, err := r.Save(dir)
+	assert.NoError(err)
+	assert.NotEqual(path, again)
+	assert.FileExists(path)
+	assert.FileExists(again)
+}
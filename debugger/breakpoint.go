@@ -0,0 +1,171 @@
+package debugger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Breakpoint stops execution before the instruction at Addr runs, or before
+// any instruction if Anywhere is set.
+type Breakpoint struct {
+	Addr     uint16
+	Anywhere bool
+
+	// Only stop while the condition is true, if set
+	Condition *Expr
+
+	// Only stop on hits that match, if set
+	HitCondition *HitCondition
+
+	// Log the message instead of stopping, if set
+	Log *Template
+
+	// Times the breakpoint was reached with its condition true
+	Hits int
+}
+
+func (bp *Breakpoint) String() string {
+	var parts []string
+	if !bp.Anywhere {
+		parts = append(parts, fmt.Sprintf("0x%03X", bp.Addr))
+	}
+	if bp.Condition != nil {
+		parts = append(parts, "if "+bp.Condition.String())
+	}
+	if bp.HitCondition != nil {
+		parts = append(parts, "hit "+bp.HitCondition.String())
+	}
+	if bp.Log != nil {
+		parts = append(parts, "log "+bp.Log.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Plain reports whether the breakpoint always stops at its address.
+func (bp *Breakpoint) Plain() bool {
+	return !bp.Anywhere && bp.Condition == nil && bp.HitCondition == nil && bp.Log == nil
+}
+
+// HitCondition compares a breakpoint's hit count with a number.
+type HitCondition struct {
+	// One of ==, >, >=, <, <= or %, which matches every Nth hit
+	Op string
+	N  int
+}
+
+// ParseHitCondition parses a hit condition such as ">= 3" or "% 2". A bare
+// number N matches the Nth hit and every one after it.
+func ParseHitCondition(s string) (*HitCondition, error) {
+	s = strings.TrimSpace(s)
+
+	op := ">="
+	for _, prefix := range []string{"==", ">=", "<=", "=", ">", "<", "%"} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			op, s = prefix, strings.TrimSpace(rest)
+			break
+		}
+	}
+	if op == "=" {
+		op = "=="
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || (op == "%" && n == 0) {
+		return nil, fmt.Errorf("invalid hit count %q", s)
+	}
+
+	return &HitCondition{Op: op, N: n}, nil
+}
+
+func (h *HitCondition) String() string {
+	return fmt.Sprintf("%s %d", h.Op, h.N)
+}
+
+// Match reports whether a breakpoint should stop on its hits'th hit.
+func (h *HitCondition) Match(hits int) bool {
+	switch h.Op {
+	case "==":
+		return hits == h.N
+	case ">":
+		return hits > h.N
+	case "<":
+		return hits < h.N
+	case "<=":
+		return hits <= h.N
+	case "%":
+		return hits%h.N == 0
+	}
+
+	return hits >= h.N
+}
+
+// Clauses of a breakpoint, after its address
+var breakpointClause = regexp.MustCompile(`(^|\s)(if|hit|log)(\s|$)`)
+
+// ParseBreakpoint parses a breakpoint such as "2a4", "2a4 if v3 > 5",
+// "if frame > 600 && pc == 0x2a4" or "2a4 hit 3 log v3={v3}". The address is
+// hexadecimal, and breakpoints without one are checked before every
+// instruction. Each clause can be given once, and log takes the rest of the
+// line. Errors in expressions give their column in s.
+func ParseBreakpoint(s string) (Breakpoint, error) {
+	var bp Breakpoint
+
+	clauses := breakpointClause.FindAllStringSubmatchIndex(s, -1)
+
+	// The address is whatever comes before the first clause
+	end := len(s)
+	if len(clauses) > 0 {
+		end = clauses[0][0]
+	}
+	if target := strings.TrimSpace(s[:end]); target != "" {
+		addr, err := ParseAddress(target)
+		if err != nil {
+			return bp, err
+		}
+		bp.Addr = addr
+	} else {
+		bp.Anywhere = true
+	}
+
+	seen := map[string]bool{}
+	for idx, clause := range clauses {
+		keyword := s[clause[4]:clause[5]]
+		if seen[keyword] {
+			return bp, &ExprError{Pos: clause[4], Msg: fmt.Sprintf("%q given twice", keyword)}
+		}
+		seen[keyword] = true
+
+		start, end := clause[5], len(s)
+		if keyword != "log" && idx+1 < len(clauses) {
+			end = clauses[idx+1][0]
+		}
+		body := s[start:end]
+
+		var err error
+		switch keyword {
+		case "if":
+			bp.Condition, err = parseExprAt(body, start)
+		case "hit":
+			bp.HitCondition, err = ParseHitCondition(body)
+		case "log":
+			msg := strings.TrimLeft(body, " \t")
+			bp.Log, err = parseTemplateAt(msg, start+len(body)-len(msg))
+		}
+		if err != nil {
+			return bp, err
+		}
+
+		if keyword == "log" {
+			break
+		}
+	}
+
+	if bp.Anywhere && bp.Condition == nil {
+		return bp, fmt.Errorf("expected an address or a condition, got %q", s)
+	}
+
+	return bp, nil
+}
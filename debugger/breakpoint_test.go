@@ -0,0 +1,132 @@
+package debugger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBreakpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	bp, err := ParseBreakpoint("2a4")
+	assert.Nil(err)
+	assert.True(bp.Plain())
+	assert.Equal(uint16(0x2A4), bp.Addr)
+
+	bp, err = ParseBreakpoint("2a4 if v3 > 5 hit % 2 log v3={v3} if {i}")
+	assert.Nil(err)
+	assert.Equal("v3 > 5", bp.Condition.String())
+	assert.Equal(&HitCondition{Op: "%", N: 2}, bp.HitCondition)
+	assert.Equal("v3={v3} if {i}", bp.Log.String())
+	assert.Equal("0x2A4 if v3 > 5 hit % 2 log v3={v3} if {i}", bp.String())
+
+	bp, err = ParseBreakpoint("if frame > 600 && pc == 0x2a4")
+	assert.Nil(err)
+	assert.True(bp.Anywhere)
+
+	// Errors in clauses point into the whole breakpoint
+	_, err = ParseBreakpoint("2a4 if v3 > > 5")
+	var exprErr *ExprError
+	assert.True(errors.As(err, &exprErr))
+	assert.Equal(12, exprErr.Pos)
+
+	_, err = ParseBreakpoint("2a4 log a {v3 +}")
+	assert.True(errors.As(err, &exprErr))
+	assert.Equal(15, exprErr.Pos)
+
+	for _, bad := range []string{"", "xyz", "hit 3", "2a4 if v3 if v4", "2a4 hit many"} {
+		_, err = ParseBreakpoint(bad)
+		assert.NotNil(err, bad)
+	}
+}
+
+func TestHitCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	for src, hits := range map[string][]bool{
+		"3":    {false, false, true, true},
+		"== 2": {false, true, false, false},
+		"=2":   {false, true, false, false},
+		"< 3":  {true, true, false, false},
+		"% 2":  {false, true, false, true},
+	} {
+		h, err := ParseHitCondition(src)
+		if !assert.Nil(err, src) {
+			continue
+		}
+
+		for idx, want := range hits {
+			assert.Equal(want, h.Match(idx+1), "%s on hit %d", src, idx+1)
+		}
+	}
+
+	_, err := ParseHitCondition("% 0")
+	assert.NotNil(err)
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	// The loop at 0x204 runs forever, and V1 is 1 by then
+	bp, err := ParseBreakpoint("204 if v1 == 1 hit 3")
+	assert.Nil(err)
+	added := d.AddBreakpoint(bp)
+	assert.True(d.HasBreakpoint(0x204))
+
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.False(d.Running())
+	assert.Equal(3, added.Hits)
+
+	// Conditions that are never true don't stop
+	d.ClearBreakpoints()
+	bp, _ = ParseBreakpoint("204 if v1 == 2")
+	d.AddBreakpoint(bp)
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.True(d.Running())
+
+	// Breakpoints without an address are checked everywhere
+	d = setup(t)
+	bp, _ = ParseBreakpoint("if v0 == 5 && sp == 1")
+	added = d.AddBreakpoint(bp)
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.False(d.Running())
+	assert.Equal(uint16(0x208), pc(d))
+	assert.Len(d.AnywhereBreakpoints(), 1)
+
+	d.RemoveBreakpoint(added)
+	assert.Empty(d.AnywhereBreakpoints())
+
+	// Conditions that fail stop with their error
+	bp, _ = ParseBreakpoint("if 1 / v2")
+	d.AddBreakpoint(bp)
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	reason, err := d.Stopped()
+	assert.Equal(StopBreakpoint, reason)
+	assert.ErrorIs(err, ErrDivideByZero)
+}
+
+func TestLogpoint(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	var logs []string
+	d.OnLog(func(bp *Breakpoint, msg string) {
+		logs = append(logs, msg)
+	})
+
+	bp, _ := ParseBreakpoint("206 log v0={v0} sp={sp}")
+	d.AddBreakpoint(bp)
+	d.Continue()
+	assert.Nil(d.Tick(100))
+
+	// Logpoints don't stop
+	assert.True(d.Running())
+	assert.Equal([]string{"v0=0 sp=1"}, logs)
+}
@@ -0,0 +1,1048 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/octo"
+
+	"github.com/google/go-dap"
+)
+
+// Thread ID reported for the CHIP-8, which has a single thread
+const DAP_THREAD_ID = 1
+
+// Bytes per variable in the memory scope
+const MEMORY_ROW = 16
+
+// Variable references for the scopes of every stack frame
+const (
+	scopeRegisters = iota + 1
+	scopeTimers
+	scopeStack
+	scopeMemory
+)
+
+var ErrNotLaunched = errors.New("no program has been launched")
+
+// LaunchArgs are the arguments of a DAP launch request.
+type LaunchArgs struct {
+	// ROM or Octo source file to run
+	Program string `json:"program"`
+
+	// Symbol file written by gr8 compile, for source breakpoints in a ROM.
+	// Octo programs don't need one.
+	Symbols string `json:"symbols,omitempty"`
+
+	// Directory that relative source paths in the symbols are resolved against
+	Cwd string `json:"cwd,omitempty"`
+
+	// Pause before the first instruction
+	StopOnEntry bool `json:"stopOnEntry,omitempty"`
+
+	// Quirks preset and engine to run with, instead of the session's
+	Quirks string `json:"quirks,omitempty"`
+	Engine string `json:"engine,omitempty"`
+}
+
+// DAPSession serves the Debug Adapter Protocol to a single client.
+type DAPSession struct {
+	r *bufio.Reader
+	w io.Writer
+
+	seq int
+
+	dbg         *Debugger
+	symbols     *octo.Program
+	names       map[uint16]string
+	cwd         string
+	stopOnEntry bool
+
+	// Breakpoints by source path, and those set on addresses and functions
+	sourceBreakpoints      map[string][]Breakpoint
+	instructionBreakpoints []Breakpoint
+	functionBreakpoints    []Breakpoint
+	breakpointID           int
+
+	// Logpoint messages waiting to be sent
+	logs []string
+
+	// IDs of the watchpoints set as data breakpoints
+	dataBreakpoints []int
+
+	// Time between frames while running
+	clock time.Duration
+
+	// What programs run with unless the launch request says otherwise
+	quirks emulator.Quirks
+	engine emulator.Engine
+
+	done bool
+}
+
+// NewDAPSession returns a session that reads requests from r and writes
+// responses and events to w.
+func NewDAPSession(r io.Reader, w io.Writer) *DAPSession {
+	return &DAPSession{
+		r:                 bufio.NewReader(r),
+		w:                 w,
+		sourceBreakpoints: map[string][]Breakpoint{},
+		clock:             emulator.DEFAULT_CLOCK_SPEED,
+		quirks:            emulator.QuirksGR8,
+	}
+}
+
+// SetQuirks changes the quirks programs are launched with when the launch
+// request doesn't pick a preset.
+func (s *DAPSession) SetQuirks(q emulator.Quirks) {
+	s.quirks = q
+}
+
+// SetEngine changes the engine programs are launched with when the launch
+// request doesn't pick one.
+func (s *DAPSession) SetEngine(e emulator.Engine) {
+	s.engine = e
+}
+
+// Serve handles requests until the client disconnects.
+func (s *DAPSession) Serve() error {
+	requests := make(chan dap.Message)
+	errs := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for {
+			msg, err := dap.ReadProtocolMessage(s.r)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case requests <- msg:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(s.clock)
+	defer ticker.Stop()
+
+	for !s.done {
+		select {
+		case msg := <-requests:
+			if err := s.handle(msg); err != nil {
+				return err
+			}
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if err := s.tick(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// tick runs one frame while the program is running, and reports when it stops.
+func (s *DAPSession) tick() error {
+	if s.dbg == nil || !s.dbg.Running() {
+		return nil
+	}
+
+	// Faults are reported through Stopped
+	s.dbg.Tick(emulator.DEFAULT_IPF)
+
+	for _, msg := range s.logs {
+		err := s.send(&dap.OutputEvent{
+			Event: event("output"),
+			Body:  dap.OutputEventBody{Category: "console", Output: msg + "\n"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.logs = nil
+
+	if s.dbg.Running() {
+		return nil
+	}
+
+	return s.reportStop()
+}
+
+// reportStop tells the client why the debugger stopped.
+func (s *DAPSession) reportStop() error {
+	reason, err := s.dbg.Stopped()
+	switch reason {
+	case StopBreakpoint:
+		if err != nil {
+			return s.stopped("breakpoint", err.Error())
+		}
+		return s.stopped("breakpoint", "")
+	case StopFault:
+		return s.stopped("exception", err.Error())
+	case StopPause:
+		return s.stopped("pause", "")
+	case StopWatchpoint:
+		return s.stopped("data breakpoint", s.dbg.Hits()[0].String())
+	}
+
+	return s.stopped("step", "")
+}
+
+// -- Messages
+
+func (s *DAPSession) send(msg dap.Message) error {
+	s.seq++
+	switch m := msg.(type) {
+	case dap.ResponseMessage:
+		m.GetResponse().Seq = s.seq
+	case dap.EventMessage:
+		m.GetEvent().Seq = s.seq
+	}
+
+	return dap.WriteProtocolMessage(s.w, msg)
+}
+
+func response(req dap.RequestMessage) dap.Response {
+	r := req.GetRequest()
+	return dap.Response{
+		ProtocolMessage: dap.ProtocolMessage{Type: "response"},
+		RequestSeq:      r.Seq,
+		Success:         true,
+		Command:         r.Command,
+	}
+}
+
+func event(name string) dap.Event {
+	return dap.Event{ProtocolMessage: dap.ProtocolMessage{Type: "event"}, Event: name}
+}
+
+func (s *DAPSession) stopped(reason, text string) error {
+	return s.send(&dap.StoppedEvent{
+		Event: event("stopped"),
+		Body: dap.StoppedEventBody{
+			Reason:            reason,
+			Text:              text,
+			ThreadId:          DAP_THREAD_ID,
+			AllThreadsStopped: true,
+		},
+	})
+}
+
+func (s *DAPSession) fail(req dap.RequestMessage, err error) error {
+	r := response(req)
+	r.Success = false
+	r.Message = err.Error()
+
+	return s.send(&dap.ErrorResponse{
+		Response: r,
+		Body:     dap.ErrorResponseBody{Error: &dap.ErrorMessage{Format: err.Error(), ShowUser: true}},
+	})
+}
+
+// handle answers a single request. Errors are sent to the client, and only
+// errors writing to it are returned.
+func (s *DAPSession) handle(msg dap.Message) error {
+	req, ok := msg.(dap.RequestMessage)
+	if !ok {
+		return nil
+	}
+
+	switch req.(type) {
+	case *dap.InitializeRequest, *dap.LaunchRequest, *dap.DisconnectRequest, *dap.TerminateRequest:
+	default:
+		if s.dbg == nil {
+			return s.fail(req, ErrNotLaunched)
+		}
+	}
+
+	var err error
+	switch r := req.(type) {
+	case *dap.InitializeRequest:
+		err = s.initialize(r)
+	case *dap.LaunchRequest:
+		err = s.launch(r)
+	case *dap.ConfigurationDoneRequest:
+		err = s.configurationDone(r)
+	case *dap.SetBreakpointsRequest:
+		err = s.setBreakpoints(r)
+	case *dap.SetInstructionBreakpointsRequest:
+		err = s.setInstructionBreakpoints(r)
+	case *dap.SetFunctionBreakpointsRequest:
+		err = s.setFunctionBreakpoints(r)
+	case *dap.DataBreakpointInfoRequest:
+		err = s.dataBreakpointInfo(r)
+	case *dap.SetDataBreakpointsRequest:
+		err = s.setDataBreakpoints(r)
+	case *dap.SetExceptionBreakpointsRequest:
+		err = s.send(&dap.SetExceptionBreakpointsResponse{Response: response(r)})
+	case *dap.ThreadsRequest:
+		err = s.send(&dap.ThreadsResponse{
+			Response: response(r),
+			Body:     dap.ThreadsResponseBody{Threads: []dap.Thread{{Id: DAP_THREAD_ID, Name: "CHIP-8"}}},
+		})
+	case *dap.StackTraceRequest:
+		err = s.stackTrace(r)
+	case *dap.ScopesRequest:
+		err = s.scopes(r)
+	case *dap.VariablesRequest:
+		err = s.variables(r)
+	case *dap.SetVariableRequest:
+		err = s.setVariable(r)
+	case *dap.ReadMemoryRequest:
+		err = s.readMemory(r)
+	case *dap.WriteMemoryRequest:
+		err = s.writeMemory(r)
+	case *dap.DisassembleRequest:
+		err = s.disassemble(r)
+	case *dap.ContinueRequest:
+		s.dbg.Continue()
+		err = s.send(&dap.ContinueResponse{
+			Response: response(r),
+			Body:     dap.ContinueResponseBody{AllThreadsContinued: true},
+		})
+	case *dap.PauseRequest:
+		s.dbg.Pause()
+		err = s.send(&dap.PauseResponse{Response: response(r)})
+		if err == nil {
+			err = s.stopped("pause", "")
+		}
+	case *dap.NextRequest:
+		err = s.step(r, s.dbg.StepOver, &dap.NextResponse{Response: response(r)})
+	case *dap.StepInRequest:
+		err = s.step(r, s.dbg.Step, &dap.StepInResponse{Response: response(r)})
+	case *dap.StepOutRequest:
+		err = s.step(r, s.dbg.StepOut, &dap.StepOutResponse{Response: response(r)})
+	case *dap.DisconnectRequest:
+		s.done = true
+		err = s.send(&dap.DisconnectResponse{Response: response(r)})
+	case *dap.TerminateRequest:
+		s.done = true
+		err = s.send(&dap.TerminateResponse{Response: response(r)})
+		if err == nil {
+			err = s.send(&dap.TerminatedEvent{Event: event("terminated")})
+		}
+	default:
+		err = s.fail(req, fmt.Errorf("unsupported request %q", req.GetRequest().Command))
+	}
+
+	return err
+}
+
+// -- Lifecycle
+
+func (s *DAPSession) initialize(r *dap.InitializeRequest) error {
+	return s.send(&dap.InitializeResponse{
+		Response: response(r),
+		Body: dap.Capabilities{
+			SupportsConfigurationDoneRequest:  true,
+			SupportsFunctionBreakpoints:       true,
+			SupportsInstructionBreakpoints:    true,
+			SupportsDataBreakpoints:           true,
+			SupportsConditionalBreakpoints:    true,
+			SupportsHitConditionalBreakpoints: true,
+			SupportsLogPoints:                 true,
+			SupportsSetVariable:               true,
+			SupportsReadMemoryRequest:         true,
+			SupportsWriteMemoryRequest:        true,
+			SupportsDisassembleRequest:        true,
+			SupportsTerminateRequest:          true,
+		},
+	})
+}
+
+// load reads the program to debug, compiling Octo sources, and its symbols.
+func (s *DAPSession) load(args LaunchArgs) ([]byte, error) {
+	if args.Program == "" {
+		return nil, errors.New("launch: missing program")
+	}
+
+	if strings.EqualFold(filepath.Ext(args.Program), ".8o") {
+		prog, err := octo.CompileFile(args.Program)
+		if err != nil {
+			return nil, err
+		}
+		s.symbols = prog
+		return prog.ROM, nil
+	}
+
+	rom, err := os.ReadFile(args.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Symbols != "" {
+		fd, err := os.Open(args.Symbols)
+		if err != nil {
+			return nil, err
+		}
+		defer fd.Close()
+
+		s.symbols, err = octo.ReadSymbols(fd)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", args.Symbols, err)
+		}
+	}
+
+	return rom, nil
+}
+
+func (s *DAPSession) launch(r *dap.LaunchRequest) error {
+	var args LaunchArgs
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.fail(r, fmt.Errorf("launch: %w", err))
+	}
+
+	var err error
+	quirks, engine := s.quirks, s.engine
+	if args.Quirks != "" {
+		if quirks, err = emulator.ParseQuirks(args.Quirks); err != nil {
+			return s.fail(r, fmt.Errorf("launch: %w", err))
+		}
+	}
+	if args.Engine != "" {
+		if engine, err = emulator.ParseEngine(args.Engine); err != nil {
+			return s.fail(r, fmt.Errorf("launch: %w", err))
+		}
+	}
+
+	rom, err := s.load(args)
+	if err != nil {
+		return s.fail(r, err)
+	}
+
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		return s.fail(r, err)
+	}
+	emu.SetQuirks(quirks)
+	emu.SetEngine(engine)
+
+	s.dbg = New(emu)
+	s.dbg.OnLog(func(bp *Breakpoint, msg string) {
+		s.logs = append(s.logs, msg)
+	})
+	s.names = map[uint16]string{}
+	if s.symbols != nil {
+		for name, addr := range s.symbols.Labels {
+			// Pick one name deterministically when an address has several
+			if existing, ok := s.names[addr]; !ok || name < existing {
+				s.names[addr] = name
+			}
+		}
+	}
+	s.cwd = args.Cwd
+	s.stopOnEntry = args.StopOnEntry
+
+	if err := s.send(&dap.LaunchResponse{Response: response(r)}); err != nil {
+		return err
+	}
+
+	return s.send(&dap.InitializedEvent{Event: event("initialized")})
+}
+
+func (s *DAPSession) configurationDone(r *dap.ConfigurationDoneRequest) error {
+	if err := s.send(&dap.ConfigurationDoneResponse{Response: response(r)}); err != nil {
+		return err
+	}
+
+	if s.stopOnEntry {
+		return s.stopped("entry", "")
+	}
+
+	s.dbg.Continue()
+	return nil
+}
+
+// step runs a stepping command. Steps that complete immediately are reported
+// straight away, and the rest when tick sees them finish.
+func (s *DAPSession) step(r dap.RequestMessage, run func() error, resp dap.Message) error {
+	if err := run(); err != nil {
+		return s.fail(r, err)
+	}
+
+	if err := s.send(resp); err != nil {
+		return err
+	}
+
+	if s.dbg.Running() {
+		return nil
+	}
+	return s.reportStop()
+}
+
+// -- Breakpoints
+
+// syncBreakpoints replaces the debugger's breakpoints with every kind set
+// through the protocol.
+func (s *DAPSession) syncBreakpoints() {
+	s.dbg.ClearBreakpoints()
+
+	for _, bps := range s.sourceBreakpoints {
+		for _, bp := range bps {
+			s.dbg.AddBreakpoint(bp)
+		}
+	}
+	for _, bp := range s.instructionBreakpoints {
+		s.dbg.AddBreakpoint(bp)
+	}
+	for _, bp := range s.functionBreakpoints {
+		s.dbg.AddBreakpoint(bp)
+	}
+}
+
+// breakpointAt returns a breakpoint at addr with the clauses the client gave.
+func breakpointAt(addr uint16, condition, hitCondition, logMessage string) (Breakpoint, error) {
+	bp := Breakpoint{Addr: addr}
+
+	var err error
+	if condition != "" {
+		if bp.Condition, err = ParseExpr(condition); err != nil {
+			return bp, fmt.Errorf("condition: %w", err)
+		}
+	}
+	if hitCondition != "" {
+		if bp.HitCondition, err = ParseHitCondition(hitCondition); err != nil {
+			return bp, err
+		}
+	}
+	if logMessage != "" {
+		if bp.Log, err = ParseTemplate(logMessage); err != nil {
+			return bp, fmt.Errorf("log message: %w", err)
+		}
+	}
+
+	return bp, nil
+}
+
+func (s *DAPSession) nextBreakpointID() int {
+	s.breakpointID++
+	return s.breakpointID
+}
+
+// resolvePath makes a source path from the symbols absolute.
+func (s *DAPSession) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	if s.cwd != "" {
+		return filepath.Join(s.cwd, path)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// samePath reports whether a source path from the client names the same
+// file as one from the symbols.
+func (s *DAPSession) samePath(client, symbol string) bool {
+	return filepath.Clean(client) == filepath.Clean(s.resolvePath(symbol))
+}
+
+// lineAddress finds the first instruction compiled from line of path, or from
+// the closest line after it.
+func (s *DAPSession) lineAddress(path string, line int) (uint16, octo.Pos, bool) {
+	var best octo.Pos
+	var addr uint16
+	found := false
+
+	if s.symbols == nil {
+		return 0, best, false
+	}
+
+	for a, pos := range s.symbols.Lines {
+		if pos.Line < line || !s.samePath(path, pos.Filename) {
+			continue
+		}
+
+		if !found || pos.Line < best.Line || pos.Line == best.Line && a < addr {
+			addr, best, found = a, pos, true
+		}
+	}
+
+	return addr, best, found
+}
+
+func (s *DAPSession) setBreakpoints(r *dap.SetBreakpointsRequest) error {
+	path := r.Arguments.Source.Path
+	set := []Breakpoint{}
+	bps := []dap.Breakpoint{}
+
+	for _, sb := range r.Arguments.Breakpoints {
+		bp := dap.Breakpoint{Id: s.nextBreakpointID(), Source: &r.Arguments.Source, Line: sb.Line}
+
+		addr, pos, ok := s.lineAddress(path, sb.Line)
+		if !ok {
+			bp.Message = fmt.Sprintf("no code at line %d", sb.Line)
+			bps = append(bps, bp)
+			continue
+		}
+
+		bp.Line = pos.Line
+		bp.InstructionReference = fmt.Sprintf("0x%03X", addr)
+		if b, err := breakpointAt(addr, sb.Condition, sb.HitCondition, sb.LogMessage); err != nil {
+			bp.Message = err.Error()
+		} else {
+			bp.Verified = true
+			set = append(set, b)
+		}
+
+		bps = append(bps, bp)
+	}
+
+	s.sourceBreakpoints[filepath.Clean(path)] = set
+	s.syncBreakpoints()
+
+	return s.send(&dap.SetBreakpointsResponse{
+		Response: response(r),
+		Body:     dap.SetBreakpointsResponseBody{Breakpoints: bps},
+	})
+}
+
+func (s *DAPSession) setInstructionBreakpoints(r *dap.SetInstructionBreakpointsRequest) error {
+	s.instructionBreakpoints = nil
+	bps := []dap.Breakpoint{}
+
+	for _, ib := range r.Arguments.Breakpoints {
+		bp := dap.Breakpoint{Id: s.nextBreakpointID(), InstructionReference: ib.InstructionReference, Offset: ib.Offset}
+
+		ref, err := ParseNumber(ib.InstructionReference)
+		addr := int(ref) + ib.Offset
+		if err != nil || addr < 0 || addr >= emulator.MEM_SIZE {
+			bp.Message = fmt.Sprintf("invalid address %s%+d", ib.InstructionReference, ib.Offset)
+		} else if b, err := breakpointAt(uint16(addr), ib.Condition, ib.HitCondition, ""); err != nil {
+			bp.Message = err.Error()
+		} else {
+			bp.Verified = true
+			s.instructionBreakpoints = append(s.instructionBreakpoints, b)
+		}
+
+		bps = append(bps, bp)
+	}
+
+	s.syncBreakpoints()
+
+	return s.send(&dap.SetInstructionBreakpointsResponse{
+		Response: response(r),
+		Body:     dap.SetInstructionBreakpointsResponseBody{Breakpoints: bps},
+	})
+}
+
+// setFunctionBreakpoints sets breakpoints on labels, or on addresses such as "2a4".
+func (s *DAPSession) setFunctionBreakpoints(r *dap.SetFunctionBreakpointsRequest) error {
+	s.functionBreakpoints = nil
+	bps := []dap.Breakpoint{}
+
+	for _, fb := range r.Arguments.Breakpoints {
+		bp := dap.Breakpoint{Id: s.nextBreakpointID()}
+
+		addr, ok := uint16(0), false
+		if s.symbols != nil {
+			addr, ok = s.symbols.Labels[fb.Name]
+		}
+		if !ok {
+			var err error
+			addr, err = ParseAddress(fb.Name)
+			ok = err == nil
+		}
+
+		if ok {
+			bp.InstructionReference = fmt.Sprintf("0x%03X", addr)
+			if b, err := breakpointAt(addr, fb.Condition, fb.HitCondition, ""); err != nil {
+				bp.Message = err.Error()
+			} else {
+				bp.Verified = true
+				s.functionBreakpoints = append(s.functionBreakpoints, b)
+			}
+		} else {
+			bp.Message = fmt.Sprintf("unknown label %q", fb.Name)
+		}
+
+		bps = append(bps, bp)
+	}
+
+	s.syncBreakpoints()
+
+	return s.send(&dap.SetFunctionBreakpointsResponse{
+		Response: response(r),
+		Body:     dap.SetFunctionBreakpointsResponseBody{Breakpoints: bps},
+	})
+}
+
+// dataBreakpointInfo describes the registers, timers and memory rows that
+// can be watched. Their data IDs are targets for ParseWatchpoint.
+func (s *DAPSession) dataBreakpointInfo(r *dap.DataBreakpointInfoRequest) error {
+	body := dap.DataBreakpointInfoResponseBody{
+		AccessTypes: []dap.DataBreakpointAccessType{"write", "read", "readWrite"},
+	}
+
+	name := r.Arguments.Name
+	switch r.Arguments.VariablesReference {
+	case scopeRegisters, scopeTimers:
+		if _, ok := parseRegister(name); ok {
+			body.DataId = name
+			body.Description = name
+		}
+	case scopeMemory:
+		if addr, err := ParseNumber(name); err == nil {
+			body.DataId = fmt.Sprintf("%03X-%03X", addr, addr+MEMORY_ROW-1)
+			body.Description = fmt.Sprintf("%s-0x%03X", hex16(addr), addr+MEMORY_ROW-1)
+		}
+	}
+
+	if body.DataId == nil {
+		body.AccessTypes = nil
+		body.Description = fmt.Sprintf("%s can't be watched", name)
+	}
+
+	return s.send(&dap.DataBreakpointInfoResponse{Response: response(r), Body: body})
+}
+
+func (s *DAPSession) setDataBreakpoints(r *dap.SetDataBreakpointsRequest) error {
+	for _, id := range s.dataBreakpoints {
+		s.dbg.Unwatch(id)
+	}
+	s.dataBreakpoints = nil
+	bps := []dap.Breakpoint{}
+
+	for _, db := range r.Arguments.Breakpoints {
+		bp := dap.Breakpoint{Id: s.nextBreakpointID()}
+
+		access := "w"
+		switch db.AccessType {
+		case "read":
+			access = "r"
+		case "readWrite":
+			access = "rw"
+		}
+
+		w, err := ParseWatchpoint(db.DataId + " " + access)
+		if err != nil {
+			bp.Message = err.Error()
+		} else {
+			bp.Verified = true
+			s.dataBreakpoints = append(s.dataBreakpoints, s.dbg.Watch(w))
+		}
+
+		bps = append(bps, bp)
+	}
+
+	return s.send(&dap.SetDataBreakpointsResponse{
+		Response: response(r),
+		Body:     dap.SetDataBreakpointsResponseBody{Breakpoints: bps},
+	})
+}
+
+// -- Inspection
+
+// label returns the name of the label at addr, or an empty string.
+func (s *DAPSession) label(addr uint16) string {
+	return s.names[addr]
+}
+
+// source returns where addr was compiled from, if the symbols know.
+func (s *DAPSession) source(addr uint16) (*dap.Source, octo.Pos) {
+	if s.symbols == nil {
+		return nil, octo.Pos{}
+	}
+
+	pos, ok := s.symbols.Lines[addr]
+	if !ok || pos.Filename == "" {
+		return nil, octo.Pos{}
+	}
+
+	path := s.resolvePath(pos.Filename)
+	return &dap.Source{Name: filepath.Base(path), Path: path}, pos
+}
+
+// frames returns the address each stack frame is executing, innermost
+// first, and the entry point of its subroutine.
+func (s *DAPSession) frames() (addrs, entries []uint16) {
+	state := s.dbg.Emulator().State()
+
+	addrs = append(addrs, state.PC)
+	for idx := len(state.Stack) - 1; idx >= 0; idx-- {
+		site := state.Stack[idx] - 2
+		entries = append(entries, s.dbg.Instruction(site).NNN)
+		addrs = append(addrs, site)
+	}
+	entries = append(entries, emulator.ROM_START)
+
+	return addrs, entries
+}
+
+func (s *DAPSession) stackTrace(r *dap.StackTraceRequest) error {
+	addrs, entries := s.frames()
+
+	frames := []dap.StackFrame{}
+	for idx, addr := range addrs {
+		name := s.label(entries[idx])
+		if name == "" {
+			name = fmt.Sprintf("sub_%03X", entries[idx])
+		}
+
+		frame := dap.StackFrame{
+			Id:                          idx + 1,
+			Name:                        name,
+			InstructionPointerReference: fmt.Sprintf("0x%03X", addr),
+		}
+		if src, pos := s.source(addr); src != nil {
+			frame.Source = src
+			frame.Line = pos.Line
+			frame.Column = pos.Column
+		}
+
+		frames = append(frames, frame)
+	}
+
+	total := len(frames)
+	start := min(r.Arguments.StartFrame, total)
+	frames = frames[start:]
+	if r.Arguments.Levels > 0 && r.Arguments.Levels < len(frames) {
+		frames = frames[:r.Arguments.Levels]
+	}
+
+	return s.send(&dap.StackTraceResponse{
+		Response: response(r),
+		Body:     dap.StackTraceResponseBody{StackFrames: frames, TotalFrames: total},
+	})
+}
+
+func (s *DAPSession) scopes(r *dap.ScopesRequest) error {
+	return s.send(&dap.ScopesResponse{
+		Response: response(r),
+		Body: dap.ScopesResponseBody{Scopes: []dap.Scope{
+			{Name: "Registers", PresentationHint: "registers", VariablesReference: scopeRegisters, NamedVariables: 18},
+			{Name: "Timers", VariablesReference: scopeTimers, NamedVariables: 2},
+			{Name: "Stack", VariablesReference: scopeStack, IndexedVariables: len(s.dbg.Emulator().Stack())},
+			{Name: "Memory", VariablesReference: scopeMemory, IndexedVariables: emulator.MEM_SIZE / MEMORY_ROW, Expensive: true},
+		}},
+	})
+}
+
+func hex8(v byte) string {
+	return fmt.Sprintf("0x%02X", v)
+}
+
+func hex16(v uint16) string {
+	return fmt.Sprintf("0x%03X", v)
+}
+
+func (s *DAPSession) variables(r *dap.VariablesRequest) error {
+	state := s.dbg.Emulator().State()
+	vars := []dap.Variable{}
+
+	switch r.Arguments.VariablesReference {
+	case scopeRegisters:
+		for reg, v := range state.V {
+			vars = append(vars, dap.Variable{Name: fmt.Sprintf("V%X", reg), Value: hex8(v), Type: "byte"})
+		}
+		vars = append(vars,
+			dap.Variable{Name: "I", Value: hex16(state.I), Type: "address", MemoryReference: hex16(state.I)},
+			dap.Variable{Name: "PC", Value: hex16(state.PC), Type: "address", MemoryReference: hex16(state.PC)},
+		)
+	case scopeTimers:
+		vars = append(vars,
+			dap.Variable{Name: "DT", Value: hex8(state.DelayTimer), Type: "byte"},
+			dap.Variable{Name: "ST", Value: hex8(state.SoundTimer), Type: "byte"},
+		)
+	case scopeStack:
+		for idx, ret := range state.Stack {
+			vars = append(vars, dap.Variable{Name: fmt.Sprintf("[%d]", idx), Value: hex16(ret), Type: "address", MemoryReference: hex16(ret)})
+		}
+	case scopeMemory:
+		rows := emulator.MEM_SIZE / MEMORY_ROW
+		start := min(r.Arguments.Start, rows)
+		end := rows
+		if r.Arguments.Count > 0 {
+			end = min(start+r.Arguments.Count, rows)
+		}
+
+		buf := make([]byte, MEMORY_ROW)
+		for row := start; row < end; row++ {
+			addr := uint16(row * MEMORY_ROW)
+			s.dbg.Emulator().ReadMemory(addr, buf)
+			vars = append(vars, dap.Variable{Name: hex16(addr), Value: hexBytes(buf), MemoryReference: hex16(addr)})
+		}
+	default:
+		return s.fail(r, fmt.Errorf("unknown variables reference %d", r.Arguments.VariablesReference))
+	}
+
+	return s.send(&dap.VariablesResponse{
+		Response: response(r),
+		Body:     dap.VariablesResponseBody{Variables: vars},
+	})
+}
+
+func hexBytes(b []byte) string {
+	parts := make([]string, len(b))
+	for idx, v := range b {
+		parts[idx] = fmt.Sprintf("%02X", v)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (s *DAPSession) setVariable(r *dap.SetVariableRequest) error {
+	if s.dbg.Running() {
+		return s.fail(r, errNotPaused)
+	}
+
+	args := r.Arguments
+	var value string
+
+	switch args.VariablesReference {
+	case scopeRegisters, scopeTimers:
+		n, err := ParseNumber(args.Value)
+		if err == nil {
+			err = s.dbg.SetRegister(args.Name, n)
+		}
+		if err != nil {
+			return s.fail(r, err)
+		}
+
+		if strings.HasPrefix(strings.ToUpper(args.Name), "V") || args.VariablesReference == scopeTimers {
+			value = hex8(byte(n))
+		} else {
+			value = hex16(n)
+		}
+	case scopeMemory:
+		addr, err := ParseNumber(args.Name)
+		if err != nil {
+			return s.fail(r, err)
+		}
+
+		data, err := ParseBytes(args.Value)
+		if err == nil {
+			err = s.dbg.WriteMemory(addr, data)
+		}
+		if err != nil {
+			return s.fail(r, err)
+		}
+
+		buf := make([]byte, MEMORY_ROW)
+		s.dbg.Emulator().ReadMemory(addr, buf)
+		value = hexBytes(buf)
+	default:
+		return s.fail(r, fmt.Errorf("variables in reference %d can't be set", args.VariablesReference))
+	}
+
+	return s.send(&dap.SetVariableResponse{
+		Response: response(r),
+		Body:     dap.SetVariableResponseBody{Value: value},
+	})
+}
+
+func (s *DAPSession) readMemory(r *dap.ReadMemoryRequest) error {
+	ref, err := ParseNumber(r.Arguments.MemoryReference)
+	if err != nil {
+		return s.fail(r, err)
+	}
+
+	addr := int(ref) + r.Arguments.Offset
+	body := dap.ReadMemoryResponseBody{Address: fmt.Sprintf("0x%03X", max(addr, 0))}
+
+	if addr >= 0 && addr < emulator.MEM_SIZE {
+		buf := make([]byte, min(r.Arguments.Count, emulator.MEM_SIZE-addr))
+		s.dbg.Emulator().ReadMemory(uint16(addr), buf)
+		body.Data = base64.StdEncoding.EncodeToString(buf)
+		body.UnreadableBytes = r.Arguments.Count - len(buf)
+	} else {
+		body.UnreadableBytes = r.Arguments.Count
+	}
+
+	return s.send(&dap.ReadMemoryResponse{Response: response(r), Body: body})
+}
+
+func (s *DAPSession) writeMemory(r *dap.WriteMemoryRequest) error {
+	ref, err := ParseNumber(r.Arguments.MemoryReference)
+	if err != nil {
+		return s.fail(r, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(r.Arguments.Data)
+	if err != nil {
+		return s.fail(r, err)
+	}
+
+	addr := int(ref) + r.Arguments.Offset
+	if addr < 0 || addr >= emulator.MEM_SIZE {
+		return s.fail(r, fmt.Errorf("invalid address 0x%X", addr))
+	}
+	if r.Arguments.AllowPartial {
+		data = data[:min(len(data), emulator.MEM_SIZE-addr)]
+	}
+
+	if err := s.dbg.WriteMemory(uint16(addr), data); err != nil {
+		return s.fail(r, err)
+	}
+
+	return s.send(&dap.WriteMemoryResponse{
+		Response: response(r),
+		Body:     dap.WriteMemoryResponseBody{BytesWritten: len(data)},
+	})
+}
+
+func (s *DAPSession) disassemble(r *dap.DisassembleRequest) error {
+	ref, err := ParseNumber(r.Arguments.MemoryReference)
+	if err != nil {
+		return s.fail(r, err)
+	}
+
+	instructions := []dap.DisassembledInstruction{}
+	addr := int(ref) + r.Arguments.Offset + r.Arguments.InstructionOffset*2
+	for range r.Arguments.InstructionCount {
+		if addr < 0 || addr+1 >= emulator.MEM_SIZE {
+			// The protocol wants exactly the requested number of instructions
+			instructions = append(instructions, dap.DisassembledInstruction{
+				Address:     fmt.Sprintf("0x%03X", max(addr, 0)),
+				Instruction: "??",
+			})
+			addr += 2
+			continue
+		}
+
+		in := s.dbg.Instruction(uint16(addr))
+		di := dap.DisassembledInstruction{
+			Address:          fmt.Sprintf("0x%03X", addr),
+			InstructionBytes: fmt.Sprintf("%02X %02X", in.Opcode>>8, in.Opcode&0xFF),
+			Instruction:      in.Format(s.label),
+			Symbol:           s.label(uint16(addr)),
+		}
+		if src, pos := s.source(uint16(addr)); src != nil {
+			di.Location = src
+			di.Line = pos.Line
+			di.Column = pos.Column
+		}
+
+		instructions = append(instructions, di)
+		addr += 2
+	}
+
+	return s.send(&dap.DisassembleResponse{
+		Response: response(r),
+		Body:     dap.DisassembleResponseBody{Instructions: instructions},
+	})
+}
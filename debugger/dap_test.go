@@ -0,0 +1,344 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+)
+
+// 0x200 LD V0, 1; 0x202 CALL sub; 0x204 LD V2, 3; 0x206 JMP 0x206
+// 0x208 LD V1, 2; 0x20A RET
+const dapSource = `: main
+	v0 := 1
+	sub
+	v2 := 3
+	loop again
+: sub
+	v1 := 2
+	return
+`
+
+// dapClient scripts a DAP session the way an editor would.
+type dapClient struct {
+	t   *testing.T
+	r   *bufio.Reader
+	w   io.Writer
+	seq int
+}
+
+func setupDAP(t *testing.T) (*dapClient, string) {
+	path := filepath.Join(t.TempDir(), "test.8o")
+	if err := os.WriteFile(path, []byte(dapSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+
+	s := NewDAPSession(toServer, fromServer)
+	s.clock = time.Millisecond
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve()
+		fromServer.Close()
+	}()
+
+	t.Cleanup(func() {
+		fromClient.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	return &dapClient{t: t, r: bufio.NewReader(toClient), w: fromClient}, path
+}
+
+func (c *dapClient) send(command string, args any) {
+	c.seq++
+	msg, err := json.Marshal(map[string]any{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": args,
+	})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if err := dap.WriteBaseMessage(c.w, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *dapClient) read() dap.Message {
+	msg, err := dap.ReadProtocolMessage(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return msg
+}
+
+// request sends a request and returns its response.
+func (c *dapClient) request(command string, args any) dap.Message {
+	c.send(command, args)
+	return c.read()
+}
+
+// stopped reads a stopped event and returns its reason.
+func (c *dapClient) stopped() string {
+	ev, ok := c.read().(*dap.StoppedEvent)
+	if !ok {
+		c.t.Fatal("expected a stopped event")
+	}
+
+	return ev.Body.Reason
+}
+
+func (c *dapClient) frames() []dap.StackFrame {
+	return c.request("stackTrace", map[string]any{"threadId": DAP_THREAD_ID}).(*dap.StackTraceResponse).Body.StackFrames
+}
+
+func TestDAPSession(t *testing.T) {
+	assert := assert.New(t)
+	c, path := setupDAP(t)
+
+	init := c.request("initialize", map[string]any{"adapterID": "gr8"}).(*dap.InitializeResponse)
+	assert.True(init.Body.SupportsConfigurationDoneRequest)
+
+	_, ok := c.request("launch", map[string]any{"program": path, "stopOnEntry": true}).(*dap.LaunchResponse)
+	assert.True(ok)
+	_, ok = c.read().(*dap.InitializedEvent)
+	assert.True(ok)
+
+	bps := c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []map[string]any{{"line": 6}, {"line": 20}},
+	}).(*dap.SetBreakpointsResponse).Body.Breakpoints
+	assert.True(bps[0].Verified)
+	assert.Equal(7, bps[0].Line)
+	assert.Equal("0x208", bps[0].InstructionReference)
+	assert.False(bps[1].Verified)
+
+	c.request("configurationDone", nil)
+	assert.Equal("entry", c.stopped())
+
+	frames := c.frames()
+	assert.Len(frames, 1)
+	assert.Equal("main", frames[0].Name)
+	assert.Equal(2, frames[0].Line)
+	assert.Equal(path, frames[0].Source.Path)
+
+	// Stops at the first line of sub, called from main
+	c.request("continue", map[string]any{"threadId": DAP_THREAD_ID})
+	assert.Equal("breakpoint", c.stopped())
+
+	frames = c.frames()
+	assert.Len(frames, 2)
+	assert.Equal("sub", frames[0].Name)
+	assert.Equal(7, frames[0].Line)
+	assert.Equal("main", frames[1].Name)
+	assert.Equal(3, frames[1].Line)
+	assert.Equal("0x202", frames[1].InstructionPointerReference)
+
+	scopes := c.request("scopes", map[string]any{"frameId": 1}).(*dap.ScopesResponse).Body.Scopes
+	assert.Equal("Registers", scopes[0].Name)
+
+	vars := c.request("variables", map[string]any{"variablesReference": scopes[0].VariablesReference}).(*dap.VariablesResponse).Body.Variables
+	assert.Equal("V0", vars[0].Name)
+	assert.Equal("0x01", vars[0].Value)
+
+	stack := c.request("variables", map[string]any{"variablesReference": scopes[2].VariablesReference}).(*dap.VariablesResponse).Body.Variables
+	assert.Equal("0x204", stack[0].Value)
+
+	mem := c.request("variables", map[string]any{"variablesReference": scopes[3].VariablesReference, "start": 0x20, "count": 1}).(*dap.VariablesResponse).Body.Variables
+	assert.Len(mem, 1)
+	assert.Equal("0x200", mem[0].Name)
+	assert.Equal("60 01 22 08 62 03 12 06 61 02 00 EE 00 00 00 00", mem[0].Value)
+
+	set := c.request("setVariable", map[string]any{"variablesReference": scopes[0].VariablesReference, "name": "V3", "value": "0x44"}).(*dap.SetVariableResponse)
+	assert.Equal("0x44", set.Body.Value)
+
+	// Stepping over an instruction that isn't a call stops straight away
+	c.request("next", map[string]any{"threadId": DAP_THREAD_ID})
+	assert.Equal("step", c.stopped())
+	assert.Equal(8, c.frames()[0].Line)
+
+	c.request("stepOut", map[string]any{"threadId": DAP_THREAD_ID})
+	assert.Equal("step", c.stopped())
+	frames = c.frames()
+	assert.Len(frames, 1)
+	assert.Equal(4, frames[0].Line)
+
+	read := c.request("readMemory", map[string]any{"memoryReference": "0x200", "count": 4}).(*dap.ReadMemoryResponse)
+	data, err := base64.StdEncoding.DecodeString(read.Body.Data)
+	assert.Nil(err)
+	assert.Equal([]byte{0x60, 0x01, 0x22, 0x08}, data)
+
+	_, ok = c.request("writeMemory", map[string]any{"memoryReference": "0x300", "data": base64.StdEncoding.EncodeToString([]byte{0xAB})}).(*dap.WriteMemoryResponse)
+	assert.True(ok)
+	read = c.request("readMemory", map[string]any{"memoryReference": "0x2FF", "offset": 1, "count": 1}).(*dap.ReadMemoryResponse)
+	assert.Equal(base64.StdEncoding.EncodeToString([]byte{0xAB}), read.Body.Data)
+
+	dis := c.request("disassemble", map[string]any{"memoryReference": "0x200", "instructionCount": 2}).(*dap.DisassembleResponse).Body.Instructions
+	assert.Equal("LD V0, 0x01", dis[0].Instruction)
+	assert.Equal("main", dis[0].Symbol)
+	assert.Equal("CALL sub", dis[1].Instruction)
+
+	// Address breakpoints are kept alongside source breakpoints
+	ibps := c.request("setInstructionBreakpoints", map[string]any{
+		"breakpoints": []map[string]any{{"instructionReference": "0x206"}},
+	}).(*dap.SetInstructionBreakpointsResponse).Body.Breakpoints
+	assert.True(ibps[0].Verified)
+
+	c.request("continue", map[string]any{"threadId": DAP_THREAD_ID})
+	assert.Equal("breakpoint", c.stopped())
+	assert.Equal(5, c.frames()[0].Line)
+
+	fbps := c.request("setFunctionBreakpoints", map[string]any{
+		"breakpoints": []map[string]any{{"name": "sub"}, {"name": "nope"}},
+	}).(*dap.SetFunctionBreakpointsResponse).Body.Breakpoints
+	assert.True(fbps[0].Verified)
+	assert.Equal("0x208", fbps[0].InstructionReference)
+	assert.False(fbps[1].Verified)
+
+	c.request("pause", map[string]any{"threadId": DAP_THREAD_ID})
+	assert.Equal("pause", c.stopped())
+
+	_, ok = c.request("disconnect", nil).(*dap.DisconnectResponse)
+	assert.True(ok)
+}
+
+func TestDAPSymbols(t *testing.T) {
+	assert := assert.New(t)
+	c, path := setupDAP(t)
+	dir := filepath.Dir(path)
+
+	// A compiled ROM with a symbol file using paths relative to cwd
+	rom := filepath.Join(dir, "test.ch8")
+	syms := filepath.Join(dir, "test.sym")
+	assert.Nil(os.WriteFile(rom, []byte{0x60, 0x01, 0x12, 0x02}, 0o644))
+	assert.Nil(os.WriteFile(syms, []byte(`{"labels": {"main": 512}, "lines": [{"address": 512, "file": "test.8o", "line": 2, "column": 2}]}`), 0o644))
+
+	c.request("initialize", nil)
+	c.request("launch", map[string]any{"program": rom, "symbols": syms, "cwd": dir, "stopOnEntry": true})
+	c.read()
+
+	bps := c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []map[string]any{{"line": 2}},
+	}).(*dap.SetBreakpointsResponse).Body.Breakpoints
+	assert.True(bps[0].Verified)
+	assert.Equal("0x200", bps[0].InstructionReference)
+
+	c.request("configurationDone", nil)
+	assert.Equal("entry", c.stopped())
+	assert.Equal(path, c.frames()[0].Source.Path)
+
+	c.request("terminate", nil)
+	_, ok := c.read().(*dap.TerminatedEvent)
+	assert.True(ok)
+}
+
+func TestDAPConditions(t *testing.T) {
+	assert := assert.New(t)
+	c, path := setupDAP(t)
+
+	init := c.request("initialize", nil).(*dap.InitializeResponse)
+	assert.True(init.Body.SupportsConditionalBreakpoints)
+	assert.True(init.Body.SupportsLogPoints)
+
+	c.request("launch", map[string]any{"program": path})
+	c.read()
+
+	bps := c.request("setBreakpoints", map[string]any{
+		"source": map[string]any{"path": path},
+		"breakpoints": []map[string]any{
+			{"line": 4, "logMessage": "v1={v1}"},
+			{"line": 5, "condition": "v2 == 3", "hitCondition": "3"},
+			{"line": 2, "condition": "v2 =="},
+		},
+	}).(*dap.SetBreakpointsResponse).Body.Breakpoints
+	assert.True(bps[0].Verified)
+	assert.True(bps[1].Verified)
+	assert.False(bps[2].Verified)
+	assert.Equal("condition: column 6: expected a value, got the end of the expression", bps[2].Message)
+
+	c.request("configurationDone", nil)
+
+	out, ok := c.read().(*dap.OutputEvent)
+	assert.True(ok)
+	assert.Equal("v1=2\n", out.Body.Output)
+	assert.Equal("breakpoint", c.stopped())
+	assert.Equal(5, c.frames()[0].Line)
+
+	c.request("disconnect", nil)
+}
+
+func TestDAPDataBreakpoints(t *testing.T) {
+	assert := assert.New(t)
+	c, path := setupDAP(t)
+
+	c.request("initialize", nil)
+	c.request("launch", map[string]any{"program": path, "stopOnEntry": true})
+	c.read()
+
+	info := c.request("dataBreakpointInfo", map[string]any{"variablesReference": scopeRegisters, "name": "V2"}).(*dap.DataBreakpointInfoResponse)
+	assert.Equal("V2", info.Body.DataId)
+
+	info = c.request("dataBreakpointInfo", map[string]any{"variablesReference": scopeRegisters, "name": "PC"}).(*dap.DataBreakpointInfoResponse)
+	assert.Nil(info.Body.DataId)
+
+	info = c.request("dataBreakpointInfo", map[string]any{"variablesReference": scopeMemory, "name": "0x300"}).(*dap.DataBreakpointInfoResponse)
+	assert.Equal("300-30F", info.Body.DataId)
+
+	bps := c.request("setDataBreakpoints", map[string]any{
+		"breakpoints": []map[string]any{{"dataId": "V2", "accessType": "write"}, {"dataId": "PC"}},
+	}).(*dap.SetDataBreakpointsResponse).Body.Breakpoints
+	assert.True(bps[0].Verified)
+	assert.False(bps[1].Verified)
+
+	c.request("configurationDone", nil)
+	assert.Equal("entry", c.stopped())
+
+	c.request("continue", map[string]any{"threadId": DAP_THREAD_ID})
+	ev, ok := c.read().(*dap.StoppedEvent)
+	assert.True(ok)
+	assert.Equal("data breakpoint", ev.Body.Reason)
+	assert.Equal("0x204 (6203) wrote V2: 0x00 -> 0x03", ev.Body.Text)
+	assert.Equal(5, c.frames()[0].Line)
+
+	c.request("disconnect", nil)
+}
+
+func TestDAPErrors(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := setupDAP(t)
+
+	resp := c.request("threads", nil).(*dap.ErrorResponse)
+	assert.False(resp.Success)
+	assert.Equal(ErrNotLaunched.Error(), resp.Message)
+
+	resp = c.request("launch", map[string]any{}).(*dap.ErrorResponse)
+	assert.Equal("launch: missing program", resp.Message)
+
+	resp = c.request("launch", map[string]any{"program": "missing.ch8"}).(*dap.ErrorResponse)
+	assert.Contains(resp.Message, "missing.ch8")
+
+	resp = c.request("launch", map[string]any{"program": "missing.ch8", "quirks": "chip48"}).(*dap.ErrorResponse)
+	assert.Contains(resp.Message, `unknown quirks preset "chip48"`)
+
+	resp = c.request("launch", map[string]any{"program": "missing.ch8", "engine": "jit"}).(*dap.ErrorResponse)
+	assert.Contains(resp.Message, `unknown engine "jit"`)
+
+	c.request("disconnect", nil)
+}
@@ -0,0 +1,597 @@
+// Package debugger implements an interactive CHIP-8 debugger.
+//
+// A Debugger drives an emulator one instruction at a time through Step, so it
+// needs no clock or window of its own. Frontends call Tick at 60Hz while it
+// is running and inspect the machine whenever it is paused.
+package debugger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+var ErrNotInSubroutine = errors.New("not in a subroutine")
+var ErrUnknownRegister = errors.New("unknown register")
+
+// StopReason explains why execution paused.
+type StopReason int
+
+const (
+	// Still running, or never started
+	StopNone StopReason = iota
+
+	// Paused by the user
+	StopPause
+
+	// Reached a breakpoint
+	StopBreakpoint
+
+	// Finished a step, step over or step out
+	StopStep
+
+	// The emulator returned an error
+	StopFault
+
+	// An instruction hit a watchpoint
+	StopWatchpoint
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopPause:
+		return "paused"
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopStep:
+		return "step"
+	case StopFault:
+		return "fault"
+	case StopWatchpoint:
+		return "watchpoint"
+	}
+
+	return "running"
+}
+
+// Debugger controls execution of an emulator.
+type Debugger struct {
+	emu emulator.Debuggable
+
+	// Breakpoints by address, and those checked before every instruction
+	breakpoints map[uint16]*Breakpoint
+	anywhere    []*Breakpoint
+	logHooks    []LogHook
+
+	running bool
+
+	// Watchpoints by ID, and those hit by the last instruction
+	watchpoints map[int]emulator.Watchpoint
+	hits        []emulator.WatchHit
+
+	// Stops execution once it returns true, for step over and step out
+	until func(s emulator.State) bool
+
+	// Don't stop at the breakpoint under the PC when resuming from it
+	resuming bool
+
+	reason StopReason
+	err    error
+}
+
+// New returns a paused debugger for emu.
+func New(emu emulator.Debuggable) *Debugger {
+	d := &Debugger{
+		emu:         emu,
+		breakpoints: map[uint16]*Breakpoint{},
+		watchpoints: map[int]emulator.Watchpoint{},
+	}
+	emu.OnWatch(func(hit emulator.WatchHit) {
+		d.hits = append(d.hits, hit)
+	})
+
+	return d
+}
+
+// Emulator returns the emulator being debugged.
+func (d *Debugger) Emulator() emulator.Debuggable {
+	return d.emu
+}
+
+// Running reports whether execution is in progress.
+func (d *Debugger) Running() bool {
+	return d.running
+}
+
+// Stopped returns why execution last stopped, with the emulator error for
+// faults and the error of breakpoint conditions that failed to evaluate.
+func (d *Debugger) Stopped() (StopReason, error) {
+	return d.reason, d.err
+}
+
+// -- Breakpoints
+
+// LogHook receives the messages of logpoints as they are hit.
+type LogHook func(bp *Breakpoint, msg string)
+
+// SetBreakpoint adds a breakpoint at addr.
+func (d *Debugger) SetBreakpoint(addr uint16) {
+	d.breakpoints[addr] = &Breakpoint{Addr: addr}
+}
+
+// AddBreakpoint adds a copy of bp, replacing any breakpoint at the same
+// address, and returns it.
+func (d *Debugger) AddBreakpoint(bp Breakpoint) *Breakpoint {
+	if bp.Anywhere {
+		d.anywhere = append(d.anywhere, &bp)
+	} else {
+		d.breakpoints[bp.Addr] = &bp
+	}
+
+	return &bp
+}
+
+// RemoveBreakpoint removes bp, which was returned by AddBreakpoint or
+// Breakpoint.
+func (d *Debugger) RemoveBreakpoint(bp *Breakpoint) {
+	if !bp.Anywhere {
+		if d.breakpoints[bp.Addr] == bp {
+			delete(d.breakpoints, bp.Addr)
+		}
+		return
+	}
+
+	d.anywhere = slices.DeleteFunc(d.anywhere, func(other *Breakpoint) bool {
+		return other == bp
+	})
+}
+
+// ClearBreakpoint removes the breakpoint at addr.
+func (d *Debugger) ClearBreakpoint(addr uint16) {
+	delete(d.breakpoints, addr)
+}
+
+// ClearBreakpoints removes every breakpoint.
+func (d *Debugger) ClearBreakpoints() {
+	clear(d.breakpoints)
+	d.anywhere = nil
+}
+
+// ToggleBreakpoint adds or removes the breakpoint at addr, and reports
+// whether one is now set.
+func (d *Debugger) ToggleBreakpoint(addr uint16) bool {
+	if d.breakpoints[addr] != nil {
+		d.ClearBreakpoint(addr)
+		return false
+	}
+
+	d.SetBreakpoint(addr)
+	return true
+}
+
+// HasBreakpoint reports whether there is a breakpoint at addr.
+func (d *Debugger) HasBreakpoint(addr uint16) bool {
+	return d.breakpoints[addr] != nil
+}
+
+// Breakpoint returns the breakpoint at addr, or nil.
+func (d *Debugger) Breakpoint(addr uint16) *Breakpoint {
+	return d.breakpoints[addr]
+}
+
+// AnywhereBreakpoints returns the breakpoints checked before every
+// instruction, in the order they were added.
+func (d *Debugger) AnywhereBreakpoints() []*Breakpoint {
+	return slices.Clone(d.anywhere)
+}
+
+// OnLog registers a hook that is called with the message of every logpoint hit.
+func (d *Debugger) OnLog(hook LogHook) {
+	d.logHooks = append(d.logHooks, hook)
+}
+
+// checkBreakpoints checks the breakpoints for the instruction about to run,
+// and stops if one of them says to.
+func (d *Debugger) checkBreakpoints() bool {
+	if bp := d.breakpoints[d.emu.PC()]; bp != nil && d.checkBreakpoint(bp) {
+		return true
+	}
+
+	for _, bp := range d.anywhere {
+		if d.checkBreakpoint(bp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkBreakpoint counts a hit on bp if its condition holds, and either logs
+// its message or stops. Conditions that fail to evaluate stop with their error.
+func (d *Debugger) checkBreakpoint(bp *Breakpoint) bool {
+	if bp.Condition != nil {
+		ok, err := bp.Condition.True(d.emu)
+		if err != nil {
+			d.stop(StopBreakpoint, fmt.Errorf("condition %q: %w", bp.Condition, err))
+			return true
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	bp.Hits++
+	if bp.HitCondition != nil && !bp.HitCondition.Match(bp.Hits) {
+		return false
+	}
+
+	if bp.Log != nil {
+		msg := bp.Log.Format(d.emu)
+		for _, hook := range d.logHooks {
+			hook(bp, msg)
+		}
+		return false
+	}
+
+	d.stop(StopBreakpoint, nil)
+	return true
+}
+
+// Breakpoints returns the addresses of all breakpoints in order.
+func (d *Debugger) Breakpoints() []uint16 {
+	addrs := make([]uint16, 0, len(d.breakpoints))
+	for addr := range d.breakpoints {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+
+	return addrs
+}
+
+// -- Watchpoints
+
+// Watch adds a watchpoint and returns its ID. Execution stops after any
+// instruction that triggers it.
+func (d *Debugger) Watch(w emulator.Watchpoint) int {
+	id := d.emu.Watch(w)
+	d.watchpoints[id] = w
+
+	return id
+}
+
+// Unwatch removes the watchpoint with the given ID.
+func (d *Debugger) Unwatch(id int) {
+	d.emu.Unwatch(id)
+	delete(d.watchpoints, id)
+}
+
+// ToggleWatchpoint adds w, or removes it if an identical watchpoint is set,
+// and reports whether it is now set.
+func (d *Debugger) ToggleWatchpoint(w emulator.Watchpoint) bool {
+	for id, existing := range d.watchpoints {
+		if existing == w {
+			d.Unwatch(id)
+			return false
+		}
+	}
+
+	d.Watch(w)
+	return true
+}
+
+// Watchpoints returns the watchpoints that are set, by ID.
+func (d *Debugger) Watchpoints() map[int]emulator.Watchpoint {
+	return maps.Clone(d.watchpoints)
+}
+
+// Hits returns the watchpoint hits of the last instruction run.
+func (d *Debugger) Hits() []emulator.WatchHit {
+	return d.hits
+}
+
+// -- Execution
+
+// Instruction decodes the instruction at addr.
+func (d *Debugger) Instruction(addr uint16) disasm.Instruction {
+	buf := make([]byte, 2)
+	d.emu.ReadMemory(addr, buf)
+
+	return disasm.Decode(binary.BigEndian.Uint16(buf))
+}
+
+func (d *Debugger) stop(reason StopReason, err error) {
+	d.running = false
+	d.until = nil
+	d.reason = reason
+	d.err = err
+}
+
+func (d *Debugger) resume(until func(s emulator.State) bool) {
+	d.running = true
+	d.until = until
+	d.resuming = true
+	d.reason = StopNone
+	d.err = nil
+}
+
+// Pause stops execution.
+func (d *Debugger) Pause() {
+	if d.running {
+		d.stop(StopPause, nil)
+	}
+}
+
+// Continue resumes execution until a breakpoint is hit.
+func (d *Debugger) Continue() {
+	d.resume(nil)
+}
+
+// step runs one instruction, collecting the watchpoints it hits.
+func (d *Debugger) step() error {
+	d.hits = nil
+	return d.emu.Step()
+}
+
+// Step runs a single instruction.
+func (d *Debugger) Step() error {
+	err := d.step()
+	if err != nil {
+		d.stop(StopFault, err)
+		return err
+	}
+
+	if len(d.hits) > 0 {
+		d.stop(StopWatchpoint, nil)
+	} else {
+		d.stop(StopStep, nil)
+	}
+	return nil
+}
+
+// StepOver runs a single instruction, treating subroutine calls as one
+// instruction. Execution continues in the background until the call returns.
+func (d *Debugger) StepOver() error {
+	s := d.emu.State()
+	if d.Instruction(s.PC).Mnemonic != "CALL" {
+		return d.Step()
+	}
+
+	ret, depth := s.PC+2, len(s.Stack)
+	d.resume(func(s emulator.State) bool {
+		return s.PC == ret && len(s.Stack) == depth
+	})
+
+	return nil
+}
+
+// StepOut continues until the current subroutine returns.
+func (d *Debugger) StepOut() error {
+	depth := len(d.emu.Stack())
+	if depth == 0 {
+		return ErrNotInSubroutine
+	}
+
+	d.resume(func(s emulator.State) bool {
+		return len(s.Stack) < depth
+	})
+
+	return nil
+}
+
+// Tick runs up to budget instructions while the debugger is running,
+// stopping early at breakpoints, after instructions that hit watchpoints and
+// when a step over or step out completes.
+func (d *Debugger) Tick(budget int) error {
+	for range budget {
+		if !d.running {
+			return nil
+		}
+
+		if !d.resuming && d.checkBreakpoints() {
+			return nil
+		}
+		d.resuming = false
+
+		err := d.step()
+		if err != nil {
+			d.stop(StopFault, err)
+			return err
+		}
+
+		if len(d.hits) > 0 {
+			d.stop(StopWatchpoint, nil)
+			return nil
+		}
+
+		if d.until != nil && d.until(d.emu.State()) {
+			d.stop(StopStep, nil)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// -- Editing
+
+// SetRegister sets a register by name: V0-VF, I, PC, DT or ST.
+func (d *Debugger) SetRegister(name string, value uint16) error {
+	s := d.emu.State()
+
+	name = strings.ToUpper(name)
+	switch {
+	case len(name) == 2 && name[0] == 'V':
+		reg, err := strconv.ParseUint(name[1:], 16, 8)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnknownRegister, name)
+		}
+		if value > 0xFF {
+			return fmt.Errorf("value 0x%X too large for %s", value, name)
+		}
+		s.V[reg] = byte(value)
+	case name == "I":
+		s.I = value
+	case name == "PC":
+		s.PC = value
+	case name == "DT" || name == "ST":
+		if value > 0xFF {
+			return fmt.Errorf("value 0x%X too large for %s", value, name)
+		}
+		if name == "DT" {
+			s.DelayTimer = byte(value)
+		} else {
+			s.SoundTimer = byte(value)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownRegister, name)
+	}
+
+	d.emu.SetState(s)
+	return nil
+}
+
+// WriteMemory stores data at addr.
+func (d *Debugger) WriteMemory(addr uint16, data []byte) error {
+	if int(addr)+len(data) > emulator.MEM_SIZE {
+		return fmt.Errorf("write of %d bytes at 0x%03X runs past the end of memory", len(data), addr)
+	}
+
+	d.emu.WriteMemory(addr, data)
+	return nil
+}
+
+// Keys returns the live keypad state as a bitmask, with key 0 in bit 0.
+func (d *Debugger) Keys() uint16 {
+	var mask uint16
+	for key := range uint8(16) {
+		if d.emu.Pressed(key) {
+			mask |= 1 << key
+		}
+	}
+
+	return mask
+}
+
+// ParseNumber parses a decimal, 0x hexadecimal or 0b binary number.
+func ParseNumber(s string) (uint16, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+
+	return uint16(n), nil
+}
+
+// ParseAddress parses a memory address. Bare numbers are hexadecimal.
+func ParseAddress(s string) (uint16, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+
+	addr, err := ParseNumber(s)
+	if err != nil || int(addr) >= emulator.MEM_SIZE {
+		return 0, fmt.Errorf("invalid address %q", strings.TrimPrefix(s, "0x"))
+	}
+
+	return addr, nil
+}
+
+// ParseBytes parses whitespace-separated hexadecimal bytes, such as "FF 0x00".
+func ParseBytes(s string) ([]byte, error) {
+	var data []byte
+	for _, field := range strings.Fields(s) {
+		b, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(field), "0x"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid byte %q", field)
+		}
+		data = append(data, byte(b))
+	}
+
+	return data, nil
+}
+
+// ParseWatchpoint parses a watchpoint such as "300-30F rw", "v3" or "dt r".
+// The target is an address, an inclusive range of addresses or a register
+// (V0-VF, I, DT or ST), followed by any of r, w and x. Watchpoints with no
+// accesses given watch for writes.
+func ParseWatchpoint(s string) (emulator.Watchpoint, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return emulator.Watchpoint{}, fmt.Errorf("expected a target and accesses, got %q", s)
+	}
+
+	access := emulator.AccessWrite
+	if len(fields) == 2 {
+		access = 0
+		for _, r := range strings.ToLower(fields[1]) {
+			switch r {
+			case 'r':
+				access |= emulator.AccessRead
+			case 'w':
+				access |= emulator.AccessWrite
+			case 'x':
+				access |= emulator.AccessExecute
+			default:
+				return emulator.Watchpoint{}, fmt.Errorf("invalid access %q, expected r, w or x", fields[1])
+			}
+		}
+	}
+
+	if reg, ok := parseRegister(fields[0]); ok {
+		if access&emulator.AccessExecute != 0 {
+			return emulator.Watchpoint{}, fmt.Errorf("register %s can't be executed", reg)
+		}
+		return emulator.WatchRegister(reg, access), nil
+	}
+
+	first, last, isRange := strings.Cut(fields[0], "-")
+	start, err := ParseAddress(first)
+	if err != nil {
+		return emulator.Watchpoint{}, err
+	}
+
+	end := start
+	if isRange {
+		end, err = ParseAddress(last)
+		if err != nil {
+			return emulator.Watchpoint{}, err
+		}
+		if end < start {
+			return emulator.Watchpoint{}, fmt.Errorf("range %q ends before it starts", fields[0])
+		}
+	}
+
+	return emulator.WatchMemory(start, end+1, access), nil
+}
+
+// parseRegister parses the name of a register that can be watched.
+func parseRegister(name string) (emulator.Register, bool) {
+	name = strings.ToUpper(name)
+	switch name {
+	case "I":
+		return emulator.RegI, true
+	case "DT":
+		return emulator.RegDT, true
+	case "ST":
+		return emulator.RegST, true
+	}
+
+	if len(name) != 2 || name[0] != 'V' {
+		return 0, false
+	}
+
+	reg, err := strconv.ParseUint(name[1:], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+
+	return emulator.Register(reg), true
+}
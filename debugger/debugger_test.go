@@ -0,0 +1,263 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/octo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 0x200 CALL 0x206; 0x202 ADD V1, 1; 0x204 JMP 0x204
+// 0x206 LD V0, 5; 0x208 CALL 0x20C; 0x20A RET; 0x20C RET
+const testSource = `
+: main
+	sub
+	v1 += 1
+	loop again
+: sub
+	v0 := 5
+	leaf
+	return
+: leaf
+	return
+`
+
+func setup(t *testing.T) *Debugger {
+	prog, err := octo.Compile(strings.NewReader(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(prog.ROM), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(emu)
+}
+
+func pc(d *Debugger) uint16 {
+	return d.Emulator().State().PC
+}
+
+func TestStep(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	assert.Nil(d.Step())
+	assert.Equal(uint16(0x206), pc(d))
+	assert.False(d.Running())
+
+	reason, err := d.Stopped()
+	assert.Equal(StopStep, reason)
+	assert.Nil(err)
+}
+
+func TestBreakpoint(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	d.SetBreakpoint(0x20A)
+	assert.Equal([]uint16{0x20A}, d.Breakpoints())
+
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.False(d.Running())
+	assert.Equal(uint16(0x20A), pc(d))
+
+	reason, _ := d.Stopped()
+	assert.Equal(StopBreakpoint, reason)
+
+	// Continuing runs past the breakpoint it stopped at
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.True(d.Running())
+
+	assert.False(d.ToggleBreakpoint(0x20A))
+	assert.Empty(d.Breakpoints())
+}
+
+func TestWatchpoint(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	// Stops after the instruction that writes V1
+	id := d.Watch(emulator.WatchRegister(1, emulator.AccessWrite))
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.False(d.Running())
+	assert.Equal(uint16(0x204), pc(d))
+
+	reason, _ := d.Stopped()
+	assert.Equal(StopWatchpoint, reason)
+	assert.Len(d.Hits(), 1)
+	assert.Equal("0x202 (7101) wrote V1: 0x00 -> 0x01", d.Hits()[0].String())
+
+	d.Unwatch(id)
+	assert.Empty(d.Watchpoints())
+
+	// Execute watchpoints report the instruction that was run
+	d = setup(t)
+	assert.True(d.ToggleWatchpoint(emulator.WatchMemory(0x20C, 0x20E, emulator.AccessExecute)))
+	d.Continue()
+	assert.Nil(d.Tick(100))
+	assert.Equal(uint16(0x20A), pc(d))
+	assert.Equal(uint16(0x20C), d.Hits()[0].PC)
+
+	// Single steps report watchpoints too
+	assert.False(d.ToggleWatchpoint(emulator.WatchMemory(0x20C, 0x20E, emulator.AccessExecute)))
+	d.Watch(emulator.WatchRegister(1, emulator.AccessRead))
+	assert.Nil(d.Step())
+	reason, _ = d.Stopped()
+	assert.Equal(StopStep, reason)
+	assert.Empty(d.Hits())
+
+	assert.Nil(d.Step())
+	reason, _ = d.Stopped()
+	assert.Equal(StopWatchpoint, reason)
+	assert.Equal("0x202 (7101) read V1: 0x00", d.Hits()[0].String())
+}
+
+func TestPause(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	d.Continue()
+	assert.Nil(d.Tick(10))
+	d.Pause()
+	assert.False(d.Running())
+
+	reason, _ := d.Stopped()
+	assert.Equal(StopPause, reason)
+}
+
+func TestStepOver(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	// Steps over the whole subroutine, including its nested call
+	assert.Nil(d.StepOver())
+	assert.True(d.Running())
+	assert.Nil(d.Tick(100))
+	assert.False(d.Running())
+	assert.Equal(uint16(0x202), pc(d))
+
+	// Single-steps anything that isn't a call
+	assert.Nil(d.StepOver())
+	assert.False(d.Running())
+	assert.Equal(uint16(0x204), pc(d))
+	assert.Equal(byte(1), d.Emulator().State().V[1])
+}
+
+func TestStepOut(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	assert.ErrorIs(d.StepOut(), ErrNotInSubroutine)
+
+	assert.Nil(d.Step())
+	assert.Nil(d.Step())
+	assert.Equal(uint16(0x208), pc(d))
+
+	assert.Nil(d.StepOut())
+	assert.Nil(d.Tick(100))
+	assert.Equal(uint16(0x202), pc(d))
+	assert.Empty(d.Emulator().State().Stack)
+}
+
+func TestSetRegister(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	assert.Nil(d.SetRegister("v3", 0x12))
+	assert.Nil(d.SetRegister("VF", 1))
+	assert.Nil(d.SetRegister("i", 0x300))
+	assert.Nil(d.SetRegister("PC", 0x204))
+	assert.Nil(d.SetRegister("dt", 60))
+	assert.Nil(d.SetRegister("st", 2))
+
+	s := d.Emulator().State()
+	assert.Equal(byte(0x12), s.V[3])
+	assert.Equal(byte(1), s.V[0xF])
+	assert.Equal(uint16(0x300), s.I)
+	assert.Equal(uint16(0x204), s.PC)
+	assert.Equal(byte(60), s.DelayTimer)
+	assert.Equal(byte(2), s.SoundTimer)
+
+	assert.ErrorIs(d.SetRegister("vg", 1), ErrUnknownRegister)
+	assert.ErrorIs(d.SetRegister("sp", 1), ErrUnknownRegister)
+	assert.NotNil(d.SetRegister("v0", 0x100))
+}
+
+func TestWriteMemory(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	assert.Nil(d.WriteMemory(0x300, []byte{0xAB, 0xCD}))
+
+	buf := make([]byte, 2)
+	d.Emulator().ReadMemory(0x300, buf)
+	assert.Equal([]byte{0xAB, 0xCD}, buf)
+
+	assert.NotNil(d.WriteMemory(emulator.MEM_SIZE-1, []byte{1, 2}))
+}
+
+func TestKeys(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	d.Emulator().Press(0x0)
+	d.Emulator().Press(0xA)
+	assert.Equal(uint16(0x0401), d.Keys())
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	n, err := ParseNumber("0x1F")
+	assert.Nil(err)
+	assert.Equal(uint16(0x1F), n)
+
+	n, err = ParseNumber("31")
+	assert.Nil(err)
+	assert.Equal(uint16(31), n)
+
+	_, err = ParseNumber("x")
+	assert.NotNil(err)
+
+	addr, err := ParseAddress("2a4")
+	assert.Nil(err)
+	assert.Equal(uint16(0x2A4), addr)
+
+	addr, err = ParseAddress("0x300")
+	assert.Nil(err)
+	assert.Equal(uint16(0x300), addr)
+
+	_, err = ParseAddress("1000")
+	assert.NotNil(err)
+
+	w, err := ParseWatchpoint("300-30f rw")
+	assert.Nil(err)
+	assert.Equal(emulator.WatchMemory(0x300, 0x310, emulator.AccessRead|emulator.AccessWrite), w)
+
+	w, err = ParseWatchpoint("2a4 x")
+	assert.Nil(err)
+	assert.Equal(emulator.WatchMemory(0x2A4, 0x2A5, emulator.AccessExecute), w)
+
+	w, err = ParseWatchpoint("vf")
+	assert.Nil(err)
+	assert.Equal(emulator.WatchRegister(0xF, emulator.AccessWrite), w)
+
+	w, err = ParseWatchpoint("DT r")
+	assert.Nil(err)
+	assert.Equal(emulator.WatchRegister(emulator.RegDT, emulator.AccessRead), w)
+
+	for _, bad := range []string{"", "v3 x", "310-300", "300 q", "vg", "300 r w"} {
+		_, err = ParseWatchpoint(bad)
+		assert.NotNil(err, bad)
+	}
+}
@@ -0,0 +1,518 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+var ErrDivideByZero = errors.New("division by zero")
+
+// ExprError is an error in an expression, with the column it occurred at.
+type ExprError struct {
+	// Byte offset into the source, starting at 0
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Expr is a compiled expression over machine state, such as
+// "pc == 0x2a4 && v3 > 5 && mem[i] != 0 && frame > 600".
+//
+// Values are integers, and comparisons and logical operators give 1 or 0.
+// The operators are those of C, without assignment:
+//
+//	|| && | ^ & == != < <= > >= << >> + - * / % ! ~
+//
+// Names are V0-VF, I, PC, DT, ST, SP (the stack depth) and frame (the number
+// of frames begun), with mem[addr] for memory and key[k] for the keypad. Names
+// are case-insensitive, and numbers can be decimal, 0x hexadecimal or 0b
+// binary.
+type Expr struct {
+	src  string
+	eval evalFunc
+}
+
+// env is the machine an expression is evaluated against. The state is read
+// once per evaluation.
+type env struct {
+	emu   emulator.Emulator
+	state emulator.State
+}
+
+type evalFunc func(e *env) (int, error)
+
+// ParseExpr compiles an expression.
+func ParseExpr(src string) (*Expr, error) {
+	return parseExprAt(src, 0)
+}
+
+// parseExprAt compiles an expression found at offset in a larger string, so
+// that errors point into the larger string.
+func parseExprAt(src string, offset int) (*Expr, error) {
+	p := &exprParser{src: src, offset: offset}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	eval, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+
+	return &Expr{src: strings.TrimSpace(src), eval: eval}, nil
+}
+
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Eval evaluates the expression against emu.
+func (x *Expr) Eval(emu emulator.Emulator) (int, error) {
+	return x.eval(&env{emu: emu, state: emu.State()})
+}
+
+// True evaluates the expression against emu and reports whether it is non-zero.
+func (x *Expr) True(emu emulator.Emulator) (bool, error) {
+	v, err := x.Eval(emu)
+	return v != 0, err
+}
+
+// -- Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	value int
+}
+
+// Operators, longest first so that "<=" isn't read as "<"
+var exprOps = []string{
+	"||", "&&", "==", "!=", "<=", ">=", "<<", ">>",
+	"|", "^", "&", "<", ">", "+", "-", "*", "/", "%", "!", "~", "(", ")", "[", "]",
+}
+
+type exprParser struct {
+	src    string
+	offset int
+	pos    int
+	tok    token
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return &ExprError{Pos: p.offset + p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next reads the next token into p.tok.
+func (p *exprParser) next() error {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+
+	start := p.pos
+	p.tok = token{kind: tokEOF, pos: start}
+	if p.pos >= len(p.src) {
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case isDigit(c):
+		for p.pos < len(p.src) && isWordByte(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+
+		n, err := strconv.ParseUint(p.tok.text, 0, 16)
+		if err != nil {
+			return p.errorf("invalid number %q", p.tok.text)
+		}
+		p.tok.value = int(n)
+	case isWordByte(c):
+		for p.pos < len(p.src) && isWordByte(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: strings.ToLower(p.src[start:p.pos]), pos: start}
+	default:
+		for _, op := range exprOps {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return nil
+			}
+		}
+		return p.errorf("unexpected character %q", c)
+	}
+
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return isDigit(c) || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expect consumes the operator op.
+func (p *exprParser) expect(op string) error {
+	if p.tok.kind != tokOp || p.tok.text != op {
+		return p.errorf("expected %q", op)
+	}
+
+	return p.next()
+}
+
+// -- Parser
+
+// Binary operators by precedence, loosest first
+var exprPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+// parseBinary parses operators that bind tighter than minPrec, by precedence
+// climbing.
+func (p *exprParser) parseBinary(minPrec int) (evalFunc, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		prec, ok := exprPrecedence[p.tok.text]
+		if p.tok.kind != tokOp || !ok || prec <= minPrec {
+			return lhs, nil
+		}
+
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		rhs, err := p.parseBinary(prec)
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = binaryOp(op, lhs, rhs)
+	}
+}
+
+func (p *exprParser) parseUnary() (evalFunc, error) {
+	if p.tok.kind != tokOp {
+		return p.parsePrimary()
+	}
+
+	op := p.tok.text
+	switch op {
+	case "!", "-", "~":
+	default:
+		return p.parsePrimary()
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(e *env) (int, error) {
+		v, err := operand(e)
+		switch op {
+		case "!":
+			return boolInt(v == 0), err
+		case "-":
+			return -v, err
+		}
+		return ^v, err
+	}, nil
+}
+
+func (p *exprParser) parsePrimary() (evalFunc, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return func(*env) (int, error) { return tok.value, nil }, nil
+	case tok.kind == tokName:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return p.parseName(tok)
+	case tok.kind == tokOp && tok.text == "(":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case tok.kind == tokEOF:
+		return nil, p.errorf("expected a value, got the end of the expression")
+	}
+
+	return nil, p.errorf("expected a value, got %q", tok.text)
+}
+
+// parseName parses a register, or memory or keypad indexing.
+func (p *exprParser) parseName(tok token) (evalFunc, error) {
+	switch tok.text {
+	case "mem", "key":
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		index, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+
+		if tok.text == "key" {
+			return func(e *env) (int, error) {
+				k, err := index(e)
+				if err != nil || k < 0 || k > 0xF {
+					return 0, err
+				}
+				return boolInt(e.emu.Pressed(uint8(k))), nil
+			}, nil
+		}
+
+		return func(e *env) (int, error) {
+			addr, err := index(e)
+			if err != nil {
+				return 0, err
+			}
+			if addr < 0 || addr >= emulator.MEM_SIZE {
+				return 0, fmt.Errorf("mem[0x%X] is out of range", addr)
+			}
+
+			buf := make([]byte, 1)
+			e.emu.ReadMemory(uint16(addr), buf)
+			return int(buf[0]), nil
+		}, nil
+	case "i":
+		return func(e *env) (int, error) { return int(e.state.I), nil }, nil
+	case "pc":
+		return func(e *env) (int, error) { return int(e.state.PC), nil }, nil
+	case "dt":
+		return func(e *env) (int, error) { return int(e.state.DelayTimer), nil }, nil
+	case "st":
+		return func(e *env) (int, error) { return int(e.state.SoundTimer), nil }, nil
+	case "sp":
+		return func(e *env) (int, error) { return len(e.state.Stack), nil }, nil
+	case "frame":
+		return func(e *env) (int, error) { return int(e.emu.FrameCount()), nil }, nil
+	}
+
+	if len(tok.text) == 2 && tok.text[0] == 'v' {
+		if reg, err := strconv.ParseUint(tok.text[1:], 16, 8); err == nil {
+			return func(e *env) (int, error) { return int(e.state.V[reg]), nil }, nil
+		}
+	}
+
+	return nil, &ExprError{Pos: p.offset + tok.pos, Msg: fmt.Sprintf("unknown name %q", tok.text)}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// binaryOp combines two operands. && and || only evaluate the right-hand side
+// when they need to.
+func binaryOp(op string, lhs, rhs evalFunc) evalFunc {
+	switch op {
+	case "&&", "||":
+		return func(e *env) (int, error) {
+			l, err := lhs(e)
+			if err != nil {
+				return 0, err
+			}
+			if (l != 0) == (op == "||") {
+				return boolInt(l != 0), nil
+			}
+
+			r, err := rhs(e)
+			return boolInt(r != 0), err
+		}
+	}
+
+	return func(e *env) (int, error) {
+		l, err := lhs(e)
+		if err != nil {
+			return 0, err
+		}
+		r, err := rhs(e)
+		if err != nil {
+			return 0, err
+		}
+
+		switch op {
+		case "|":
+			return l | r, nil
+		case "^":
+			return l ^ r, nil
+		case "&":
+			return l & r, nil
+		case "==":
+			return boolInt(l == r), nil
+		case "!=":
+			return boolInt(l != r), nil
+		case "<":
+			return boolInt(l < r), nil
+		case "<=":
+			return boolInt(l <= r), nil
+		case ">":
+			return boolInt(l > r), nil
+		case ">=":
+			return boolInt(l >= r), nil
+		case "<<":
+			return l << (r & 63), nil
+		case ">>":
+			return l >> (r & 63), nil
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		}
+
+		if r == 0 {
+			return 0, ErrDivideByZero
+		}
+		if op == "/" {
+			return l / r, nil
+		}
+		return l % r, nil
+	}
+}
+
+// -- Templates
+
+// Template is a log message with expressions in braces, such as
+// "score={v3} at {pc:x}". Expressions followed by :x are printed in
+// hexadecimal, and {{ and }} are literal braces.
+type Template struct {
+	src   string
+	parts []templatePart
+}
+
+type templatePart struct {
+	text string
+	expr *Expr
+	hex  bool
+}
+
+// ParseTemplate compiles a log message.
+func ParseTemplate(src string) (*Template, error) {
+	return parseTemplateAt(src, 0)
+}
+
+func parseTemplateAt(src string, offset int) (*Template, error) {
+	t := &Template{src: src}
+
+	var text strings.Builder
+	for pos := 0; pos < len(src); pos++ {
+		c := src[pos]
+		switch {
+		case (c == '{' || c == '}') && pos+1 < len(src) && src[pos+1] == c:
+			text.WriteByte(c)
+			pos++
+		case c == '}':
+			return nil, &ExprError{Pos: offset + pos, Msg: "unmatched \"}\""}
+		case c == '{':
+			end := strings.IndexByte(src[pos:], '}')
+			if end < 0 {
+				return nil, &ExprError{Pos: offset + pos, Msg: "unterminated \"{\""}
+			}
+
+			inner, hex := src[pos+1:pos+end], false
+			if before, ok := strings.CutSuffix(inner, ":x"); ok {
+				inner, hex = before, true
+			}
+
+			expr, err := parseExprAt(inner, offset+pos+1)
+			if err != nil {
+				return nil, err
+			}
+
+			t.parts = append(t.parts, templatePart{text: text.String()}, templatePart{expr: expr, hex: hex})
+			text.Reset()
+			pos += end
+		default:
+			text.WriteByte(c)
+		}
+	}
+	t.parts = append(t.parts, templatePart{text: text.String()})
+
+	return t, nil
+}
+
+func (t *Template) String() string {
+	return t.src
+}
+
+// Format interpolates the template's expressions against emu. Expressions
+// that fail are replaced by their error.
+func (t *Template) Format(emu emulator.Emulator) string {
+	e := &env{emu: emu, state: emu.State()}
+
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.expr == nil {
+			b.WriteString(part.text)
+			continue
+		}
+
+		v, err := part.expr.eval(e)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "<%s>", err)
+		case part.hex:
+			fmt.Fprintf(&b, "0x%X", v)
+		default:
+			fmt.Fprintf(&b, "%d", v)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package debugger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+	emu := d.Emulator()
+
+	s := emu.State()
+	s.V[3] = 7
+	s.I = 0x200
+	s.Stack = []uint16{0x202}
+	emu.SetState(s)
+	emu.Press(0xA)
+
+	for src, want := range map[string]int{
+		"1 + 2 * 3":            7,
+		"(1 + 2) * 3":          9,
+		"0x10 | 0b1":           17,
+		"v3":                   7,
+		"V3 > 5 && i == 0x200": 1,
+		"pc == 0x200 && v3 > 5 && mem[i] != 0 && frame > 600": 0,
+		"mem[i + 1]":          0x06,
+		"sp":                  1,
+		"key[0xa] && !key[1]": 1,
+		"-1 < 0":              1,
+		"~0 & 0xFF":           0xFF,
+		"1 << 4 >> 2":         4,
+		"7 % 4 == 3 || 1 / 0": 1,
+		"0 && 1 / 0":          0,
+		"1 == 1 == 1":         1,
+		"5 - 3 - 1":           1,
+	} {
+		v, err := ParseExpr(src)
+		if !assert.Nil(err, src) {
+			continue
+		}
+
+		got, err := v.Eval(emu)
+		assert.Nil(err, src)
+		assert.Equal(want, got, src)
+	}
+
+	x, _ := ParseExpr("v3 / (v0 - v0)")
+	_, err := x.Eval(emu)
+	assert.ErrorIs(err, ErrDivideByZero)
+
+	x, _ = ParseExpr("mem[0x1000]")
+	_, err = x.Eval(emu)
+	assert.NotNil(err)
+}
+
+func TestExprErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for src, pos := range map[string]int{
+		"v3 >":        4,
+		"v3 > > 5":    5,
+		"pc == 0x2g4": 6,
+		"v3 $ 1":      3,
+		"mem[i":       5,
+		"(v3 + 1":     7,
+		"v3 && score": 6,
+		"mem i":       4,
+		"v3 5":        3,
+		"0x10000":     0,
+	} {
+		_, err := ParseExpr(src)
+
+		var exprErr *ExprError
+		if assert.True(errors.As(err, &exprErr), src) {
+			assert.Equal(pos, exprErr.Pos, src)
+		}
+	}
+
+	_, err := ParseExpr("v3 > ")
+	assert.Equal("column 6: expected a value, got the end of the expression", err.Error())
+}
+
+func TestTemplate(t *testing.T) {
+	assert := assert.New(t)
+	d := setup(t)
+
+	s := d.Emulator().State()
+	s.V[3] = 42
+	d.Emulator().SetState(s)
+
+	tmpl, err := ParseTemplate("score={v3} pc={pc:x} {{literal}} {1 / 0}")
+	assert.Nil(err)
+	assert.Equal("score=42 pc=0x200 {literal} <division by zero>", tmpl.Format(d.Emulator()))
+
+	_, err = ParseTemplate("a {v3 +}")
+	var exprErr *ExprError
+	assert.True(errors.As(err, &exprErr))
+	assert.Equal(7, exprErr.Pos)
+
+	_, err = ParseTemplate("a {v3")
+	assert.NotNil(err)
+	_, err = ParseTemplate("a }")
+	assert.NotNil(err)
+}
@@ -0,0 +1,609 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Register numbers used by the GDB stub: V0-VF, then these
+const (
+	GDB_REG_I = 16 + iota
+	GDB_REG_PC
+	GDB_REG_SP
+	GDB_REG_DT
+	GDB_REG_ST
+
+	GDB_REG_COUNT
+)
+
+// Signals reported in stop replies
+const (
+	sigInt  = 2
+	sigIll  = 4
+	sigTrap = 5
+)
+
+// Largest packet the stub accepts, advertised to clients
+const GDB_PACKET_SIZE = 0x1000
+
+// Byte sent by clients to interrupt a running target
+const gdbInterrupt = 0x03
+
+// Register layout for clients that read target descriptions. Registers are
+// big-endian, like the CHIP-8 itself.
+const gdbTargetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gr8.chip8">
+    <reg name="v0" bitsize="8" regnum="0"/>
+    <reg name="v1" bitsize="8"/>
+    <reg name="v2" bitsize="8"/>
+    <reg name="v3" bitsize="8"/>
+    <reg name="v4" bitsize="8"/>
+    <reg name="v5" bitsize="8"/>
+    <reg name="v6" bitsize="8"/>
+    <reg name="v7" bitsize="8"/>
+    <reg name="v8" bitsize="8"/>
+    <reg name="v9" bitsize="8"/>
+    <reg name="va" bitsize="8"/>
+    <reg name="vb" bitsize="8"/>
+    <reg name="vc" bitsize="8"/>
+    <reg name="vd" bitsize="8"/>
+    <reg name="ve" bitsize="8"/>
+    <reg name="vf" bitsize="8"/>
+    <reg name="i" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+    <reg name="sp" bitsize="8"/>
+    <reg name="dt" bitsize="8"/>
+    <reg name="st" bitsize="8"/>
+  </feature>
+</target>
+`
+
+var errBadPacket = errors.New("malformed packet")
+
+// Returned for register values the machine can't hold, such as a stack
+// pointer past the end of the stack
+var errBadValue = errors.New("register value out of range")
+
+// Returned by commands that resume the target, which reply once it stops
+var errNoReply = errors.New("no reply")
+
+// gdbPacket is a packet or interrupt read from the client.
+type gdbPacket struct {
+	data      string
+	valid     bool
+	interrupt bool
+}
+
+// GDBSession serves GDB's remote serial protocol to a single client.
+type GDBSession struct {
+	dbg *Debugger
+
+	r *bufio.Reader
+	w io.Writer
+
+	// Whether the client turned off acknowledgements with QStartNoAckMode
+	noAck bool
+
+	// Watchpoint IDs by the type, address and length the client gave them
+	watchpoints map[string]int
+
+	// Time between frames while running
+	clock time.Duration
+
+	done bool
+}
+
+// NewGDBSession returns a session that debugs dbg, reading packets from r
+// and writing replies to w.
+func NewGDBSession(dbg *Debugger, r io.Reader, w io.Writer) *GDBSession {
+	return &GDBSession{
+		dbg:         dbg,
+		r:           bufio.NewReader(r),
+		w:           w,
+		watchpoints: map[string]int{},
+		clock:       emulator.DEFAULT_CLOCK_SPEED,
+	}
+}
+
+// Serve handles packets until the client detaches or kills the target.
+func (s *GDBSession) Serve() error {
+	packets := make(chan gdbPacket)
+	errs := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// Watchpoints are identified by packet, so later clients couldn't remove them
+	defer func() {
+		for _, id := range s.watchpoints {
+			s.dbg.Unwatch(id)
+		}
+	}()
+
+	go func() {
+		for {
+			p, err := s.read()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case packets <- p:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(s.clock)
+	defer ticker.Stop()
+
+	for !s.done {
+		select {
+		case p := <-packets:
+			if err := s.handle(p); err != nil {
+				return err
+			}
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if err := s.tick(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// read reads the next packet or interrupt, skipping acknowledgements.
+func (s *GDBSession) read() (gdbPacket, error) {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return gdbPacket{}, err
+		}
+
+		switch b {
+		case gdbInterrupt:
+			return gdbPacket{interrupt: true}, nil
+		case '$':
+		default:
+			continue
+		}
+
+		data, err := s.r.ReadString('#')
+		if err != nil {
+			return gdbPacket{}, err
+		}
+		data = data[:len(data)-1]
+
+		sum := make([]byte, 2)
+		if _, err := io.ReadFull(s.r, sum); err != nil {
+			return gdbPacket{}, err
+		}
+
+		want, err := strconv.ParseUint(string(sum), 16, 8)
+		return gdbPacket{data: data, valid: err == nil && byte(want) == checksum(data)}, nil
+	}
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for idx := range len(data) {
+		sum += data[idx]
+	}
+
+	return sum
+}
+
+func (s *GDBSession) reply(data string) error {
+	_, err := fmt.Fprintf(s.w, "$%s#%02x", data, checksum(data))
+	return err
+}
+
+func (s *GDBSession) stopReply(signal int) error {
+	return s.reply(fmt.Sprintf("S%02x", signal))
+}
+
+// stopped returns the stop reply for why the debugger last stopped. Memory
+// watchpoint hits name the address that was accessed.
+func (s *GDBSession) stopped() string {
+	reason, _ := s.dbg.Stopped()
+	switch reason {
+	case StopFault:
+		return fmt.Sprintf("S%02x", sigIll)
+	case StopWatchpoint:
+		for _, hit := range s.dbg.Hits() {
+			if !hit.Watchpoint.Memory || hit.Access == emulator.AccessExecute {
+				continue
+			}
+
+			kind := "watch"
+			switch hit.Watchpoint.Access {
+			case emulator.AccessRead:
+				kind = "rwatch"
+			case emulator.AccessRead | emulator.AccessWrite:
+				kind = "awatch"
+			}
+			return fmt.Sprintf("T%02x%s:%x;", sigTrap, kind, hit.Addr)
+		}
+	}
+
+	return fmt.Sprintf("S%02x", sigTrap)
+}
+
+// tick runs one frame while the target is running, and reports when it stops.
+func (s *GDBSession) tick() error {
+	if !s.dbg.Running() {
+		return nil
+	}
+
+	// Faults are reported through Stopped
+	s.dbg.Tick(emulator.DEFAULT_IPF)
+	if s.dbg.Running() {
+		return nil
+	}
+
+	return s.reply(s.stopped())
+}
+
+// handle acknowledges a packet and answers it.
+func (s *GDBSession) handle(p gdbPacket) error {
+	if p.interrupt {
+		if !s.dbg.Running() {
+			return nil
+		}
+		s.dbg.Pause()
+		return s.stopReply(sigInt)
+	}
+
+	if !s.noAck {
+		ack := "+"
+		if !p.valid {
+			ack = "-"
+		}
+		if _, err := io.WriteString(s.w, ack); err != nil {
+			return err
+		}
+	}
+	if !p.valid {
+		return nil
+	}
+
+	resp, err := s.command(p.data)
+	if errors.Is(err, errNoReply) {
+		return nil
+	}
+	if err != nil {
+		resp = "E01"
+	}
+
+	return s.reply(resp)
+}
+
+// okOrError replies OK unless err is set.
+func okOrError(err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return "OK", nil
+}
+
+// command runs a single command and returns its reply.
+func (s *GDBSession) command(data string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+
+	args := data[1:]
+	switch data[0] {
+	case '?':
+		return s.stopped(), nil
+	case 'g':
+		return s.readRegisters(), nil
+	case 'G':
+		return okOrError(s.writeRegisters(args))
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 8)
+		if err != nil || n >= GDB_REG_COUNT {
+			return "", errBadPacket
+		}
+		return s.readRegister(int(n)), nil
+	case 'P':
+		reg, value, found := strings.Cut(args, "=")
+		n, err := strconv.ParseUint(reg, 16, 8)
+		if !found || err != nil || n >= GDB_REG_COUNT {
+			return "", errBadPacket
+		}
+		return okOrError(s.writeRegister(int(n), value))
+	case 'm':
+		return s.readMemory(args)
+	case 'M':
+		return okOrError(s.writeMemory(args))
+	case 'Z', 'z':
+		return s.breakpoint(data[0] == 'Z', args)
+	case 'c', 's':
+		if args != "" {
+			addr, err := strconv.ParseUint(args, 16, 16)
+			if err != nil {
+				return "", errBadPacket
+			}
+			if err := s.dbg.SetRegister("PC", uint16(addr)); err != nil {
+				return "", err
+			}
+		}
+
+		if data[0] == 'c' {
+			s.dbg.Continue()
+			return "", errNoReply
+		}
+
+		// Faults are reported through Stopped
+		s.dbg.Step()
+		return s.stopped(), nil
+	case 'H', 'T':
+		// There is only one thread
+		return "OK", nil
+	case 'D':
+		s.done = true
+		return "OK", nil
+	case 'k':
+		s.done = true
+		return "", errNoReply
+	case 'q', 'Q':
+		return s.query(data)
+	}
+
+	// Empty replies tell the client a command isn't supported
+	return "", nil
+}
+
+func (s *GDBSession) query(data string) (string, error) {
+	name, _, _ := strings.Cut(data, ":")
+
+	switch name {
+	case "qSupported":
+		return fmt.Sprintf("PacketSize=%x;QStartNoAckMode+;qXfer:features:read+;swbreak+", GDB_PACKET_SIZE), nil
+	case "QStartNoAckMode":
+		s.noAck = true
+		return "OK", nil
+	case "qAttached":
+		return "1", nil
+	case "qC":
+		return "QC1", nil
+	case "qfThreadInfo":
+		return "m1", nil
+	case "qsThreadInfo":
+		return "l", nil
+	case "qXfer":
+		return s.targetXML(data)
+	}
+
+	return "", nil
+}
+
+// targetXML answers qXfer:features:read:target.xml:offset,length.
+func (s *GDBSession) targetXML(data string) (string, error) {
+	const prefix = "qXfer:features:read:target.xml:"
+	if !strings.HasPrefix(data, prefix) {
+		return "", nil
+	}
+
+	offset, length, err := parseRange(strings.TrimPrefix(data, prefix))
+	if err != nil {
+		return "", err
+	}
+
+	if offset >= len(gdbTargetXML) {
+		return "l", nil
+	}
+
+	end := min(offset+length, len(gdbTargetXML))
+	if end == len(gdbTargetXML) {
+		return "l" + gdbTargetXML[offset:end], nil
+	}
+	return "m" + gdbTargetXML[offset:end], nil
+}
+
+// parseRange parses "addr,length" in hexadecimal.
+func parseRange(s string) (int, int, error) {
+	a, l, found := strings.Cut(s, ",")
+	addr, err1 := strconv.ParseUint(a, 16, 32)
+	length, err2 := strconv.ParseUint(l, 16, 32)
+	if !found || err1 != nil || err2 != nil {
+		return 0, 0, errBadPacket
+	}
+
+	return int(addr), int(length), nil
+}
+
+// -- Registers
+
+// registerBytes returns the value of register n in target byte order.
+func (s *GDBSession) registerBytes(n int) []byte {
+	state := s.dbg.Emulator().State()
+
+	switch n {
+	case GDB_REG_I:
+		return []byte{byte(state.I >> 8), byte(state.I)}
+	case GDB_REG_PC:
+		return []byte{byte(state.PC >> 8), byte(state.PC)}
+	case GDB_REG_SP:
+		return []byte{byte(len(state.Stack))}
+	case GDB_REG_DT:
+		return []byte{state.DelayTimer}
+	case GDB_REG_ST:
+		return []byte{state.SoundTimer}
+	}
+
+	return []byte{state.V[n]}
+}
+
+func (s *GDBSession) readRegister(n int) string {
+	return hex.EncodeToString(s.registerBytes(n))
+}
+
+func (s *GDBSession) readRegisters() string {
+	var out strings.Builder
+	for n := range GDB_REG_COUNT {
+		out.WriteString(s.readRegister(n))
+	}
+
+	return out.String()
+}
+
+// setRegister decodes the value of register n in target byte order into
+// state.
+func (s *GDBSession) setRegister(state *emulator.State, n int, value string) error {
+	b, err := hex.DecodeString(value)
+	if err != nil || len(b) != len(s.registerBytes(n)) {
+		return errBadPacket
+	}
+
+	switch n {
+	case GDB_REG_I:
+		state.I = uint16(b[0])<<8 | uint16(b[1])
+	case GDB_REG_PC:
+		state.PC = uint16(b[0])<<8 | uint16(b[1])
+	case GDB_REG_SP:
+		if int(b[0]) > emulator.STACK_SIZE {
+			return errBadValue
+		}
+
+		// Shrinking drops return addresses, growing pushes zeroes
+		stack := make([]uint16, b[0])
+		copy(stack, state.Stack)
+		state.Stack = stack
+	case GDB_REG_DT:
+		state.DelayTimer = b[0]
+	case GDB_REG_ST:
+		state.SoundTimer = b[0]
+	default:
+		state.V[n] = b[0]
+	}
+
+	return nil
+}
+
+func (s *GDBSession) writeRegister(n int, value string) error {
+	state := s.dbg.Emulator().State()
+	if err := s.setRegister(&state, n, value); err != nil {
+		return err
+	}
+	s.dbg.Emulator().SetState(state)
+
+	return nil
+}
+
+// writeRegisters writes every register at once, or none of them if any
+// value is bad.
+func (s *GDBSession) writeRegisters(value string) error {
+	state := s.dbg.Emulator().State()
+	for n := range GDB_REG_COUNT {
+		size := 2 * len(s.registerBytes(n))
+		if len(value) < size {
+			return errBadPacket
+		}
+
+		if err := s.setRegister(&state, n, value[:size]); err != nil {
+			return err
+		}
+		value = value[size:]
+	}
+	s.dbg.Emulator().SetState(state)
+
+	return nil
+}
+
+// -- Memory
+
+func (s *GDBSession) readMemory(args string) (string, error) {
+	addr, length, err := parseRange(args)
+	if err != nil || addr >= emulator.MEM_SIZE {
+		return "", errBadPacket
+	}
+
+	buf := make([]byte, min(length, emulator.MEM_SIZE-addr, GDB_PACKET_SIZE/2))
+	s.dbg.Emulator().ReadMemory(uint16(addr), buf)
+
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *GDBSession) writeMemory(args string) error {
+	target, value, found := strings.Cut(args, ":")
+	addr, length, err := parseRange(target)
+	if !found || err != nil || addr >= emulator.MEM_SIZE {
+		return errBadPacket
+	}
+
+	data, err := hex.DecodeString(value)
+	if err != nil || len(data) != length {
+		return errBadPacket
+	}
+
+	return s.dbg.WriteMemory(uint16(addr), data)
+}
+
+// breakpoint handles Z and z packets. Software and hardware breakpoints are
+// the same thing here, and write, read and access watchpoints watch the
+// given number of bytes.
+func (s *GDBSession) breakpoint(insert bool, args string) (string, error) {
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return "", errBadPacket
+	}
+
+	addr, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil || addr >= emulator.MEM_SIZE {
+		return "", errBadPacket
+	}
+
+	var access emulator.Access
+	switch parts[0] {
+	case "0", "1":
+		if insert {
+			s.dbg.SetBreakpoint(uint16(addr))
+		} else {
+			s.dbg.ClearBreakpoint(uint16(addr))
+		}
+		return "OK", nil
+	case "2":
+		access = emulator.AccessWrite
+	case "3":
+		access = emulator.AccessRead
+	case "4":
+		access = emulator.AccessRead | emulator.AccessWrite
+	default:
+		return "", nil
+	}
+
+	length, err := strconv.ParseUint(parts[2], 16, 16)
+	if err != nil || length == 0 || addr+length > emulator.MEM_SIZE {
+		return "", errBadPacket
+	}
+
+	key := strings.Join(parts[:3], ",")
+	if id, ok := s.watchpoints[key]; ok {
+		s.dbg.Unwatch(id)
+		delete(s.watchpoints, key)
+	}
+	if insert {
+		s.watchpoints[key] = s.dbg.Watch(emulator.WatchMemory(uint16(addr), uint16(addr+length), access))
+	}
+
+	return "OK", nil
+}
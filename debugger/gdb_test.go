@@ -0,0 +1,212 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gdbClient speaks the remote serial protocol over a TCP connection.
+type gdbClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func setupGDB(t *testing.T) (*gdbClient, *Debugger) {
+	dbg := setup(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+
+		s := NewGDBSession(dbg, conn, conn)
+		s.clock = time.Millisecond
+		done <- s.Serve()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	return &gdbClient{t: t, conn: conn, r: bufio.NewReader(conn)}, dbg
+}
+
+func (c *gdbClient) send(data string) {
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", data, checksum(data)); err != nil {
+		c.t.Fatal(err)
+	}
+
+	ack, err := c.r.ReadByte()
+	if err != nil || ack != '+' {
+		c.t.Fatalf("expected an acknowledgement, got %q", ack)
+	}
+}
+
+// reply reads the next packet, checks its checksum and acknowledges it.
+func (c *gdbClient) reply() string {
+	if _, err := c.r.ReadString('$'); err != nil {
+		c.t.Fatal(err)
+	}
+	data, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	data = strings.TrimSuffix(data, "#")
+
+	sum := make([]byte, 2)
+	if _, err := io.ReadFull(c.r, sum); err != nil {
+		c.t.Fatal(err)
+	}
+	assert.Equal(c.t, fmt.Sprintf("%02x", checksum(data)), string(sum))
+
+	c.conn.Write([]byte("+"))
+	return data
+}
+
+func (c *gdbClient) request(data string) string {
+	c.send(data)
+	return c.reply()
+}
+
+func TestGDBRegisters(t *testing.T) {
+	assert := assert.New(t)
+	c, dbg := setupGDB(t)
+
+	assert.Contains(c.request("qSupported:swbreak+"), "PacketSize=1000")
+	assert.Equal("S05", c.request("?"))
+
+	// V0-VF, I, PC, SP, DT, ST
+	regs := strings.Repeat("00", 16) + "0000" + "0200" + "00" + "00" + "00"
+	assert.Equal(regs, c.request("g"))
+
+	assert.Equal("OK", c.request("P3=7f"))
+	assert.Equal("7f", c.request("p3"))
+	assert.Equal("OK", c.request("P10=0300"))
+	assert.Equal("0300", c.request("p10"))
+	assert.Equal("OK", c.request("P13=3c"))
+	assert.Equal(byte(0x3C), dbg.Emulator().State().DelayTimer)
+
+	regs = strings.Repeat("01", 16) + "0345" + "0204" + "00" + "02" + "03"
+	assert.Equal("OK", c.request("G"+regs))
+	assert.Equal(regs, c.request("g"))
+
+	// The stack can't grow past its size, and a bad register leaves the
+	// others as they were
+	assert.Equal("OK", c.request("P12=10"))
+	assert.Equal("10", c.request("p12"))
+	assert.Equal("E01", c.request("P12=11"))
+	assert.Equal("10", c.request("p12"))
+	assert.Equal("E01", c.request("G"+strings.Repeat("02", 16)+"0345"+"0204"+"11"+"02"+"03"))
+	assert.Equal(byte(0x01), dbg.Emulator().State().V[0])
+
+	assert.Equal("E01", c.request("p15"))
+	assert.Equal("E01", c.request("P3=7f7f"))
+	assert.Equal("", c.request("vMustReplyEmpty"))
+
+	xml := c.request("qXfer:features:read:target.xml:0,20")
+	assert.Equal("m<?xml version=\"1.0\"?>", xml[:22])
+
+	c.request("D")
+}
+
+func TestGDBStopReason(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := setupGDB(t)
+
+	assert.Equal("S05", c.request("?"))
+
+	// An invalid opcode faults, and asking again gives the same reason
+	assert.Equal("OK", c.request("M200,2:ffff"))
+	assert.Equal("S04", c.request("s"))
+	assert.Equal("S04", c.request("?"))
+
+	c.request("D")
+}
+
+func TestGDBMemory(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := setupGDB(t)
+
+	assert.Equal("22067101", c.request("m200,4"))
+	assert.Equal("OK", c.request("M300,2:abcd"))
+	assert.Equal("abcd", c.request("m300,2"))
+	assert.Equal("E01", c.request("M300,2:ab"))
+	assert.Equal("E01", c.request("m1000,1"))
+
+	c.request("D")
+}
+
+func TestGDBExecution(t *testing.T) {
+	assert := assert.New(t)
+	c, dbg := setupGDB(t)
+
+	assert.Equal("S05", c.request("s"))
+	assert.Equal("0206", c.request("p11"))
+
+	assert.Equal("OK", c.request("Z0,20a,2"))
+	c.send("c")
+	assert.Equal("S05", c.reply())
+	assert.Equal("020a", c.request("p11"))
+	assert.Equal("01", c.request("p12"))
+
+	assert.Equal("OK", c.request("z0,20a,2"))
+	assert.Empty(dbg.Breakpoints())
+
+	// Interrupting the endless loop at the end of main
+	c.send("c")
+	c.conn.Write([]byte{gdbInterrupt})
+	assert.Equal("S02", c.reply())
+
+	c.send("k")
+}
+
+func TestGDBWatchpoints(t *testing.T) {
+	assert := assert.New(t)
+	c, dbg := setupGDB(t)
+
+	// 0x300 LD I, 0x310; 0x302 LD [I], V0; 0x304 JMP 0x302
+	assert.Equal("OK", c.request("M300,6:a310f0551302"))
+
+	assert.Equal("OK", c.request("Z2,310,1"))
+	c.send("c300")
+	assert.Equal("T05watch:310;", c.reply())
+	assert.Equal("0304", c.request("p11"))
+
+	assert.Equal("OK", c.request("z2,310,1"))
+	assert.Empty(dbg.Watchpoints())
+
+	// I has moved on to the next byte
+	assert.Equal("OK", c.request("Z4,311,1"))
+	c.send("c")
+	assert.Equal("T05awatch:311;", c.reply())
+
+	assert.Equal("E01", c.request("Z2,fff,2"))
+	assert.Equal("", c.request("Z5,300,2"))
+
+	c.request("D")
+}
@@ -0,0 +1,504 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Bytes per row of the hex dump
+const DUMP_WIDTH = 16
+
+// Rows in the hex dump
+const DUMP_ROWS = 16
+
+// Instructions shown before the PC in the disassembly
+const DISASM_CONTEXT = 6
+
+// Frames a key stays pressed after being typed in keypad mode, since
+// terminals don't report key releases
+const KEY_HOLD_FRAMES = 6
+
+// Terminal keys for each CHIP-8 key, laid out the same way as Keybinds
+var keyRunes = map[rune]uint8{
+	'x': 0x0,
+	'1': 0x1, '2': 0x2, '3': 0x3,
+	'q': 0x4, 'w': 0x5, 'e': 0x6,
+	'a': 0x7, 's': 0x8, 'd': 0x9,
+	'z': 0xA, 'c': 0xB,
+	'4': 0xC, 'r': 0xD, 'f': 0xE, 'v': 0xF,
+}
+
+const help = "c:continue  space:pause  s:step  n:over  o:out  b:break  w:watch  r:reg  m:mem  g:goto  tab:keypad  q:quit"
+
+// TUI is a terminal frontend for a Debugger.
+type TUI struct {
+	dbg    *Debugger
+	screen tcell.Screen
+
+	// Top of the hex dump
+	dumpAddr uint16
+
+	// Forward typed keys to the keypad instead of treating them as commands
+	keypadMode bool
+	held       map[uint8]int
+
+	// Active input prompt, if any, and what to do with the answer
+	prompt  string
+	input   string
+	onInput func(string) error
+
+	// Where the last answer had an error, to point at it, or -1
+	errPos int
+
+	message string
+	quit    bool
+}
+
+// NewTUI returns a TUI that draws dbg on screen.
+func NewTUI(dbg *Debugger, screen tcell.Screen) *TUI {
+	t := &TUI{
+		dbg:      dbg,
+		screen:   screen,
+		dumpAddr: emulator.ROM_START,
+		held:     map[uint8]int{},
+		errPos:   -1,
+	}
+
+	// Logpoints show their latest message in the status line
+	dbg.OnLog(func(bp *Breakpoint, msg string) {
+		t.message = msg
+	})
+
+	return t
+}
+
+// Run handles input and runs the debugger at 60Hz until the user quits.
+func (t *TUI) Run() error {
+	if err := t.screen.Init(); err != nil {
+		return err
+	}
+	defer t.screen.Fini()
+
+	events := make(chan tcell.Event)
+	quit := make(chan struct{})
+	defer close(quit)
+	go t.screen.ChannelEvents(events, quit)
+
+	ticker := time.NewTicker(emulator.DEFAULT_CLOCK_SPEED)
+	defer ticker.Stop()
+
+	t.draw()
+	for !t.quit {
+		select {
+		case ev := <-events:
+			t.handle(ev)
+		case <-ticker.C:
+			t.tick()
+		}
+		t.draw()
+	}
+
+	return nil
+}
+
+// tick runs one frame's worth of instructions and releases held keys.
+func (t *TUI) tick() {
+	if err := t.dbg.Tick(emulator.DEFAULT_IPF); err != nil {
+		t.message = err.Error()
+	}
+
+	for key, frames := range t.held {
+		if frames <= 1 {
+			t.dbg.Emulator().Release(key)
+			delete(t.held, key)
+		} else {
+			t.held[key] = frames - 1
+		}
+	}
+}
+
+func (t *TUI) ask(prompt string, onInput func(string) error) {
+	t.prompt = prompt
+	t.input = ""
+	t.onInput = onInput
+	t.errPos = -1
+}
+
+// handle processes a single input event.
+func (t *TUI) handle(ev tcell.Event) {
+	key, ok := ev.(*tcell.EventKey)
+	if !ok {
+		if _, ok := ev.(*tcell.EventResize); ok {
+			t.screen.Sync()
+		}
+		return
+	}
+
+	if t.prompt != "" {
+		t.handlePrompt(key)
+		return
+	}
+
+	switch key.Key() {
+	case tcell.KeyCtrlC, tcell.KeyEscape:
+		t.quit = true
+		return
+	case tcell.KeyTab:
+		t.keypadMode = !t.keypadMode
+		return
+	case tcell.KeyPgDn:
+		t.dumpAddr = (t.dumpAddr + DUMP_WIDTH*DUMP_ROWS) % emulator.MEM_SIZE
+		return
+	case tcell.KeyPgUp:
+		t.dumpAddr = (t.dumpAddr + emulator.MEM_SIZE - DUMP_WIDTH*DUMP_ROWS) % emulator.MEM_SIZE
+		return
+	case tcell.KeyRune:
+	default:
+		return
+	}
+
+	if t.keypadMode {
+		if code, ok := keyRunes[key.Rune()]; ok {
+			t.dbg.Emulator().Press(code)
+			t.held[code] = KEY_HOLD_FRAMES
+		}
+		return
+	}
+
+	t.message = ""
+	var err error
+	switch key.Rune() {
+	case 'q':
+		t.quit = true
+	case 'c':
+		t.dbg.Continue()
+	case ' ', 'p':
+		t.dbg.Pause()
+	case 's':
+		err = t.dbg.Step()
+	case 'n':
+		err = t.dbg.StepOver()
+	case 'o':
+		err = t.dbg.StepOut()
+	case 'b':
+		t.ask("breakpoint (2a4, 2a4 if v3 > 5, if frame > 600, 2a4 log v3={v3})", t.editBreakpoint)
+	case 'w':
+		t.ask("watch (300-30F rw, v3, i r, dt)", func(s string) error {
+			w, err := ParseWatchpoint(s)
+			if err != nil {
+				return err
+			}
+			if t.dbg.ToggleWatchpoint(w) {
+				t.message = fmt.Sprintf("watching %s", w)
+			} else {
+				t.message = fmt.Sprintf("stopped watching %s", w)
+			}
+			return nil
+		})
+	case 'r':
+		t.ask("register (v3=0x12, i=0x300, pc, dt, st)", t.editRegister)
+	case 'm':
+		t.ask("memory (0x300=FF 00 ...)", t.editMemory)
+	case 'g':
+		t.ask("go to address", func(s string) error {
+			addr, err := ParseAddress(s)
+			if err != nil {
+				return err
+			}
+			t.dumpAddr = addr &^ (DUMP_WIDTH - 1)
+			return nil
+		})
+	}
+
+	if err != nil {
+		t.message = err.Error()
+	}
+}
+
+func (t *TUI) handlePrompt(key *tcell.EventKey) {
+	switch key.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		t.prompt = ""
+	case tcell.KeyEnter:
+		prompt, onInput, input := t.prompt, t.onInput, t.input
+		t.prompt = ""
+		err := onInput(input)
+		if err != nil {
+			t.message = err.Error()
+		}
+
+		// Keep the answer to fix errors in expressions, pointing at them
+		var exprErr *ExprError
+		if errors.As(err, &exprErr) {
+			t.ask(prompt, onInput)
+			t.input, t.errPos = input, exprErr.Pos
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+		t.errPos = -1
+	case tcell.KeyRune:
+		t.input += string(key.Rune())
+		t.errPos = -1
+	}
+}
+
+var errNotPaused = errors.New("pause before editing")
+
+// editBreakpoint toggles a plain breakpoint, or sets one with clauses. Giving
+// an address-less breakpoint again removes it.
+func (t *TUI) editBreakpoint(s string) error {
+	bp, err := ParseBreakpoint(s)
+	if err != nil {
+		return err
+	}
+
+	if bp.Plain() {
+		if t.dbg.ToggleBreakpoint(bp.Addr) {
+			t.message = fmt.Sprintf("breakpoint set at 0x%03X", bp.Addr)
+		} else {
+			t.message = fmt.Sprintf("breakpoint cleared at 0x%03X", bp.Addr)
+		}
+		return nil
+	}
+
+	if bp.Anywhere {
+		for _, other := range t.dbg.AnywhereBreakpoints() {
+			if other.String() == bp.String() {
+				t.dbg.RemoveBreakpoint(other)
+				t.message = fmt.Sprintf("breakpoint cleared: %s", other)
+				return nil
+			}
+		}
+	}
+
+	t.message = fmt.Sprintf("breakpoint set: %s", t.dbg.AddBreakpoint(bp))
+	return nil
+}
+
+// editRegister parses an assignment such as "v3=0x12".
+func (t *TUI) editRegister(s string) error {
+	if t.dbg.Running() {
+		return errNotPaused
+	}
+
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected register=value, got %q", s)
+	}
+
+	n, err := ParseNumber(value)
+	if err != nil {
+		return err
+	}
+
+	return t.dbg.SetRegister(strings.TrimSpace(name), n)
+}
+
+// editMemory parses an assignment such as "0x300=FF 00", with the bytes in
+// hexadecimal.
+func (t *TUI) editMemory(s string) error {
+	if t.dbg.Running() {
+		return errNotPaused
+	}
+
+	target, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected address=bytes, got %q", s)
+	}
+
+	addr, err := ParseAddress(target)
+	if err != nil {
+		return err
+	}
+
+	data, err := ParseBytes(value)
+	if err != nil {
+		return err
+	}
+
+	return t.dbg.WriteMemory(addr, data)
+}
+
+// -- Drawing
+
+var (
+	styleNormal = tcell.StyleDefault
+	styleDim    = styleNormal.Foreground(tcell.ColorGray)
+	styleTitle  = styleNormal.Bold(true)
+	styleActive = styleNormal.Reverse(true)
+	styleBreak  = styleNormal.Foreground(tcell.ColorRed)
+)
+
+func (t *TUI) text(x, y int, style tcell.Style, s string) {
+	for idx, r := range s {
+		t.screen.SetContent(x+idx, y, r, nil, style)
+	}
+}
+
+// draw redraws the whole screen.
+func (t *TUI) draw() {
+	t.screen.Clear()
+
+	t.drawDisplay(0, 0)
+	t.drawRegisters(67, 0)
+	t.drawDisassembly(0, 18)
+	t.drawDump(36, 18)
+	t.drawStatus(0, 36)
+
+	t.screen.Show()
+}
+
+// drawDisplay mirrors the CHIP-8 display, two pixels per character cell.
+func (t *TUI) drawDisplay(x, y int) {
+	emu := t.dbg.Emulator()
+
+	for row := 0; row < emulator.DISPLAY_HEIGHT; row += 2 {
+		for col := range emulator.DISPLAY_WIDTH {
+			top, bottom := emu.Pixel(col, row), emu.Pixel(col, row+1)
+
+			r := ' '
+			switch {
+			case top && bottom:
+				r = '█'
+			case top:
+				r = '▀'
+			case bottom:
+				r = '▄'
+			}
+			t.screen.SetContent(x+1+col, y+1+row/2, r, nil, styleNormal)
+		}
+	}
+
+	t.text(x, y, styleTitle, "display")
+}
+
+func (t *TUI) drawRegisters(x, y int) {
+	s := t.dbg.Emulator().State()
+
+	t.text(x, y, styleTitle, "registers")
+	for reg := range 16 {
+		t.text(x+(reg%4)*8, y+1+reg/4, styleNormal, fmt.Sprintf("V%X %02X", reg, s.V[reg]))
+	}
+	t.text(x, y+6, styleNormal, fmt.Sprintf("PC %03X  I %03X", s.PC, s.I))
+	t.text(x, y+7, styleNormal, fmt.Sprintf("DT %02X    ST %02X", s.DelayTimer, s.SoundTimer))
+
+	t.text(x, y+9, styleTitle, "stack")
+	if len(s.Stack) == 0 {
+		t.text(x, y+10, styleDim, "empty")
+	}
+	for idx := range s.Stack {
+		// Innermost return address first
+		t.text(x, y+10+idx, styleNormal, fmt.Sprintf("%2d %03X", idx, s.Stack[len(s.Stack)-1-idx]))
+	}
+
+	title := "keypad"
+	if t.keypadMode {
+		title = "keypad (input)"
+	}
+	t.text(x+16, y+9, styleTitle, title)
+	keys := t.dbg.Keys()
+	layout := [4][4]uint8{{0x1, 0x2, 0x3, 0xC}, {0x4, 0x5, 0x6, 0xD}, {0x7, 0x8, 0x9, 0xE}, {0xA, 0x0, 0xB, 0xF}}
+	for row, codes := range layout {
+		for col, code := range codes {
+			style := styleDim
+			if keys&(1<<code) != 0 {
+				style = styleActive
+			}
+			t.text(x+16+col*2, y+10+row, style, fmt.Sprintf("%X", code))
+		}
+	}
+	t.text(x+16, y+14, styleNormal, fmt.Sprintf("%04X", keys))
+}
+
+func (t *TUI) drawDisassembly(x, y int) {
+	pc := t.dbg.Emulator().PC()
+
+	t.text(x, y, styleTitle, "disassembly")
+
+	addr := int(pc) - DISASM_CONTEXT*2
+	if addr < 0 {
+		addr = int(pc) % 2
+	}
+	for row := range DUMP_ROWS {
+		if addr+1 >= emulator.MEM_SIZE {
+			break
+		}
+
+		in := t.dbg.Instruction(uint16(addr))
+		marker, style := ' ', styleNormal
+		if t.dbg.HasBreakpoint(uint16(addr)) {
+			marker, style = '*', styleBreak
+		}
+		if uint16(addr) == pc {
+			style = styleActive
+		}
+
+		cursor := ' '
+		if uint16(addr) == pc {
+			cursor = '>'
+		}
+		line := fmt.Sprintf("%c%c %03X  %04X  %s", cursor, marker, addr, in.Opcode, in)
+		t.text(x, y+1+row, style, fmt.Sprintf("%-34s", line))
+
+		addr += 2
+	}
+}
+
+func (t *TUI) drawDump(x, y int) {
+	t.text(x, y, styleTitle, "memory")
+
+	emu := t.dbg.Emulator()
+	i := emu.I()
+	buf := make([]byte, DUMP_WIDTH)
+	for row := range DUMP_ROWS {
+		base := (int(t.dumpAddr) + row*DUMP_WIDTH) % emulator.MEM_SIZE
+		n := emu.ReadMemory(uint16(base), buf)
+
+		t.text(x, y+1+row, styleDim, fmt.Sprintf("%03X", base))
+		for col := range n {
+			style := styleNormal
+			if uint16(base+col) == i {
+				style = styleActive
+			}
+			t.text(x+5+col*3, y+1+row, style, fmt.Sprintf("%02X", buf[col]))
+		}
+	}
+}
+
+func (t *TUI) drawStatus(x, y int) {
+	status := "paused"
+	if t.dbg.Running() {
+		status = "running"
+	} else if reason, err := t.dbg.Stopped(); err != nil {
+		status = fmt.Sprintf("%s: %s", reason, err)
+	} else if hits := t.dbg.Hits(); reason == StopWatchpoint && len(hits) > 0 {
+		status = fmt.Sprintf("%s: %s", reason, hits[0])
+		if len(hits) > 1 {
+			status += fmt.Sprintf(" (+%d more)", len(hits)-1)
+		}
+	} else if reason != StopNone {
+		status = reason.String()
+	}
+	t.text(x, y, styleTitle, status)
+
+	if t.message != "" {
+		t.text(x+len(status)+2, y, styleNormal, t.message)
+	}
+
+	if t.prompt != "" {
+		t.text(x, y+1, styleNormal, fmt.Sprintf("%s: %s_", t.prompt, t.input))
+		if t.errPos >= 0 {
+			t.text(x+len(t.prompt)+2+t.errPos, y+2, styleBreak, "^")
+		}
+	} else {
+		t.text(x, y+1, styleDim, help)
+	}
+}
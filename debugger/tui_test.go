@@ -0,0 +1,180 @@
+package debugger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTUI(t *testing.T) (*TUI, tcell.SimulationScreen) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(120, 40)
+	t.Cleanup(screen.Fini)
+
+	return NewTUI(setup(t), screen), screen
+}
+
+func typeRune(tui *TUI, r rune) {
+	tui.handle(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+}
+
+func typeLine(tui *TUI, s string) {
+	for _, r := range s {
+		typeRune(tui, r)
+	}
+	tui.handle(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+}
+
+// contents returns the simulated screen as lines of text.
+func contents(screen tcell.SimulationScreen) []string {
+	cells, width, height := screen.GetContents()
+
+	lines := make([]string, height)
+	for y := range height {
+		var line strings.Builder
+		for x := range width {
+			r := ' '
+			if runes := cells[y*width+x].Runes; len(runes) > 0 {
+				r = runes[0]
+			}
+			line.WriteRune(r)
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+func TestTUICommands(t *testing.T) {
+	assert := assert.New(t)
+	tui, _ := setupTUI(t)
+
+	typeRune(tui, 's')
+	assert.Equal(uint16(0x206), pc(tui.dbg))
+
+	typeRune(tui, 'o')
+	assert.True(tui.dbg.Running())
+	tui.tick()
+	assert.Equal(uint16(0x202), pc(tui.dbg))
+
+	typeRune(tui, 'b')
+	typeLine(tui, "204")
+	assert.True(tui.dbg.HasBreakpoint(0x204))
+	assert.Equal("breakpoint set at 0x204", tui.message)
+
+	typeRune(tui, 'c')
+	tui.tick()
+	assert.False(tui.dbg.Running())
+	assert.Equal(uint16(0x204), pc(tui.dbg))
+
+	typeRune(tui, 'q')
+	assert.True(tui.quit)
+}
+
+func TestTUIEditing(t *testing.T) {
+	assert := assert.New(t)
+	tui, _ := setupTUI(t)
+
+	typeRune(tui, 'r')
+	typeLine(tui, "v3=0x12")
+	assert.Equal(byte(0x12), tui.dbg.Emulator().State().V[3])
+
+	typeRune(tui, 'm')
+	typeLine(tui, "0x300=ab CD")
+	buf := make([]byte, 2)
+	tui.dbg.Emulator().ReadMemory(0x300, buf)
+	assert.Equal([]byte{0xAB, 0xCD}, buf)
+
+	typeRune(tui, 'r')
+	typeLine(tui, "v3")
+	assert.Contains(tui.message, "expected register=value")
+
+	typeRune(tui, 'm')
+	typeLine(tui, "0x300=zz")
+	assert.Equal(`invalid byte "zz"`, tui.message)
+
+	// Editing is refused while running
+	typeRune(tui, 'c')
+	typeRune(tui, 'r')
+	typeLine(tui, "v3=1")
+	assert.Equal(errNotPaused.Error(), tui.message)
+	assert.Equal(byte(0x12), tui.dbg.Emulator().State().V[3])
+
+	typeRune(tui, 'g')
+	typeLine(tui, "0x345")
+	assert.Equal(uint16(0x340), tui.dumpAddr)
+}
+
+func TestTUIBreakpoints(t *testing.T) {
+	assert := assert.New(t)
+	tui, screen := setupTUI(t)
+
+	// Errors keep the answer and point at where they are
+	typeRune(tui, 'b')
+	typeLine(tui, "204 if v1 >")
+	assert.NotEmpty(tui.prompt)
+	assert.Equal("204 if v1 >", tui.input)
+	assert.Equal(11, tui.errPos)
+
+	tui.draw()
+	lines := contents(screen)
+	prompt := strings.Index(lines[37], ">_")
+	assert.Equal(prompt+1, strings.Index(lines[38], "^"))
+
+	typeLine(tui, " 0")
+	assert.Empty(tui.prompt)
+	assert.Equal("breakpoint set: 0x204 if v1 > 0", tui.message)
+
+	typeRune(tui, 'b')
+	typeLine(tui, "206 log v0={v0}")
+	typeRune(tui, 'c')
+	tui.tick()
+	assert.Equal("v0=0", tui.message)
+	assert.Equal(uint16(0x204), pc(tui.dbg))
+
+	// Giving an address-less breakpoint again removes it
+	typeRune(tui, 'b')
+	typeLine(tui, "if frame > 600")
+	assert.Len(tui.dbg.AnywhereBreakpoints(), 1)
+	typeRune(tui, 'b')
+	typeLine(tui, "if frame > 600")
+	assert.Empty(tui.dbg.AnywhereBreakpoints())
+}
+
+func TestTUIKeypad(t *testing.T) {
+	assert := assert.New(t)
+	tui, _ := setupTUI(t)
+
+	tui.handle(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	assert.True(tui.keypadMode)
+
+	// Keys go to the keypad instead of running commands
+	typeRune(tui, 'q')
+	assert.False(tui.quit)
+	assert.Equal(uint16(1<<0x4), tui.dbg.Keys())
+
+	for range KEY_HOLD_FRAMES {
+		tui.tick()
+	}
+	assert.Equal(uint16(0), tui.dbg.Keys())
+}
+
+func TestTUIDraw(t *testing.T) {
+	assert := assert.New(t)
+	tui, screen := setupTUI(t)
+
+	tui.dbg.SetBreakpoint(0x202)
+	tui.draw()
+	screenText := strings.Join(contents(screen), "\n")
+
+	assert.Contains(screenText, ">  200  2206  CALL 0x206")
+	assert.Contains(screenText, " * 202  7101  ADD V1, 0x01")
+	assert.Contains(screenText, "PC 200  I 000")
+	assert.Contains(screenText, "200  22 06 71 01")
+	assert.Contains(screenText, help)
+}
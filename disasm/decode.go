@@ -0,0 +1,132 @@
+package disasm
+
+import (
+	"fmt"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Flow describes how an instruction affects the program counter.
+type Flow int
+
+const (
+	// Continues with the next instruction
+	FlowNext Flow = iota
+
+	// Continues with either the next instruction or the one after it
+	FlowSkip
+
+	// Jumps to NNN
+	FlowJump
+
+	// Calls the subroutine at NNN, then continues with the next instruction
+	FlowCall
+
+	// Returns to the caller
+	FlowReturn
+
+	// Jumps to NNN + V0, which can't be resolved statically
+	FlowIndirect
+
+	// Not an instruction the emulator executes
+	FlowInvalid
+)
+
+// Instruction is a decoded CHIP-8 instruction.
+type Instruction struct {
+	Opcode uint16
+
+	// Name of the handler in emulator/opcodes.go, empty for invalid opcodes
+	Mnemonic string
+
+	X, Y, N, NN byte
+	NNN         uint16
+}
+
+// Decode decodes a 2-byte opcode.
+func Decode(opcode uint16) Instruction {
+	in := Instruction{
+		Opcode: opcode,
+		X:      byte(opcode >> 8 & 0xF),
+		Y:      byte(opcode >> 4 & 0xF),
+		N:      byte(opcode & 0xF),
+		NN:     byte(opcode),
+		NNN:    opcode & 0xFFF,
+	}
+
+	// The emulator decides what is an instruction, so that code it runs is
+	// never taken for data
+	in.Mnemonic = emulator.Mnemonic(opcode)
+
+	return in
+}
+
+// Valid reports whether the opcode is one the emulator implements.
+func (in Instruction) Valid() bool {
+	return in.Mnemonic != ""
+}
+
+// Flow returns how the instruction affects the program counter.
+func (in Instruction) Flow() Flow {
+	switch in.Mnemonic {
+	case "":
+		return FlowInvalid
+	case "RET":
+		return FlowReturn
+	case "JMP":
+		return FlowJump
+	case "CALL":
+		return FlowCall
+	case "JPV":
+		return FlowIndirect
+	case "SEVx", "SNEVx", "SEVxVy", "SNEVxVy", "SKPVx", "SKNPVx":
+		return FlowSkip
+	}
+
+	return FlowNext
+}
+
+// HasAddress reports whether NNN is a memory address operand.
+func (in Instruction) HasAddress() bool {
+	switch in.Mnemonic {
+	case "SYS", "JMP", "CALL", "LDI", "JPV":
+		return true
+	}
+
+	return false
+}
+
+// String returns the instruction in assembly form, such as "SEVx V3, 0x22".
+func (in Instruction) String() string {
+	return in.Format(nil)
+}
+
+// Format is like String, but names address operands with label when it
+// returns a non-empty string.
+func (in Instruction) Format(label func(addr uint16) string) string {
+	addr := fmt.Sprintf("0x%03X", in.NNN)
+	if label != nil {
+		if name := label(in.NNN); name != "" {
+			addr = name
+		}
+	}
+
+	switch in.Mnemonic {
+	case "":
+		return fmt.Sprintf("0x%04X", in.Opcode)
+	case "CLS", "RET":
+		return in.Mnemonic
+	case "SYS", "JMP", "CALL", "LDI", "JPV":
+		return fmt.Sprintf("%s %s", in.Mnemonic, addr)
+	case "SEVx", "SNEVx", "LD", "ADD", "RNDVx":
+		return fmt.Sprintf("%s V%X, 0x%02X", in.Mnemonic, in.X, in.NN)
+	case "DRW":
+		return fmt.Sprintf("%s V%X, V%X, %d", in.Mnemonic, in.X, in.Y, in.N)
+	}
+
+	if in.Opcode&0xF000 == 0x8000 || in.Opcode&0xF000 == 0x5000 || in.Opcode&0xF000 == 0x9000 {
+		return fmt.Sprintf("%s V%X, V%X", in.Mnemonic, in.X, in.Y)
+	}
+
+	return fmt.Sprintf("%s V%X", in.Mnemonic, in.X)
+}
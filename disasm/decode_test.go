@@ -0,0 +1,62 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[uint16]string{
+		0x00E0: "CLS",
+		0x00EE: "RET",
+		0x1228: "JMP 0x228",
+		0x2BEE: "CALL 0xBEE",
+		0x3322: "SEVx V3, 0x22",
+		0x5120: "SEVxVy V1, V2",
+		0x6A0C: "LD VA, 0x0C",
+		0x8126: "SHRVx V1, V2",
+		0x812E: "SHLVx V1, V2",
+		0xA123: "LDI 0x123",
+		0xB300: "JPV 0x300",
+		0xD01F: "DRW V0, V1, 15",
+		0xE19E: "SKPVx V1",
+		0xF265: "LDVxI V2",
+		0x0123: "SYS 0x123",
+		0x8128: "0x8128",
+		0xE1FF: "0xE1FF",
+	}
+
+	for opcode, text := range cases {
+		assert.Equal(text, Decode(opcode).String(), "%04X", opcode)
+	}
+}
+
+func TestFlow(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(FlowNext, Decode(0x6000).Flow())
+	assert.Equal(FlowSkip, Decode(0x3000).Flow())
+	assert.Equal(FlowSkip, Decode(0xE0A1).Flow())
+	assert.Equal(FlowJump, Decode(0x1200).Flow())
+	assert.Equal(FlowCall, Decode(0x2200).Flow())
+	assert.Equal(FlowReturn, Decode(0x00EE).Flow())
+	assert.Equal(FlowIndirect, Decode(0xB200).Flow())
+	assert.Equal(FlowNext, Decode(0x0123).Flow())
+	assert.Equal(FlowInvalid, Decode(0x8008).Flow())
+}
+
+func TestFormatUsesLabels(t *testing.T) {
+	assert := assert.New(t)
+	label := func(addr uint16) string {
+		if addr == 0x228 {
+			return "loop"
+		}
+		return ""
+	}
+
+	assert.Equal("JMP loop", Decode(0x1228).Format(label))
+	assert.Equal("JMP 0x300", Decode(0x1300).Format(label))
+}
@@ -0,0 +1,165 @@
+// Package disasm disassembles CHIP-8 ROMs.
+//
+// A linear sweep mis-decodes sprite data as instructions, so Disassemble
+// traces the code reachable from ROM_START instead, following jumps, calls,
+// skips and returns. Everything it doesn't reach is treated as data.
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Kind classifies a byte of a ROM.
+type Kind byte
+
+const (
+	// Not reached by any traced path
+	Data Kind = iota
+
+	// First byte of an instruction
+	Code
+
+	// Second byte of an instruction
+	Operand
+)
+
+// Label prefixes, in order of precedence when an address has several roles
+var labelPrefixes = []string{"main", "sub", "loc", "data"}
+
+const (
+	labelMain = iota
+	labelSub
+	labelLoc
+	labelData
+)
+
+// Program is a disassembled ROM.
+type Program struct {
+	// ROM image, loaded at ROM_START
+	ROM []byte
+
+	// Classification of each byte of the ROM
+	Kinds []Kind
+
+	// Synthesized labels for branch and I-load targets, by address
+	Labels map[uint16]string
+
+	// Addresses of JPV instructions, whose targets can't be traced
+	Indirect []uint16
+
+	labelRanks map[uint16]int
+}
+
+// Disassemble traces the code reachable from ROM_START.
+func Disassemble(rom []byte) *Program {
+	p := &Program{
+		ROM:        rom,
+		Kinds:      make([]Kind, len(rom)),
+		Labels:     map[uint16]string{},
+		labelRanks: map[uint16]int{},
+	}
+
+	p.label(emulator.ROM_START, labelMain)
+
+	work := []uint16{emulator.ROM_START}
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		in, ok := p.decode(addr)
+		if !ok || p.Kinds[addr-emulator.ROM_START] == Code {
+			continue
+		}
+
+		off := addr - emulator.ROM_START
+		p.Kinds[off] = Code
+		if p.Kinds[off+1] == Data {
+			p.Kinds[off+1] = Operand
+		}
+
+		switch in.Mnemonic {
+		case "CALL":
+			p.label(in.NNN, labelSub)
+		case "JMP", "JPV":
+			p.label(in.NNN, labelLoc)
+		case "LDI":
+			p.label(in.NNN, labelData)
+		}
+
+		switch in.Flow() {
+		case FlowNext:
+			work = append(work, addr+2)
+		case FlowSkip:
+			work = append(work, addr+4, addr+2)
+		case FlowJump:
+			work = append(work, in.NNN)
+		case FlowCall:
+			work = append(work, addr+2, in.NNN)
+		case FlowIndirect:
+			p.Indirect = append(p.Indirect, addr)
+		}
+	}
+
+	return p
+}
+
+// Contains reports whether addr lies within the ROM.
+func (p *Program) Contains(addr uint16) bool {
+	return addr >= emulator.ROM_START && int(addr-emulator.ROM_START) < len(p.ROM)
+}
+
+// Kind returns the classification of the byte at addr.
+func (p *Program) Kind(addr uint16) Kind {
+	if !p.Contains(addr) {
+		return Data
+	}
+
+	return p.Kinds[addr-emulator.ROM_START]
+}
+
+// Instruction returns the instruction starting at addr, if addr was traced as code.
+func (p *Program) Instruction(addr uint16) (Instruction, bool) {
+	if p.Kind(addr) != Code {
+		return Instruction{}, false
+	}
+
+	return p.decode(addr)
+}
+
+// Label returns the label for addr, or an empty string if there is none.
+func (p *Program) Label(addr uint16) string {
+	return p.Labels[addr]
+}
+
+// decode decodes the instruction at addr, if a whole one fits in the ROM.
+func (p *Program) decode(addr uint16) (Instruction, bool) {
+	if !p.Contains(addr) || !p.Contains(addr+1) {
+		return Instruction{}, false
+	}
+
+	off := addr - emulator.ROM_START
+	in := Decode(binary.BigEndian.Uint16(p.ROM[off : off+2]))
+
+	return in, in.Valid()
+}
+
+// label names addr, unless it already has a name of higher precedence.
+func (p *Program) label(addr uint16, rank int) {
+	if !p.Contains(addr) {
+		return
+	}
+
+	if existing, ok := p.labelRanks[addr]; ok && existing <= rank {
+		return
+	}
+
+	p.labelRanks[addr] = rank
+	if rank == labelMain {
+		p.Labels[addr] = labelPrefixes[rank]
+	} else {
+		p.Labels[addr] = fmt.Sprintf("%s_%03X", labelPrefixes[rank], addr)
+	}
+}
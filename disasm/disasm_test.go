@@ -0,0 +1,64 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceSeparatesCodeFromData(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Disassemble([]byte{
+		0xA2, 0x06, // 200: i := sprite
+		0xD0, 0x11, // 202: sprite v0 v1 1
+		0x12, 0x04, // 204: jump 204
+		0x3C, 0x00, // 206: sprite data that decodes as SEVx
+	})
+
+	assert.Equal([]Kind{Code, Operand, Code, Operand, Code, Operand, Data, Data}, p.Kinds)
+	assert.Equal("main", p.Label(0x200))
+	assert.Equal("loc_204", p.Label(0x204))
+	assert.Equal("data_206", p.Label(0x206))
+
+	_, ok := p.Instruction(0x206)
+	assert.False(ok)
+}
+
+func TestTraceFollowsCallsAndSkips(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Disassemble([]byte{
+		0x22, 0x08, // 200: call 208
+		0x30, 0x01, // 202: skip if v0 == 1
+		0x12, 0x02, // 204: jump 202
+		0x00, 0xEE, // 206: return (reached by the skip)
+		0x60, 0x01, // 208: v0 := 1
+		0x00, 0xEE, // 20A: return
+		0xFF, 0xFF, // 20C: unreachable
+	})
+
+	for addr := uint16(0x200); addr < 0x20C; addr += 2 {
+		_, ok := p.Instruction(addr)
+		assert.True(ok, "%03X", addr)
+	}
+	assert.Equal(Data, p.Kind(0x20C))
+	assert.Equal("sub_208", p.Label(0x208))
+}
+
+func TestIndirectJumpsAreRecorded(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Disassemble([]byte{0xB2, 0x04, 0x00, 0x00, 0x12, 0x04})
+
+	assert.Equal([]uint16{0x200}, p.Indirect)
+	assert.Equal("loc_204", p.Label(0x204))
+	assert.Equal(Data, p.Kind(0x204))
+}
+
+func TestTruncatedInstruction(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Disassemble([]byte{0x00, 0xE0, 0x12})
+	assert.Equal([]Kind{Code, Operand, Data}, p.Kinds)
+}
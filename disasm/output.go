@@ -0,0 +1,309 @@
+package disasm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Longest run of data bytes printed on one line
+const DATA_LINE_LENGTH = 8
+
+// entry is a single instruction or run of data bytes, in ROM order.
+type entry struct {
+	addr  uint16
+	bytes []byte
+	code  bool
+	in    Instruction
+}
+
+// entries splits the ROM into instructions and runs of data. Runs of data
+// are broken at labels so that every label starts an entry, except for labels
+// that point into the middle of an instruction.
+func (p *Program) entries() []entry {
+	var out []entry
+
+	for off := 0; off < len(p.ROM); {
+		addr := emulator.ROM_START + uint16(off)
+
+		if in, ok := p.Instruction(addr); ok {
+			out = append(out, entry{addr, p.ROM[off : off+2], true, in})
+			off += 2
+			continue
+		}
+
+		end := off + 1
+		for end < len(p.ROM) && end-off < DATA_LINE_LENGTH {
+			next := emulator.ROM_START + uint16(end)
+			if p.Kinds[end] == Code || p.Labels[next] != "" {
+				break
+			}
+			end++
+		}
+
+		out = append(out, entry{addr: addr, bytes: p.ROM[off:end]})
+		off = end
+	}
+
+	return out
+}
+
+func hexBytes(b []byte, sep string) string {
+	parts := make([]string, len(b))
+	for idx, v := range b {
+		parts[idx] = fmt.Sprintf("%02X", v)
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// WriteListing writes a plain listing of addresses, raw bytes and mnemonics.
+func (p *Program) WriteListing(w io.Writer) error {
+	return p.WriteAnnotatedListing(w, nil)
+}
+
+// WriteAnnotatedListing writes a listing with the result of annotate before
+// each instruction or run of data, given its address and length in bytes.
+func (p *Program) WriteAnnotatedListing(w io.Writer, annotate func(addr uint16, size int) string) error {
+	for idx, e := range p.entries() {
+		if name := p.Labels[e.addr]; name != "" {
+			if idx > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+
+		if e.code {
+			if name := p.Labels[e.addr+1]; name != "" {
+				fmt.Fprintf(w, "%s = 0x%03X\n", name, e.addr+1)
+			}
+		}
+		if annotate != nil {
+			fmt.Fprintf(w, "%s  ", annotate(e.addr, len(e.bytes)))
+		}
+
+		var err error
+		if e.code {
+			_, err = fmt.Fprintf(w, "0x%03X  %s  %s\n", e.addr, hexBytes(e.bytes, " "), e.in.Format(p.Label))
+		} else {
+			_, err = fmt.Fprintf(w, "0x%03X  %s\n", e.addr, hexBytes(e.bytes, " "))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// octo returns the Octo statement for in, or an empty string if Octo can't
+// encode the exact same opcode.
+func (p *Program) octo(in Instruction) string {
+	addr := fmt.Sprintf("0x%03X", in.NNN)
+	if name := p.Labels[in.NNN]; name != "" {
+		addr = name
+	}
+	vx, vy := fmt.Sprintf("v%X", in.X), fmt.Sprintf("v%X", in.Y)
+
+	switch in.Mnemonic {
+	case "CLS":
+		return "clear"
+	case "RET":
+		return "return"
+	case "JMP":
+		return "jump " + addr
+	case "CALL":
+		return ":call " + addr
+	case "SEVx":
+		return fmt.Sprintf("if %s != 0x%02X then", vx, in.NN)
+	case "SNEVx":
+		return fmt.Sprintf("if %s == 0x%02X then", vx, in.NN)
+	case "SEVxVy", "SNEVxVy":
+		if in.N != 0 {
+			return ""
+		}
+		if in.Mnemonic == "SEVxVy" {
+			return fmt.Sprintf("if %s != %s then", vx, vy)
+		}
+		return fmt.Sprintf("if %s == %s then", vx, vy)
+	case "LD":
+		return fmt.Sprintf("%s := 0x%02X", vx, in.NN)
+	case "ADD":
+		return fmt.Sprintf("%s += 0x%02X", vx, in.NN)
+	case "LDVxVy":
+		return fmt.Sprintf("%s := %s", vx, vy)
+	case "ORVxVy":
+		return fmt.Sprintf("%s |= %s", vx, vy)
+	case "ANDVxVy":
+		return fmt.Sprintf("%s &= %s", vx, vy)
+	case "XORVxVy":
+		return fmt.Sprintf("%s ^= %s", vx, vy)
+	case "ADDVxVy":
+		return fmt.Sprintf("%s += %s", vx, vy)
+	case "SUBVxVy":
+		return fmt.Sprintf("%s -= %s", vx, vy)
+	case "SHRVx":
+		return fmt.Sprintf("%s >>= %s", vx, vy)
+	case "SUBNVxVy":
+		return fmt.Sprintf("%s =- %s", vx, vy)
+	case "SHLVx":
+		return fmt.Sprintf("%s <<= %s", vx, vy)
+	case "LDI":
+		return "i := " + addr
+	case "JPV":
+		return "jump0 " + addr
+	case "RNDVx":
+		return fmt.Sprintf("%s := random 0x%02X", vx, in.NN)
+	case "DRW":
+		return fmt.Sprintf("sprite %s %s %d", vx, vy, in.N)
+	case "SKPVx":
+		return fmt.Sprintf("if %s -key then", vx)
+	case "SKNPVx":
+		return fmt.Sprintf("if %s key then", vx)
+	case "LDVxDT":
+		return vx + " := delay"
+	case "LDVxK":
+		return vx + " := key"
+	case "LDDTVx":
+		return "delay := " + vx
+	case "LDSTVx":
+		return "buzzer := " + vx
+	case "ADDIVx":
+		return "i += " + vx
+	case "LDFVx":
+		return "i := hex " + vx
+	case "LDBVx":
+		return "bcd " + vx
+	case "LDIVx":
+		return "save " + vx
+	case "LDVxI":
+		return "load " + vx
+	}
+
+	return ""
+}
+
+func octoBytes(b []byte) string {
+	parts := make([]string, len(b))
+	for idx, v := range b {
+		parts[idx] = fmt.Sprintf("0x%02X", v)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// WriteSource writes Octo source code that compiles back to the same ROM.
+func (p *Program) WriteSource(w io.Writer) error {
+	fmt.Fprintln(w, "# Disassembled by gr8")
+	if len(p.ROM) == 0 {
+		_, err := fmt.Fprintln(w, ": main")
+		return err
+	}
+
+	entries := p.entries()
+	for idx, e := range entries {
+		if name := p.Labels[e.addr]; name != "" {
+			fmt.Fprintf(w, "\n: %s\n", name)
+		}
+
+		var err error
+		if e.code {
+			if name := p.Labels[e.addr+1]; name != "" {
+				fmt.Fprintf(w, "\t:next %s\n", name)
+			}
+
+			stmt := p.octo(e.in)
+			// A trailing skip has no statement to guard
+			if strings.HasSuffix(stmt, " then") && idx == len(entries)-1 {
+				stmt = ""
+			}
+
+			if stmt == "" {
+				stmt = octoBytes(e.bytes)
+			}
+			_, err = fmt.Fprintf(w, "\t%-24s # 0x%03X %s\n", stmt, e.addr, e.in.Format(p.Label))
+		} else {
+			_, err = fmt.Fprintf(w, "\t%s\n", octoBytes(e.bytes))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type jsonEntry struct {
+	Address  uint16  `json:"address"`
+	Kind     string  `json:"kind"`
+	Bytes    string  `json:"bytes"`
+	Label    string  `json:"label,omitempty"`
+	Mnemonic string  `json:"mnemonic,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	Target   *uint16 `json:"target,omitempty"`
+}
+
+type jsonLabel struct {
+	Address uint16 `json:"address"`
+	Name    string `json:"name"`
+}
+
+type jsonProgram struct {
+	Start    uint16      `json:"start"`
+	Size     int         `json:"size"`
+	Labels   []jsonLabel `json:"labels"`
+	Indirect []uint16    `json:"indirect"`
+	Entries  []jsonEntry `json:"entries"`
+}
+
+// WriteJSON writes the disassembly as a JSON document for other tools.
+func (p *Program) WriteJSON(w io.Writer) error {
+	out := jsonProgram{
+		Start:    emulator.ROM_START,
+		Size:     len(p.ROM),
+		Labels:   []jsonLabel{},
+		Indirect: p.Indirect,
+		Entries:  []jsonEntry{},
+	}
+
+	if out.Indirect == nil {
+		out.Indirect = []uint16{}
+	}
+
+	for off := range p.ROM {
+		addr := emulator.ROM_START + uint16(off)
+		if name := p.Labels[addr]; name != "" {
+			out.Labels = append(out.Labels, jsonLabel{addr, name})
+		}
+	}
+
+	for _, e := range p.entries() {
+		je := jsonEntry{
+			Address: e.addr,
+			Kind:    "data",
+			Bytes:   hexBytes(e.bytes, ""),
+			Label:   p.Labels[e.addr],
+		}
+
+		if e.code {
+			je.Kind = "code"
+			je.Mnemonic = e.in.Mnemonic
+			je.Text = e.in.Format(p.Label)
+			if e.in.HasAddress() {
+				target := e.in.NNN
+				je.Target = &target
+			}
+		}
+
+		out.Entries = append(out.Entries, je)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,114 @@
+package disasm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/octo"
+	"github.com/aricodes-oss/gr8/roms"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var bundled = map[string][]byte{
+	"chip8-logo": roms.Chip8Logo,
+	"ibm-logo":   roms.IBMLogo,
+	"corax+":     roms.Corax,
+	"flags":      roms.Flags,
+	"quirks":     roms.Quirks,
+	"keypad":     roms.Keypad,
+	"beep":       roms.Beep,
+	"scrolling":  roms.Scrolling,
+}
+
+func TestSourceReassembles(t *testing.T) {
+	for name, rom := range bundled {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var src bytes.Buffer
+			assert.NoError(Disassemble(rom).WriteSource(&src))
+
+			prog, err := octo.Compile(&src)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(rom, prog.ROM)
+		})
+	}
+}
+
+func TestSourceEdgeCases(t *testing.T) {
+	assert := assert.New(t)
+
+	// A trailing skip, a label inside an instruction and an opcode Octo
+	// can't spell
+	rom := []byte{
+		0x12, 0x04, // 200: jump 204
+		0x00, 0x00, // 202: data
+		0xA2, 0x07, // 204: i := 207
+		0x51, 0x21, // 206: 5xy1
+		0x30, 0x00, // 208: trailing skip
+	}
+
+	var src bytes.Buffer
+	assert.NoError(Disassemble(rom).WriteSource(&src))
+	assert.Contains(src.String(), ":next data_207")
+
+	prog, err := octo.Compile(&src)
+	assert.NoError(err)
+	assert.Equal(rom, prog.ROM)
+}
+
+func TestListing(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(Disassemble([]byte{0xA2, 0x04, 0x12, 0x02, 0xFF}).WriteListing(&out))
+	assert.Equal(strings.Join([]string{
+		"main:",
+		"0x200  A2 04  LDI data_204",
+		"",
+		"loc_202:",
+		"0x202  12 02  JMP loc_202",
+		"",
+		"data_204:",
+		"0x204  FF",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(Disassemble([]byte{0xA2, 0x04, 0x12, 0x02, 0xFF}).WriteJSON(&out))
+
+	var doc struct {
+		Start  uint16
+		Size   int
+		Labels []struct {
+			Address uint16
+			Name    string
+		}
+		Entries []struct {
+			Address  uint16
+			Kind     string
+			Bytes    string
+			Mnemonic string
+			Target   *uint16
+		}
+	}
+	assert.NoError(json.Unmarshal(out.Bytes(), &doc))
+
+	assert.Equal(uint16(0x200), doc.Start)
+	assert.Equal(5, doc.Size)
+	assert.Len(doc.Labels, 3)
+	assert.Len(doc.Entries, 3)
+	assert.Equal("LDI", doc.Entries[0].Mnemonic)
+	assert.Equal(uint16(0x204), *doc.Entries[0].Target)
+	assert.Equal("data", doc.Entries[2].Kind)
+	assert.Equal("FF", doc.Entries[2].Bytes)
+}
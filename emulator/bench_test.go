@@ -0,0 +1,66 @@
+package emulator
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/roms"
+)
+
+// BenchmarkROMs reports the instructions per second the bundled ROMs run at,
+// on the interpreter with and without the decode cache and on the block
+// engine, and with idle loops skipped. Skipped instructions count as run.
+// Display wait is off so that every frame runs all of its instructions.
+func BenchmarkROMs(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		rom  []byte
+	}{
+		{"chip8-logo", roms.Chip8Logo},
+		{"ibm-logo", roms.IBMLogo},
+		{"corax+", roms.Corax},
+		{"flags", roms.Flags},
+		{"quirks", roms.Quirks},
+		{"keypad", roms.Keypad},
+		{"beep", roms.Beep},
+		{"scrolling", roms.Scrolling},
+	}
+
+	engines := []struct {
+		name   string
+		engine Engine
+		cache  bool
+		idle   bool
+	}{
+		{"cache=false", EngineInterpreter, false, false},
+		{"cache=true", EngineInterpreter, true, false},
+		{"blocks", EngineBlocks, true, false},
+		{"idle", EngineInterpreter, true, true},
+		{"blocks+idle", EngineBlocks, true, true},
+	}
+
+	for _, bench := range benchmarks {
+		for _, e := range engines {
+			b.Run(fmt.Sprintf("%s/%s", bench.name, e.name), func(b *testing.B) {
+				emu, err := NewEmulatorFromBuf(bytes.NewReader(bench.rom), DEFAULT_CLOCK_SPEED)
+				if err != nil {
+					b.Fatal(err)
+				}
+				c := emu.(*chip8)
+				c.SetQuirks(QuirksXOCHIP)
+				c.SetDecodeCache(e.cache)
+				c.SetEngine(e.engine)
+				c.SetIdleSkip(e.idle)
+
+				start := c.cycles
+				for b.Loop() {
+					if err := c.RunFrame(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(c.cycles-start)/b.Elapsed().Seconds(), "instr/s")
+			})
+		}
+	}
+}
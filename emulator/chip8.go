@@ -2,11 +2,9 @@ package emulator
 
 import (
 	"encoding/binary"
-	"image"
 	"math/rand/v2"
+	"sync/atomic"
 	"time"
-
-	"github.com/gammazero/deque"
 )
 
 const DISPLAY_WIDTH = 64
@@ -24,7 +22,7 @@ type chip8 struct {
 	mem [MEM_SIZE]byte
 
 	// Monochromatic display buffer
-	display [DISPLAY_SIZE]bool
+	display Bitplane
 
 	// Program counter/instruction pointer
 	pc uint16
@@ -48,6 +46,31 @@ type chip8 struct {
 	// Instructions to process per frame
 	ipf int
 
+	// Platform differences to emulate
+	quirks Quirks
+
+	// Decoded instructions by address, if the decode cache is enabled
+	decodeCache bool
+	decoded     [MEM_SIZE]instruction
+
+	// Engine that runs frames, and the blocks it has compiled by address
+	engine Engine
+	blocks [MEM_SIZE]*block
+
+	// Whether idle loops are skipped, the state at the last loop point and
+	// the instructions run before it, the instructions skipped so far, and
+	// the changes to memory, the display and the RNG so far
+	idleSkip   bool
+	idle       idleState
+	idleCycles uint64
+	idlePoints int
+	skipped    uint64
+	effects    uint64
+
+	// Frames begun so far, and instructions run in the current one
+	frame       uint64
+	frameCycles int
+
 	// Keypad state (16 keys)
 	keypad, // Live
 	lastFrameKeys, // Last frame
@@ -59,51 +82,131 @@ type chip8 struct {
 	// RNG generator
 	rng *rand.Rand
 
-	// Frame buffer
-	frameBuf deque.Deque[*image.RGBA]
+	// Rendered frames, and whether the display changed since the last one
+	frames frames
+	dirty  bool
+
+	// Hooks run before every instruction, and at the end of every frame
+	execHooks  []ExecHook
+	frameHooks []FrameHook
+
+	// Watchpoints in the order they were added, and hooks run when one is hit
+	watchpoints []watch
+	lastWatchID int
+	watchHooks  []WatchHook
+
+	// Recently executed instructions, and the number run so far
+	history [HISTORY_SIZE]HistoryEntry
+	cycles  uint64
+
+	// Fault raised by the running instruction, and the error that stopped Run
+	fault  *Fault
+	runErr atomic.Pointer[error]
 }
 
-// opcode returns the full 2-byte instruction
-func (c *chip8) opcode() uint16 {
-	return binary.BigEndian.Uint16(c.mem[c.pc : c.pc+2])
+// opcodeAt returns the 2-byte instruction at addr. It reads memory directly,
+// so it never sets off watchpoints.
+func (c *chip8) opcodeAt(addr uint16) uint16 {
+	return binary.BigEndian.Uint16(c.mem[addr : addr+2])
 }
 
-// The second opcode nibble
-func (c *chip8) x() uint8 {
-	return uint8((c.opcode() & 0x0F00) >> 8)
+// Instructions access memory, registers and timers through the accessors
+// below so that watchpoints can observe them. With no watchpoints set they
+// cost a length check.
+
+// load reads the byte at addr, faulting past the end of memory.
+func (c *chip8) load(addr uint16) byte {
+	if addr >= MEM_SIZE {
+		c.fail(ErrMemoryOutOfBounds, addr)
+		return 0
+	}
+
+	b := c.mem[addr]
+	if len(c.watchpoints) > 0 {
+		c.watchMemory(addr, AccessRead, b, b)
+	}
+
+	return b
 }
 
-// Register V[x]
-func (c *chip8) vx() uint8 {
-	return c.v[c.x()]
+// store writes b to addr, faulting past the end of memory.
+func (c *chip8) store(addr uint16, b byte) {
+	if addr >= MEM_SIZE {
+		c.fail(ErrMemoryOutOfBounds, addr)
+		return
+	}
+
+	old := c.mem[addr]
+	c.mem[addr] = b
+	c.effects++
+	if c.decodeCache || c.engine == EngineBlocks {
+		c.invalidate(int(addr), int(addr)+1)
+	}
+	if len(c.watchpoints) > 0 {
+		c.watchMemory(addr, AccessWrite, old, b)
+	}
 }
 
-// The third opcode nibble
-func (c *chip8) y() uint8 {
-	return uint8((c.opcode() & 0x00F0) >> 4)
+// reg reads register V[x].
+func (c *chip8) reg(x uint8) uint8 {
+	b := c.v[x]
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(Register(x), AccessRead, uint16(b), uint16(b))
+	}
+
+	return b
 }
 
-// Register V[y]
-func (c *chip8) vy() uint8 {
-	return c.v[c.y()]
+// setReg writes register V[x].
+func (c *chip8) setReg(x, b uint8) {
+	old := c.v[x]
+	c.v[x] = b
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(Register(x), AccessWrite, uint16(old), uint16(b))
+	}
 }
 
-// Register V[0xF]
-func (c *chip8) vf() uint8 {
-	return c.v[0xF]
+// index reads the index register.
+func (c *chip8) index() uint16 {
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(RegI, AccessRead, c.i, c.i)
+	}
+
+	return c.i
+}
+
+// setIndex writes the index register.
+func (c *chip8) setIndex(i uint16) {
+	old := c.i
+	c.i = i
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(RegI, AccessWrite, old, i)
+	}
 }
 
-// The 4th nibble
-func (c *chip8) n() uint8 {
-	return uint8(c.opcode() & 0x000F)
+// delay reads the delay timer.
+func (c *chip8) delay() byte {
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(RegDT, AccessRead, uint16(c.delayTimer), uint16(c.delayTimer))
+	}
+
+	return c.delayTimer
 }
 
-// Second byte (3rd+4th nibble)
-func (c *chip8) nn() uint8 {
-	return uint8(c.opcode() & 0x00FF)
+// setDelay writes the delay timer.
+func (c *chip8) setDelay(b byte) {
+	old := c.delayTimer
+	c.delayTimer = b
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(RegDT, AccessWrite, uint16(old), uint16(b))
+	}
 }
 
-// 2nd+3rd+4th nibble
-func (c *chip8) nnn() uint16 {
-	return uint16(c.opcode() & 0x0FFF)
+// setSound writes the sound timer.
+func (c *chip8) setSound(b byte) {
+	old := c.soundTimer
+	c.soundTimer = b
+	if len(c.watchpoints) > 0 {
+		c.watchRegister(RegST, AccessWrite, uint16(old), uint16(b))
+	}
 }
@@ -9,6 +9,6 @@ func TestOpcodeSanity(t *testing.T) {
 	c, assert := setup(t)
 
 	// Opcode
-	fmt.Println(c.opcode())
+	fmt.Println(c.opcodeAt(c.pc))
 	assert.Equal(1, 1)
 }
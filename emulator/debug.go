@@ -0,0 +1,101 @@
+package emulator
+
+// State is a snapshot of the CPU registers.
+type State struct {
+	PC uint16
+	I  uint16
+	V  [16]byte
+
+	// Return addresses, innermost last
+	Stack []uint16
+
+	DelayTimer byte
+	SoundTimer byte
+}
+
+// State returns a snapshot of the CPU registers.
+func (c *chip8) State() State {
+	return State{
+		PC:         c.pc,
+		I:          c.i,
+		V:          c.v,
+		Stack:      append([]uint16(nil), c.stack...),
+		DelayTimer: c.delayTimer,
+		SoundTimer: c.soundTimer,
+	}
+}
+
+// SetState overwrites the CPU registers.
+func (c *chip8) SetState(s State) {
+	c.pc = s.PC
+	c.i = s.I
+	c.v = s.V
+	c.stack = append([]uint16(nil), s.Stack...)
+	c.delayTimer = s.DelayTimer
+	c.soundTimer = s.SoundTimer
+}
+
+// PC returns the program counter.
+func (c *chip8) PC() uint16 {
+	return c.State().PC
+}
+
+// I returns the index register.
+func (c *chip8) I() uint16 {
+	return c.State().I
+}
+
+// Registers returns V0-VF.
+func (c *chip8) Registers() [16]byte {
+	return c.State().V
+}
+
+// Stack returns a copy of the return addresses, innermost last.
+func (c *chip8) Stack() []uint16 {
+	return c.State().Stack
+}
+
+// Timers returns the delay and sound timers.
+func (c *chip8) Timers() (delay, sound byte) {
+	s := c.State()
+	return s.DelayTimer, s.SoundTimer
+}
+
+// Memory returns a copy of memory.
+func (c *chip8) Memory() []byte {
+	mem := make([]byte, MEM_SIZE)
+	c.ReadMemory(0, mem)
+	return mem
+}
+
+// ReadMemory copies memory starting at addr into buf, and returns the
+// number of bytes copied.
+func (c *chip8) ReadMemory(addr uint16, buf []byte) int {
+	if int(addr) >= MEM_SIZE {
+		return 0
+	}
+
+	return copy(buf, c.mem[addr:])
+}
+
+// WriteMemory copies data into memory starting at addr, and returns the
+// number of bytes written.
+func (c *chip8) WriteMemory(addr uint16, data []byte) int {
+	if int(addr) >= MEM_SIZE {
+		return 0
+	}
+
+	n := copy(c.mem[addr:], data)
+	c.invalidate(int(addr), int(addr)+n)
+	return n
+}
+
+// Pixel returns whether the display pixel at (x, y) is lit.
+func (c *chip8) Pixel(x, y int) bool {
+	return c.display.Pixel(x, y)
+}
+
+// FrameCount returns the number of frames begun so far.
+func (c *chip8) FrameCount() uint64 {
+	return c.frame
+}
@@ -0,0 +1,107 @@
+package emulator
+
+import (
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	c, assert := setup(t)
+	c.v[3] = 0x12
+	c.i = 0x300
+	c.stack = []uint16{0x204}
+	c.delayTimer = 5
+
+	s := c.State()
+	assert.Equal(ROM_START, s.PC)
+	assert.Equal(uint16(0x300), s.I)
+	assert.Equal(uint8(0x12), s.V[3])
+	assert.Equal([]uint16{0x204}, s.Stack)
+	assert.Equal(uint8(5), s.DelayTimer)
+
+	// Snapshots don't alias the live stack
+	s.Stack[0] = 0
+	assert.Equal(uint16(0x204), c.stack[0])
+}
+
+func TestSetState(t *testing.T) {
+	c, assert := setup(t)
+
+	s := c.State()
+	s.PC = 0x2A4
+	s.V[0xF] = 1
+	s.Stack = append(s.Stack, 0x202)
+	s.SoundTimer = 9
+	c.SetState(s)
+
+	assert.Equal(uint16(0x2A4), c.pc)
+	assert.Equal(uint8(1), c.v[0xF])
+	assert.Equal([]uint16{0x202}, c.stack)
+	assert.Equal(uint8(9), c.soundTimer)
+}
+
+func TestReadWriteMemory(t *testing.T) {
+	c, assert := setup(t)
+
+	assert.Equal(3, c.WriteMemory(0xE00, []byte{1, 2, 3}))
+	buf := make([]byte, 4)
+	assert.Equal(4, c.ReadMemory(0xDFF, buf))
+	assert.Equal([]byte{0, 1, 2, 3}, buf)
+
+	// Accesses are clipped to the end of memory
+	assert.Equal(1, c.WriteMemory(MEM_SIZE-1, []byte{9, 9}))
+	assert.Equal(0, c.ReadMemory(MEM_SIZE, buf))
+}
+
+func TestPixel(t *testing.T) {
+	c, assert := setup(t)
+	c.display.Set(2, 1, true)
+
+	assert.True(c.Pixel(2, 1))
+	assert.False(c.Pixel(1, 2))
+	assert.False(c.Pixel(DISPLAY_WIDTH, 0))
+}
+
+func TestInspect(t *testing.T) {
+	c, assert := setup(t)
+	c.v[3] = 0x12
+	c.i = 0x300
+	c.stack = []uint16{0x204}
+	c.delayTimer, c.soundTimer = 5, 7
+
+	assert.Equal(ROM_START, c.PC())
+	assert.Equal(uint16(0x300), c.I())
+	assert.Equal(uint8(0x12), c.Registers()[3])
+
+	delay, sound := c.Timers()
+	assert.Equal(uint8(5), delay)
+	assert.Equal(uint8(7), sound)
+
+	// Copies don't alias the live machine
+	stack := c.Stack()
+	stack[0] = 0
+	assert.Equal(uint16(0x204), c.stack[0])
+
+	mem := c.Memory()
+	assert.Len(mem, MEM_SIZE)
+	assert.Equal(rom[0], mem[ROM_START])
+	mem[ROM_START] = 0
+	assert.Equal(rom[0], c.mem[ROM_START])
+}
+
+func TestDisplay(t *testing.T) {
+	c, assert := setup(t)
+	c.display.Set(2, 1, true)
+	c.display.Set(DISPLAY_WIDTH-1, DISPLAY_HEIGHT-1, true)
+
+	d := c.Display()
+	assert.Equal(DISPLAY_WIDTH, d.Width)
+	assert.Equal(DISPLAY_HEIGHT, d.Height)
+	assert.Equal(uint64(1)<<61, d.Bits[1])
+	assert.Equal(uint64(1), d.Bits[DISPLAY_HEIGHT-1])
+	assert.True(d.Pixel(2, 1))
+	assert.False(d.Pixel(1, 2))
+
+	// Snapshots don't alias the display
+	d.Set(0, 0, true)
+	assert.False(c.display.Pixel(0, 0))
+}
@@ -0,0 +1,293 @@
+package emulator
+
+// op identifies the handler for an instruction.
+type op uint8
+
+const (
+	// Not decoded yet, in the decode cache
+	opNone op = iota
+
+	opInvalid
+	opSYS
+	opCLS
+	opRET
+	opJMP
+	opCALL
+	opSEVx
+	opSNEVx
+	opSEVxVy
+	opLD
+	opADD
+	opLDVxVy
+	opORVxVy
+	opANDVxVy
+	opXORVxVy
+	opADDVxVy
+	opSUBVxVy
+	opSHRVx
+	opSUBNVxVy
+	opSHLVx
+	opSNEVxVy
+	opLDI
+	opJPV
+	opRNDVx
+	opDRW
+	opSKPVx
+	opSKNPVx
+	opLDVxDT
+	opLDVxK
+	opLDDTVx
+	opLDSTVx
+	opADDIVx
+	opLDFVx
+	opLDBVx
+	opLDIVx
+	opLDVxI
+
+	opCount
+)
+
+// instruction is an opcode split into its operands, so handlers don't have to
+// decode it again.
+type instruction struct {
+	opcode uint16
+	op     op
+
+	// Second, third and fourth nibbles
+	x, y, n uint8
+
+	// Second byte, and the last three nibbles
+	nn  uint8
+	nnn uint16
+}
+
+// decode splits opcode into its operands and finds its handler.
+func decode(opcode uint16) instruction {
+	return instruction{
+		opcode: opcode,
+		op:     opFor(opcode),
+		x:      uint8((opcode & 0x0F00) >> 8),
+		y:      uint8((opcode & 0x00F0) >> 4),
+		n:      uint8(opcode & 0x000F),
+		nn:     uint8(opcode & 0x00FF),
+		nnn:    opcode & 0x0FFF,
+	}
+}
+
+// opFor returns the handler for opcode. Machine code routines (0nnn) are
+// ignored, and any other opcode the emulator doesn't implement is invalid.
+func opFor(opcode uint16) op {
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return opCLS
+		case 0x00EE:
+			return opRET
+		}
+		return opSYS
+	case 0x1000:
+		return opJMP
+	case 0x2000:
+		return opCALL
+	case 0x3000:
+		return opSEVx
+	case 0x4000:
+		return opSNEVx
+	case 0x5000:
+		return opSEVxVy
+	case 0x6000:
+		return opLD
+	case 0x7000:
+		return opADD
+	case 0x8000:
+		switch opcode & 0xF00F {
+		case 0x8000:
+			return opLDVxVy
+		case 0x8001:
+			return opORVxVy
+		case 0x8002:
+			return opANDVxVy
+		case 0x8003:
+			return opXORVxVy
+		case 0x8004:
+			return opADDVxVy
+		case 0x8005:
+			return opSUBVxVy
+		case 0x8006:
+			return opSHRVx
+		case 0x8007:
+			return opSUBNVxVy
+		case 0x800E:
+			return opSHLVx
+		}
+	case 0x9000:
+		return opSNEVxVy
+	case 0xA000:
+		return opLDI
+	case 0xB000:
+		return opJPV
+	case 0xC000:
+		return opRNDVx
+	case 0xD000:
+		return opDRW
+	case 0xE000:
+		switch opcode & 0xF0FF {
+		case 0xE09E:
+			return opSKPVx
+		case 0xE0A1:
+			return opSKNPVx
+		}
+	case 0xF000:
+		switch opcode & 0xF0FF {
+		case 0xF007:
+			return opLDVxDT
+		case 0xF00A:
+			return opLDVxK
+		case 0xF015:
+			return opLDDTVx
+		case 0xF018:
+			return opLDSTVx
+		case 0xF01E:
+			return opADDIVx
+		case 0xF029:
+			return opLDFVx
+		case 0xF033:
+			return opLDBVx
+		case 0xF055:
+			return opLDIVx
+		case 0xF065:
+			return opLDVxI
+		}
+	}
+
+	return opInvalid
+}
+
+// opNames names each op after its handler.
+var opNames = [opCount]string{
+	opSYS:      "SYS",
+	opCLS:      "CLS",
+	opRET:      "RET",
+	opJMP:      "JMP",
+	opCALL:     "CALL",
+	opSEVx:     "SEVx",
+	opSNEVx:    "SNEVx",
+	opSEVxVy:   "SEVxVy",
+	opLD:       "LD",
+	opADD:      "ADD",
+	opLDVxVy:   "LDVxVy",
+	opORVxVy:   "ORVxVy",
+	opANDVxVy:  "ANDVxVy",
+	opXORVxVy:  "XORVxVy",
+	opADDVxVy:  "ADDVxVy",
+	opSUBVxVy:  "SUBVxVy",
+	opSHRVx:    "SHRVx",
+	opSUBNVxVy: "SUBNVxVy",
+	opSHLVx:    "SHLVx",
+	opSNEVxVy:  "SNEVxVy",
+	opLDI:      "LDI",
+	opJPV:      "JPV",
+	opRNDVx:    "RNDVx",
+	opDRW:      "DRW",
+	opSKPVx:    "SKPVx",
+	opSKNPVx:   "SKNPVx",
+	opLDVxDT:   "LDVxDT",
+	opLDVxK:    "LDVxK",
+	opLDDTVx:   "LDDTVx",
+	opLDSTVx:   "LDSTVx",
+	opADDIVx:   "ADDIVx",
+	opLDFVx:    "LDFVx",
+	opLDBVx:    "LDBVx",
+	opLDIVx:    "LDIVx",
+	opLDVxI:    "LDVxI",
+}
+
+// Mnemonic returns the name of the handler in opcodes.go that runs opcode,
+// such as "DRW", or "" if the emulator doesn't implement it. Tools that
+// decode instructions use it so that they agree with what the emulator runs.
+func Mnemonic(opcode uint16) string {
+	return opNames[opFor(opcode)]
+}
+
+// handlers runs each op. Invalid and undecoded instructions have none.
+var handlers = [opCount]func(c *chip8, in instruction){
+	opSYS:      (*chip8).SYS,
+	opCLS:      (*chip8).CLS,
+	opRET:      (*chip8).RET,
+	opJMP:      (*chip8).JMP,
+	opCALL:     (*chip8).CALL,
+	opSEVx:     (*chip8).SEVx,
+	opSNEVx:    (*chip8).SNEVx,
+	opSEVxVy:   (*chip8).SEVxVy,
+	opLD:       (*chip8).LD,
+	opADD:      (*chip8).ADD,
+	opLDVxVy:   (*chip8).LDVxVy,
+	opORVxVy:   (*chip8).ORVxVy,
+	opANDVxVy:  (*chip8).ANDVxVy,
+	opXORVxVy:  (*chip8).XORVxVy,
+	opADDVxVy:  (*chip8).ADDVxVy,
+	opSUBVxVy:  (*chip8).SUBVxVy,
+	opSHRVx:    (*chip8).SHRVx,
+	opSUBNVxVy: (*chip8).SUBNVxVy,
+	opSHLVx:    (*chip8).SHLVx,
+	opSNEVxVy:  (*chip8).SNEVxVy,
+	opLDI:      (*chip8).LDI,
+	opJPV:      (*chip8).JPV,
+	opRNDVx:    (*chip8).RNDVx,
+	opDRW:      (*chip8).DRW,
+	opSKPVx:    (*chip8).SKPVx,
+	opSKNPVx:   (*chip8).SKNPVx,
+	opLDVxDT:   (*chip8).LDVxDT,
+	opLDVxK:    (*chip8).LDVxK,
+	opLDDTVx:   (*chip8).LDDTVx,
+	opLDSTVx:   (*chip8).LDSTVx,
+	opADDIVx:   (*chip8).ADDIVx,
+	opLDFVx:    (*chip8).LDFVx,
+	opLDBVx:    (*chip8).LDBVx,
+	opLDIVx:    (*chip8).LDIVx,
+	opLDVxI:    (*chip8).LDVxI,
+}
+
+// fetch returns the decoded instruction at pc. With the decode cache enabled,
+// each address is only decoded again after memory under it is written.
+func (c *chip8) fetch() instruction {
+	if c.decodeCache {
+		if in := c.decoded[c.pc]; in.op != opNone {
+			return in
+		}
+	}
+
+	return c.decodeAt()
+}
+
+// decodeAt decodes the instruction at pc, caching it if the cache is enabled.
+func (c *chip8) decodeAt() instruction {
+	in := decode(c.opcodeAt(c.pc))
+	if c.decodeCache {
+		c.decoded[c.pc] = in
+	}
+
+	return in
+}
+
+// invalidate drops the cached instructions and compiled blocks that overlap
+// memory from addr up to but not including end.
+func (c *chip8) invalidate(addr, end int) {
+	c.invalidateBlocks(addr, end)
+
+	// The instruction starting a byte earlier overlaps addr too
+	addr = max(addr-1, 0)
+	end = min(end, MEM_SIZE)
+	if addr >= end {
+		return
+	}
+
+	clear(c.decoded[addr:end])
+}
+
+// SetDecodeCache turns the decode cache on or off.
+func (c *chip8) SetDecodeCache(enabled bool) {
+	c.decodeCache = enabled
+	c.invalidate(0, MEM_SIZE)
+}
@@ -0,0 +1,67 @@
+package emulator
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(instruction{opcode: 0xD12A, op: opDRW, x: 1, y: 2, n: 0xA, nn: 0x2A, nnn: 0x12A}, decode(0xD12A))
+	assert.Equal(opSYS, decode(0x0123).op)
+	assert.Equal(opCLS, decode(0x00E0).op)
+	assert.Equal(opSHLVx, decode(0x834E).op)
+	assert.Equal(opInvalid, decode(0x8008).op)
+	assert.Equal(opInvalid, decode(0xE0FF).op)
+	assert.Equal(opInvalid, decode(0xF0FF).op)
+
+	// Every valid op has a handler, named after it
+	for o := opSYS; o < opCount; o++ {
+		assert.NotNil(handlers[o], "op %d", o)
+		name := runtime.FuncForPC(reflect.ValueOf(handlers[o]).Pointer()).Name()
+		assert.True(strings.HasSuffix(name, "."+opNames[o]), "op %d is %s, not %s", o, opNames[o], name)
+	}
+}
+
+func TestMnemonic(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("SYS", Mnemonic(0x0123))
+	assert.Equal("RET", Mnemonic(0x00EE))
+	assert.Equal("DRW", Mnemonic(0xD12A))
+	assert.Equal("", Mnemonic(0x8008))
+}
+
+// Runs the instruction at 0x20A, then rewrites it and runs it again
+var selfModifying = []byte{
+	0x22, 0x0A, // 0x200 CALL 0x20A
+	0xA2, 0x0A, // 0x202 LD I, 0x20A
+	0x60, 0x62, // 0x204 LD V0, 0x62
+	0x61, 0x09, // 0x206 LD V1, 0x09
+	0xF1, 0x55, // 0x208 LD [I], V0-V1, making the next instruction LD V2, 0x09
+	0x62, 0x01, // 0x20A LD V2, 0x01
+	0x00, 0xEE, // 0x20C RET
+}
+
+func TestDecodeCache(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		c, assert := opcodeTest(t, selfModifying)
+		c.SetDecodeCache(cache)
+
+		for range 8 {
+			assert.NoError(c.Cycle())
+		}
+		assert.Equal(uint8(0x09), c.v[2], "decode cache %t", cache)
+
+		// Writes from outside invalidate the cache too
+		c.WriteMemory(0x20A, []byte{0x62, 0x05})
+		c.pc = 0x20A
+		assert.NoError(c.Cycle())
+		assert.Equal(uint8(0x05), c.v[2], "decode cache %t", cache)
+	}
+}
@@ -0,0 +1,145 @@
+package emulator
+
+// Bitplane is a snapshot of the display with one bit per pixel. Each row is
+// packed into words of 64 pixels, with the leftmost pixel in the most
+// significant bit.
+type Bitplane struct {
+	Width, Height int
+
+	// Rows of pixels, one after the other
+	Bits []uint64
+}
+
+// NewBitplane returns an unlit bitplane of the given size.
+func NewBitplane(width, height int) Bitplane {
+	return Bitplane{Width: width, Height: height, Bits: make([]uint64, height*stride(width))}
+}
+
+// stride returns the words in each row of a bitplane.
+func stride(width int) int {
+	return (width + 63) / 64
+}
+
+// Pixel returns whether the pixel at (x, y) is lit.
+func (b Bitplane) Pixel(x, y int) bool {
+	if x < 0 || x >= b.Width || y < 0 || y >= b.Height {
+		return false
+	}
+
+	word := b.Bits[y*stride(b.Width)+x/64]
+	return word&(1<<(63-x%64)) != 0
+}
+
+// Set lights or clears the pixel at (x, y).
+func (b Bitplane) Set(x, y int, lit bool) {
+	if x < 0 || x >= b.Width || y < 0 || y >= b.Height {
+		return
+	}
+
+	bit := uint64(1) << (63 - x%64)
+	if lit {
+		b.Bits[y*stride(b.Width)+x/64] |= bit
+	} else {
+		b.Bits[y*stride(b.Width)+x/64] &^= bit
+	}
+}
+
+// Clear turns every pixel off.
+func (b Bitplane) Clear() {
+	clear(b.Bits)
+}
+
+// Clone returns a copy of b that doesn't share its pixels.
+func (b Bitplane) Clone() Bitplane {
+	b.Bits = append([]uint64(nil), b.Bits...)
+	return b
+}
+
+// Draw flips the pixels under an 8-pixel wide sprite with its top left corner
+// at (x, y), one byte per row, and reports whether any of them were lit. The
+// corner is wrapped onto the plane, and parts of the sprite past the edges
+// wrap around to the other side if wrap is set or are clipped otherwise.
+func (b Bitplane) Draw(x, y int, sprite []byte, wrap bool) bool {
+	x, y = x%b.Width, y%b.Height
+
+	collided := false
+	for offset, row := range sprite {
+		py := y + offset
+		if py >= b.Height {
+			if !wrap {
+				break
+			}
+			py %= b.Height
+		}
+
+		if b.xorRow(x, py, uint64(row)<<56, wrap) {
+			collided = true
+		}
+	}
+
+	return collided
+}
+
+// xorRow flips the pixels in row y under the set bits of a sprite row, given
+// with its leftmost pixel, at column x, in the most significant bit. It
+// reports whether any of them were lit.
+func (b Bitplane) xorRow(x, y int, bits uint64, wrap bool) bool {
+	// Rows with padding at the end can't be shifted across words
+	if b.Width%64 != 0 {
+		return b.xorPixels(x, y, bits, wrap)
+	}
+
+	n := stride(b.Width)
+	row := b.Bits[y*n : (y+1)*n]
+	word, shift := x/64, x%64
+	collided := xorWord(&row[word], bits>>shift)
+
+	// The rest of the sprite spills into the next word, or off the right edge
+	spill := bits << (64 - shift)
+	switch {
+	case spill == 0:
+	case word+1 < n:
+		collided = xorWord(&row[word+1], spill) || collided
+	case wrap:
+		collided = xorWord(&row[0], spill) || collided
+	}
+
+	return collided
+}
+
+// xorWord flips the bits of w that are set in bits, and reports whether any of
+// them were set in w.
+func xorWord(w *uint64, bits uint64) bool {
+	collided := *w&bits != 0
+	*w ^= bits
+	return collided
+}
+
+// xorPixels flips the pixels of a sprite row one at a time.
+func (b Bitplane) xorPixels(x, y int, bits uint64, wrap bool) bool {
+	collided := false
+	for col := 0; bits != 0; col, bits = col+1, bits<<1 {
+		if bits&(1<<63) == 0 {
+			continue
+		}
+
+		px := x + col
+		if px >= b.Width {
+			if !wrap {
+				break
+			}
+			px %= b.Width
+		}
+
+		lit := b.Pixel(px, y)
+		collided = collided || lit
+		b.Set(px, y, !lit)
+	}
+
+	return collided
+}
+
+// Display returns a snapshot of the display.
+func (c *chip8) Display() Bitplane {
+	return c.display.Clone()
+}
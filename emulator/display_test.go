@@ -0,0 +1,156 @@
+package emulator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitplane(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBitplane(128, 2)
+	assert.Len(b.Bits, 4)
+
+	b.Set(0, 0, true)
+	b.Set(127, 1, true)
+	b.Set(64, 1, true)
+	assert.Equal([]uint64{1 << 63, 0, 0, 1<<63 | 1}, b.Bits)
+	assert.True(b.Pixel(64, 1))
+
+	b.Set(64, 1, false)
+	assert.False(b.Pixel(64, 1))
+
+	// Pixels off the plane are unlit and can't be set
+	b.Set(128, 0, true)
+	assert.False(b.Pixel(128, 0))
+	assert.False(b.Pixel(-1, 0))
+}
+
+// boolDisplay is the display as it was before it was packed into bits, one
+// bool per pixel, drawn to one pixel at a time. Draws are checked and
+// benchmarked against it.
+type boolDisplay struct {
+	width, height int
+	pixels        []bool
+}
+
+func newBoolDisplay(width, height int) *boolDisplay {
+	return &boolDisplay{width, height, make([]bool, width*height)}
+}
+
+func (d *boolDisplay) draw(x, y int, sprite []byte, wrap bool) bool {
+	x, y = x%d.width, y%d.height
+
+	collided := false
+	for offset, row := range sprite {
+		py := y + offset
+		if py >= d.height {
+			if !wrap {
+				continue
+			}
+			py %= d.height
+		}
+
+		for col := range 8 {
+			px := x + col
+			if px >= d.width {
+				if !wrap {
+					break
+				}
+				px %= d.width
+			}
+
+			if row&(0x80>>col) == 0 {
+				continue
+			}
+
+			index := py*d.width + px
+			if d.pixels[index] {
+				collided = true
+			}
+			d.pixels[index] = !d.pixels[index]
+		}
+	}
+
+	return collided
+}
+
+func TestBitplaneDraw(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBitplane(64, 32)
+	assert.False(b.Draw(60, 31, []byte{0xFF, 0x81}, true))
+
+	// Wrapped around both edges
+	assert.True(b.Pixel(63, 31))
+	assert.True(b.Pixel(0, 31))
+	assert.True(b.Pixel(3, 31))
+	assert.False(b.Pixel(4, 31))
+	assert.True(b.Pixel(60, 0))
+	assert.True(b.Pixel(3, 0))
+	assert.False(b.Pixel(0, 0))
+
+	// Drawing again collides and erases the sprite
+	assert.True(b.Draw(60, 31, []byte{0xFF, 0x81}, true))
+	assert.Equal(NewBitplane(64, 32), b)
+
+	// The corner wraps even when clipping, and the rest is clipped
+	assert.False(b.Draw(124, 63, []byte{0xFF, 0xFF}, false))
+	assert.Equal([]uint64{0xF}, b.Bits[31:32])
+	assert.Equal(uint64(0), b.Bits[0])
+}
+
+func TestBitplaneDrawReference(t *testing.T) {
+	assert := assert.New(t)
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	// Hi-res planes span several words a row, and widths that aren't a
+	// multiple of 64 are drawn a pixel at a time
+	for _, size := range [][2]int{{64, 32}, {128, 64}, {96, 20}} {
+		for _, wrap := range []bool{false, true} {
+			width, height := size[0], size[1]
+			b, ref := NewBitplane(width, height), newBoolDisplay(width, height)
+
+			for range 1000 {
+				sprite := make([]byte, rng.IntN(16))
+				for idx := range sprite {
+					sprite[idx] = byte(rng.Uint32())
+				}
+				x, y := rng.IntN(256), rng.IntN(256)
+
+				assert.Equal(ref.draw(x, y, sprite, wrap), b.Draw(x, y, sprite, wrap))
+			}
+
+			for y := range height {
+				for x := range width {
+					assert.Equal(ref.pixels[y*width+x], b.Pixel(x, y), "%dx%d wrap=%t at (%d, %d)", width, height, wrap, x, y)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkDraw compares drawing sprites on the packed display with drawing
+// them on a display of bools.
+func BenchmarkDraw(b *testing.B) {
+	sprite := []byte{0x3C, 0x42, 0x81, 0xA5, 0x81, 0x99, 0x42, 0x3C}
+
+	for _, wrap := range []bool{false, true} {
+		b.Run(fmt.Sprintf("bitplane/wrap=%t", wrap), func(b *testing.B) {
+			plane := NewBitplane(DISPLAY_WIDTH, DISPLAY_HEIGHT)
+			for n := 0; b.Loop(); n++ {
+				plane.Draw(n*7, n*3, sprite, wrap)
+			}
+		})
+
+		b.Run(fmt.Sprintf("bools/wrap=%t", wrap), func(b *testing.B) {
+			display := newBoolDisplay(DISPLAY_WIDTH, DISPLAY_HEIGHT)
+			for n := 0; b.Loop(); n++ {
+				display.draw(n*7, n*3, sprite, wrap)
+			}
+		})
+	}
+}
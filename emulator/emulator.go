@@ -3,15 +3,12 @@ package emulator
 import (
 	"errors"
 	"image"
-	"image/color"
 	"io"
 	"math/rand/v2"
 	"os"
 	"time"
 )
 
-const FRAME_BUFFER_LENGTH = 3
-
 type Emulator interface {
 	Keypad
 
@@ -24,18 +21,135 @@ type Emulator interface {
 	// Cycle runs one CPU cycle.
 	Cycle() error
 
-	// Run runs the emulator in the background. Call Stop() to end it.
+	// Run runs the emulator in the background. Call Stop() to end it. Run
+	// returns early if an instruction faults, and Err returns the fault.
 	Run()
 
 	// Stop stops the background emulation process.
 	Stop()
 
-	// Frame returns the most recent frame from the display buffer
+	// Frame returns the most recent frame, or nil if the display hasn't
+	// changed since the last call. The frame is only valid until the next
+	// call.
 	Frame() *image.RGBA
+
+	// FrameNumber returns the number of the frame Frame last returned.
+	FrameNumber() uint64
+
+	// Stats returns a snapshot of the emulator published by Run at the end
+	// of every frame. It's safe to call while Run is running.
+	Stats() Stats
+
+	// Step runs one instruction, starting a new frame first if the current
+	// one has run all of its instructions.
+	Step() error
+
+	// RunFrame runs until the end of the current frame, without waiting for
+	// the clock.
+	RunFrame() error
+
+	// OnExecute registers a hook that is called before every instruction.
+	OnExecute(hook ExecHook)
+
+	// OnFrame registers a hook that is called at the end of every frame.
+	OnFrame(hook FrameHook)
+
+	// State returns a snapshot of the CPU registers.
+	State() State
+
+	// PC returns the program counter.
+	PC() uint16
+
+	// I returns the index register.
+	I() uint16
+
+	// Registers returns V0-VF.
+	Registers() [16]byte
+
+	// Stack returns a copy of the return addresses, innermost last.
+	Stack() []uint16
+
+	// Timers returns the delay and sound timers.
+	Timers() (delay, sound byte)
+
+	// Memory returns a copy of memory.
+	Memory() []byte
+
+	// Display returns a snapshot of the display, one bit per pixel.
+	Display() Bitplane
+
+	// ReadMemory copies memory starting at addr into buf.
+	ReadMemory(addr uint16, buf []byte) int
+
+	// Pixel returns whether the display pixel at (x, y) is lit.
+	Pixel(x, y int) bool
+
+	// FrameCount returns the number of frames begun so far.
+	FrameCount() uint64
+
+	// Watch adds a watchpoint and returns an ID that can be passed to Unwatch.
+	Watch(w Watchpoint) int
+
+	// Unwatch removes a watchpoint.
+	Unwatch(id int)
+
+	// OnWatch registers a hook that is called whenever a watchpoint is hit.
+	OnWatch(hook WatchHook)
+
+	// History returns the most recently executed instructions, oldest first.
+	History() []HistoryEntry
+
+	// Err returns the error that stopped Run, usually a *Fault, or nil if it
+	// hasn't stopped on one.
+	Err() error
+
+	// Quirks returns the platform differences the emulator runs with.
+	Quirks() Quirks
+
+	// SetQuirks changes the platform differences the emulator runs with.
+	SetQuirks(q Quirks)
+
+	// SetDecodeCache turns caching decoded instructions on or off. It's on by
+	// default, and only affects speed.
+	SetDecodeCache(enabled bool)
+
+	// Engine returns the engine that runs frames.
+	Engine() Engine
+
+	// SetEngine changes the engine that runs frames. The interpreter is the
+	// default, and every engine behaves the same.
+	SetEngine(e Engine)
+
+	// SetIdleSkip turns skipping idle loops, which can't make progress until
+	// the next frame, on or off. It's on by default, and only affects speed.
+	SetIdleSkip(enabled bool)
+
+	// Skipped returns the number of instructions skipped in idle loops so
+	// far. They still count towards the history and frame budgets.
+	Skipped() uint64
 }
 
+// Debuggable is an Emulator whose registers and memory can be changed, as
+// debuggers do. Tools that only inspect the machine take an Emulator.
+type Debuggable interface {
+	Emulator
+
+	// SetState overwrites the CPU registers.
+	SetState(s State)
+
+	// WriteMemory copies data into memory starting at addr.
+	WriteMemory(addr uint16, data []byte) int
+}
+
+// ExecHook receives the address and opcode of an instruction about to execute.
+type ExecHook func(pc, opcode uint16)
+
+// FrameHook receives the number of a frame that just ended and the display
+// at its end. The display is only valid until the hook returns.
+type FrameHook func(frame uint64, display Bitplane)
+
 // NewEmulator takes a path to a ROM file and returns an Emulator with that ROM loaded.
-func NewEmulator(rom_path string, clockSpeed time.Duration) (Emulator, error) {
+func NewEmulator(rom_path string, clockSpeed time.Duration) (Debuggable, error) {
 	c := baseChip8(clockSpeed)
 	err := c.LoadFile(rom_path)
 
@@ -43,7 +157,7 @@ func NewEmulator(rom_path string, clockSpeed time.Duration) (Emulator, error) {
 }
 
 // NewEmulatorFromBuf takes a ROM buffer and returns an Emulator with that ROM loaded.
-func NewEmulatorFromBuf(buf io.Reader, clockSpeed time.Duration) (Emulator, error) {
+func NewEmulatorFromBuf(buf io.Reader, clockSpeed time.Duration) (Debuggable, error) {
 	c := baseChip8(clockSpeed)
 	err := c.LoadBuffer(buf)
 
@@ -77,51 +191,130 @@ func (c *chip8) LoadBuffer(buf io.Reader) error {
 
 	// Copy the ROM data into memory starting at rom_start
 	copy(c.mem[ROM_START:], rom)
+	c.invalidate(int(ROM_START), int(ROM_START)+len(rom))
 
 	return nil
 }
 
-// Cycle runs one emulation cycle.
+// Cycle runs one emulation cycle. Faults leave the program counter on the
+// faulting instruction.
 func (c *chip8) Cycle() error {
-	err := c.dispatch(c.opcode())
+	if int(c.pc)+2 > MEM_SIZE {
+		return &Fault{PC: c.pc, Err: ErrPCOutOfBounds}
+	}
+
+	in := c.fetch()
+	for _, hook := range c.execHooks {
+		hook(c.pc, in.opcode)
+	}
+	if len(c.watchpoints) > 0 {
+		c.watchExecute()
+	}
+	c.record(in.opcode)
+
+	err := c.dispatch(in)
 	if err != nil {
-		return err
+		c.fail(err, 0)
 	}
+	if c.fault != nil {
+		f := c.fault
+		c.fault = nil
+		return f
+	}
+
 	c.pc += 2
 	return nil
 }
 
+// Step runs one instruction, beginning a new frame when the current one is done.
+func (c *chip8) Step() error {
+	if c.frame == 0 || c.frameCycles >= c.ipf {
+		c.beginFrame()
+	}
+
+	err := c.Cycle()
+	if err != nil {
+		return err
+	}
+	c.frameCycles++
+
+	return nil
+}
+
+// RunFrame runs until the end of the current frame, then calls the frame
+// hooks. Execution hooks and watchpoints see every instruction, so frames
+// with them are always interpreted and never skip idle loops.
+func (c *chip8) RunFrame() error {
+	if err := c.runFrame(); err != nil {
+		return err
+	}
+
+	for _, hook := range c.frameHooks {
+		hook(c.frame, c.display)
+	}
+	return nil
+}
+
+func (c *chip8) runFrame() error {
+	observed := len(c.execHooks) > 0 || len(c.watchpoints) > 0
+	if c.engine == EngineBlocks && !observed {
+		return c.runBlocks()
+	}
+
+	for {
+		err := c.Step()
+		if err != nil {
+			return err
+		}
+
+		if c.idleSkip && !observed && loopPoint(c.history[(c.cycles-1)%HISTORY_SIZE].Opcode) {
+			c.skipIdle()
+		}
+
+		if c.frameCycles >= c.ipf {
+			return nil
+		}
+	}
+}
+
+func (c *chip8) beginFrame() {
+	c.frame++
+	c.frameCycles = 0
+
+	// 1. Input
+	c.lastFrameKeys = c.frameKeys
+	c.frameKeys = c.keypad
+
+	// 2. Timers
+	c.timerTick()
+}
+
+// OnExecute registers a hook that is called before every instruction.
+func (c *chip8) OnExecute(hook ExecHook) {
+	c.execHooks = append(c.execHooks, hook)
+}
+
+// OnFrame registers a hook that is called at the end of every frame. Frame
+// hooks don't slow down the frames they see.
+func (c *chip8) OnFrame(hook FrameHook) {
+	c.frameHooks = append(c.frameHooks, hook)
+}
+
 // Run runs the emulator.
 func (c *chip8) Run() {
 	// Create a new signal channel, in case the old one was closed
 	c.done = make(chan bool)
 
-	// Run Cycle on system clock tick, or exit if stopped
+	// Run a frame on each clock tick, or exit if stopped
 	for {
-		// Allocate a new frame before checking waiting for clock, in the likely
-		// scenario that we beat the clock signal
-		frame := image.NewRGBA(image.Rect(0, 0, DISPLAY_WIDTH, DISPLAY_HEIGHT))
-
 		select {
 		case <-c.clock.C:
-			// 1. Input
-			c.lastFrameKeys = c.frameKeys
-			c.frameKeys = c.keypad
-
-			// 2. Timers
-			c.timerTick()
-
-			// 3. Exec
-			for range c.ipf {
-				err := c.Cycle()
-				if err != nil {
-					panic(err)
-				}
+			// 1-4. Input, timers, exec and repaint
+			err := c.tick()
+			if err != nil {
+				c.runErr.Store(&err)
+				return
 			}
-
-			// 4. Repaint
-			c.draw(frame)
-			c.frameBuf.PushBack(frame)
 		case <-c.done:
 			return
 		}
@@ -133,34 +326,6 @@ func (c *chip8) Stop() {
 	close(c.done)
 }
 
-// Stop returns the most recent frame from the display buffer
-func (c *chip8) Frame() *image.RGBA {
-	if c.frameBuf.Len() == 0 {
-		return nil
-	}
-
-	// Drop unnecessary frames from the frame buffer
-	for c.frameBuf.Len() > FRAME_BUFFER_LENGTH+1 {
-		c.frameBuf.PopFront()
-	}
-
-	return c.frameBuf.PopBack()
-}
-
-func (c *chip8) draw(image *image.RGBA) {
-	for idx, pixel := range c.display {
-		image.Set(idx%DISPLAY_WIDTH, idx/DISPLAY_WIDTH, colorFor(pixel))
-	}
-}
-
-func colorFor(pixel bool) color.Color {
-	if pixel {
-		return color.White
-	}
-
-	return color.Black
-}
-
 func baseChip8(clockSpeed time.Duration) *chip8 {
 	c := &chip8{}
 
@@ -171,6 +336,10 @@ func baseChip8(clockSpeed time.Duration) *chip8 {
 	// expect different system clocks
 	c.clock = time.NewTicker(clockSpeed)
 	c.ipf = DEFAULT_IPF
+	c.quirks = QuirksGR8
+	c.decodeCache = true
+	c.idleSkip = true
+	c.display = NewBitplane(DISPLAY_WIDTH, DISPLAY_HEIGHT)
 
 	c.rng = rand.New(rand.NewPCG(uint64(time.Now().Unix()), 0))
 
@@ -180,8 +349,9 @@ func baseChip8(clockSpeed time.Duration) *chip8 {
 	// Blank out all keypad bits
 	c.keypad = keypad(0)
 
-	// Set our frame buffer minimum length
-	c.frameBuf.SetBaseCap(FRAME_BUFFER_LENGTH)
+	// Buffers for rendered frames, starting with the blank display
+	c.frames.init()
+	c.dirty = true
 
 	return c
 }
@@ -47,3 +47,79 @@ func setup(t *testing.T) (*chip8, *assert.Assertions) {
 	c := emu.(*chip8)
 	return c, assert
 }
+
+func TestRunFrame(t *testing.T) {
+	c, assert := setup(t)
+	c.delayTimer = 10
+	c.Press(3)
+
+	assert.NoError(c.RunFrame())
+
+	// Input is latched and timers tick once per frame
+	assert.Equal(uint8(9), c.delayTimer)
+	assert.True(c.frameKeys.Pressed(3))
+	assert.Equal(uint64(1), c.FrameCount())
+}
+
+func TestOnExecute(t *testing.T) {
+	c, assert := opcodeTest(t, []byte{0x60, 0x01, 0x12, 0x00})
+
+	var pcs, opcodes []uint16
+	c.OnExecute(func(pc, opcode uint16) {
+		pcs = append(pcs, pc)
+		opcodes = append(opcodes, opcode)
+	})
+
+	for range 3 {
+		assert.NoError(c.Cycle())
+	}
+
+	assert.Equal([]uint16{0x200, 0x202, 0x200}, pcs)
+	assert.Equal([]uint16{0x6001, 0x1200, 0x6001}, opcodes)
+}
+
+func TestOnFrame(t *testing.T) {
+	// Draws the 0 glyph, then idles
+	c, assert := opcodeTest(t, []byte{0xF0, 0x29, 0xD0, 0x05, 0x12, 0x04})
+
+	for _, engine := range []Engine{EngineInterpreter, EngineBlocks} {
+		c.SetEngine(engine)
+
+		var frames []uint64
+		var lit []bool
+		c.OnFrame(func(frame uint64, display Bitplane) {
+			frames = append(frames, frame)
+			lit = append(lit, display.Pixel(0, 0))
+		})
+
+		for range 2 {
+			assert.NoError(c.RunFrame())
+		}
+		assert.Len(frames, 2, engine)
+		assert.Equal(frames[0]+1, frames[1], engine)
+		assert.Equal([]bool{true, true}, lit, engine)
+		c.frameHooks = nil
+	}
+}
+
+func TestStepStartsFrames(t *testing.T) {
+	c, assert := opcodeTest(t, []byte{0x12, 0x00})
+	c.delayTimer = 10
+
+	// The first step begins a frame
+	assert.NoError(c.Step())
+	assert.Equal(uint8(9), c.delayTimer)
+
+	// The next frame begins once this one has run all of its instructions
+	for range c.ipf - 1 {
+		assert.NoError(c.Step())
+	}
+	assert.Equal(uint8(9), c.delayTimer)
+	assert.NoError(c.Step())
+	assert.Equal(uint8(8), c.delayTimer)
+
+	// RunFrame finishes the current frame
+	assert.NoError(c.RunFrame())
+	assert.Equal(uint8(8), c.delayTimer)
+	assert.Equal(c.ipf, c.frameCycles)
+}
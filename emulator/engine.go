@@ -0,0 +1,173 @@
+package emulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Engine is the way the emulator runs instructions.
+type Engine uint8
+
+const (
+	// Decodes and dispatches one instruction at a time. The other engines
+	// are tested against it.
+	EngineInterpreter Engine = iota
+
+	// Compiles basic blocks into chains of closures, bound to their decoded
+	// instructions, and caches them by address
+	EngineBlocks
+)
+
+// Engines names the engines.
+var Engines = map[string]Engine{
+	"interpreter": EngineInterpreter,
+	"blocks":      EngineBlocks,
+}
+
+func (e Engine) String() string {
+	for name, engine := range Engines {
+		if engine == e {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Engine(%d)", uint8(e))
+}
+
+// ParseEngine returns the engine with the given name.
+func ParseEngine(name string) (Engine, error) {
+	e, ok := Engines[strings.ToLower(name)]
+	if !ok {
+		return e, fmt.Errorf("unknown engine %q, expected interpreter or blocks", name)
+	}
+
+	return e, nil
+}
+
+// The most instructions compiled into one block
+const MAX_BLOCK_SIZE = 32
+
+// step is a compiled instruction.
+type step struct {
+	opcode uint16
+	run    func(c *chip8)
+}
+
+// block is a run of instructions from start up to but not including end that
+// only branches at its last instruction.
+type block struct {
+	start, end int
+	steps      []step
+
+	// Cleared when memory under the block is written
+	valid bool
+}
+
+// branches reports whether an instruction can leave pc anywhere but the next
+// instruction, which ends a block.
+func branches(o op) bool {
+	switch o {
+	case opJMP, opCALL, opRET, opJPV,
+		opSEVx, opSNEVx, opSEVxVy, opSNEVxVy, opSKPVx, opSKNPVx,
+		opLDVxK, opInvalid:
+		return true
+	}
+
+	return false
+}
+
+// compile translates the block starting at addr.
+func (c *chip8) compile(addr uint16) *block {
+	b := &block{start: int(addr), end: int(addr), valid: true}
+
+	for len(b.steps) < MAX_BLOCK_SIZE && b.end+2 <= MEM_SIZE {
+		in := decode(uint16(c.mem[b.end])<<8 | uint16(c.mem[b.end+1]))
+		b.steps = append(b.steps, step{opcode: in.opcode, run: bind(in)})
+		b.end += 2
+
+		if branches(in.op) {
+			break
+		}
+	}
+
+	return b
+}
+
+// bind returns a closure that runs in.
+func bind(in instruction) func(c *chip8) {
+	handler := handlers[in.op]
+	if handler == nil {
+		return func(c *chip8) {
+			c.fail(ErrInvalidOpcode, 0)
+		}
+	}
+
+	return func(c *chip8) {
+		handler(c, in)
+	}
+}
+
+// runBlocks runs until the end of the current frame, a block at a time.
+func (c *chip8) runBlocks() error {
+	for {
+		if c.frame == 0 || c.frameCycles >= c.ipf {
+			c.beginFrame()
+		}
+
+		if int(c.pc)+2 > MEM_SIZE {
+			return &Fault{PC: c.pc, Err: ErrPCOutOfBounds}
+		}
+
+		b := c.blocks[c.pc]
+		if b == nil {
+			b = c.compile(c.pc)
+			c.blocks[c.pc] = b
+		}
+
+		for _, s := range b.steps {
+			c.record(s.opcode)
+			s.run(c)
+			if c.fault != nil {
+				f := c.fault
+				c.fault = nil
+				return f
+			}
+			c.pc += 2
+			c.frameCycles++
+			if c.idleSkip && loopPoint(s.opcode) {
+				c.skipIdle()
+			}
+
+			// The frame can end partway through a block, and a block that
+			// rewrites itself has to be compiled again
+			if c.frameCycles >= c.ipf {
+				return nil
+			}
+			if !b.valid {
+				break
+			}
+		}
+	}
+}
+
+// invalidateBlocks drops the compiled blocks that overlap memory from addr up
+// to but not including end.
+func (c *chip8) invalidateBlocks(addr, end int) {
+	for start := max(addr-2*MAX_BLOCK_SIZE+1, 0); start < min(end, MEM_SIZE); start++ {
+		if b := c.blocks[start]; b != nil && b.end > addr {
+			b.valid = false
+			c.blocks[start] = nil
+		}
+	}
+}
+
+// Engine returns the engine the emulator runs instructions with.
+func (c *chip8) Engine() Engine {
+	return c.engine
+}
+
+// SetEngine changes the engine the emulator runs instructions with.
+func (c *chip8) SetEngine(e Engine) {
+	c.engine = e
+	c.invalidateBlocks(0, MEM_SIZE)
+}
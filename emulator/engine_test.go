@@ -0,0 +1,138 @@
+package emulator
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/roms"
+	"github.com/stretchr/testify/assert"
+)
+
+// lockstep runs a ROM on the plain interpreter, without skipping idle
+// loops, and on an emulator set up by configure side by side. It presses the
+// same random keys on both, and checks that they agree after every frame.
+func lockstep(t *testing.T, rom []byte, quirks Quirks, ipf, frames int, configure func(c *chip8)) {
+	assert := assert.New(t)
+
+	var emus [2]*chip8
+	for idx := range emus {
+		emu, err := NewEmulatorFromBuf(bytes.NewReader(rom), DEFAULT_CLOCK_SPEED)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := emu.(*chip8)
+		c.SetQuirks(quirks)
+		c.ipf = ipf
+		c.rng = rand.New(rand.NewPCG(1, 2))
+		emus[idx] = c
+	}
+	ref, c := emus[0], emus[1]
+	ref.SetIdleSkip(false)
+	configure(c)
+
+	keys := rand.New(rand.NewPCG(3, 4))
+	for frame := range frames {
+		if frame%10 == 0 {
+			key := uint8(keys.IntN(16))
+			if keys.IntN(2) == 0 {
+				ref.Press(key)
+				c.Press(key)
+			} else {
+				ref.Release(key)
+				c.Release(key)
+			}
+		}
+
+		refErr, err := ref.RunFrame(), c.RunFrame()
+		assert.Equal(refErr, err, "frame %d", frame)
+		assert.Equal(ref.State(), c.State(), "frame %d", frame)
+		assert.Equal(ref.frameCycles, c.frameCycles, "frame %d", frame)
+		assert.Equal(ref.cycles, c.cycles, "frame %d", frame)
+		assert.Equal(ref.History(), c.History(), "frame %d", frame)
+		assert.Equal(ref.Display(), c.Display(), "frame %d", frame)
+		assert.True(ref.mem == c.mem, "memory differs in frame %d", frame)
+
+		if refErr != nil || t.Failed() {
+			return
+		}
+	}
+}
+
+// lockstepROMs are the ROMs run in lockstep tests.
+var lockstepROMs = []struct {
+	name string
+	rom  []byte
+}{
+	{"chip8-logo", roms.Chip8Logo},
+	{"ibm-logo", roms.IBMLogo},
+	{"corax+", roms.Corax},
+	{"flags", roms.Flags},
+	{"quirks", roms.Quirks},
+	{"keypad", roms.Keypad},
+	{"beep", roms.Beep},
+	{"scrolling", roms.Scrolling},
+	{"self-modifying", selfModifying},
+}
+
+func TestEngineLockstep(t *testing.T) {
+	for _, test := range lockstepROMs {
+		for _, preset := range QuirksPresetNames() {
+			// Frames of a few instructions end partway through blocks
+			for _, ipf := range []int{7, DEFAULT_IPF} {
+				for _, idle := range []bool{false, true} {
+					t.Run(fmt.Sprintf("%s/%s/ipf=%d/idle=%t", test.name, preset, ipf, idle), func(t *testing.T) {
+						lockstep(t, test.rom, QuirksPresets[preset], ipf, 200, func(c *chip8) {
+							c.SetEngine(EngineBlocks)
+							c.SetIdleSkip(idle)
+						})
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEngineSelfModifying(t *testing.T) {
+	c, assert := opcodeTest(t, selfModifying)
+	c.SetEngine(EngineBlocks)
+	c.ipf = 8
+
+	// The block from 0x202 rewrites its own next instruction
+	assert.NoError(c.RunFrame())
+	assert.Equal(uint8(0x09), c.v[2])
+
+	// Writes from outside invalidate blocks too
+	c.WriteMemory(0x20A, []byte{0x62, 0x05})
+	c.pc, c.ipf = 0x20A, 1
+	assert.NoError(c.RunFrame())
+	assert.Equal(uint8(0x05), c.v[2])
+}
+
+func TestEngineHooks(t *testing.T) {
+	c, assert := opcodeTest(t, []byte{0x70, 0x01, 0x12, 0x00})
+	c.SetEngine(EngineBlocks)
+
+	// Hooks see every instruction, so the interpreter runs frames with them
+	executed := 0
+	c.OnExecute(func(pc, opcode uint16) {
+		executed++
+	})
+	assert.NoError(c.RunFrame())
+	assert.Equal(DEFAULT_IPF, executed)
+	assert.Nil(c.blocks[ROM_START])
+}
+
+func TestParseEngine(t *testing.T) {
+	assert := assert.New(t)
+
+	e, err := ParseEngine("Blocks")
+	assert.NoError(err)
+	assert.Equal(EngineBlocks, e)
+	assert.Equal("blocks", e.String())
+
+	_, err = ParseEngine("jit")
+	assert.ErrorContains(err, `unknown engine "jit"`)
+}
@@ -0,0 +1,104 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+)
+
+const STACK_SIZE = 16   // Nested subroutine calls before the stack overflows
+const HISTORY_SIZE = 64 // Instructions kept for crash reports
+
+var ErrStackOverflow = errors.New("stack overflow")
+var ErrStackUnderflow = errors.New("return with an empty stack")
+var ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+var ErrPCOutOfBounds = errors.New("program counter out of bounds")
+
+// Fault is an error raised by an instruction. The program counter is left on
+// the faulting instruction, but instructions that fault part way through keep
+// what they did before the fault: LDIVx and LDBVx may have written memory,
+// LDVxI may have loaded registers and DRW may have drawn and set VF.
+type Fault struct {
+	PC     uint16
+	Opcode uint16
+
+	// Address of an out of bounds memory access, if that was the fault
+	Addr uint16
+
+	Err error
+}
+
+func (f *Fault) Error() string {
+	if errors.Is(f.Err, ErrMemoryOutOfBounds) {
+		return fmt.Sprintf("fault at 0x%03X (%04X): %v (0x%X)", f.PC, f.Opcode, f.Err, f.Addr)
+	}
+
+	return fmt.Sprintf("fault at 0x%03X (%04X): %v", f.PC, f.Opcode, f.Err)
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+// fail records a fault raised by the running instruction. Only the first one
+// is kept.
+func (c *chip8) fail(err error, addr uint16) {
+	if c.fault == nil {
+		c.fault = &Fault{PC: c.pc, Opcode: c.opcodeAt(c.pc), Addr: addr, Err: err}
+	}
+}
+
+// HistoryEntry is an executed instruction and the registers before it ran.
+type HistoryEntry struct {
+	// Frame the instruction ran in, counting from 1
+	Frame uint64
+
+	// Instructions run before this one, across all frames
+	Cycle uint64
+
+	PC     uint16
+	Opcode uint16
+
+	V  [16]byte
+	I  uint16
+	SP int
+	DT byte
+	ST byte
+}
+
+// record adds the instruction about to run to the history, overwriting the
+// oldest entry once it is full.
+func (c *chip8) record(opcode uint16) {
+	c.history[c.cycles%HISTORY_SIZE] = HistoryEntry{
+		Frame:  c.frame,
+		Cycle:  c.cycles,
+		PC:     c.pc,
+		Opcode: opcode,
+		V:      c.v,
+		I:      c.i,
+		SP:     len(c.stack),
+		DT:     c.delayTimer,
+		ST:     c.soundTimer,
+	}
+	c.cycles++
+}
+
+// History returns up to HISTORY_SIZE of the most recently executed
+// instructions, oldest first.
+func (c *chip8) History() []HistoryEntry {
+	if c.cycles <= HISTORY_SIZE {
+		return append([]HistoryEntry(nil), c.history[:c.cycles]...)
+	}
+
+	next := c.cycles % HISTORY_SIZE
+	return append(append([]HistoryEntry(nil), c.history[next:]...), c.history[:next]...)
+}
+
+// Err returns the error that stopped Run, if any. Instructions stop it with
+// a *Fault, which errors.As finds.
+func (c *chip8) Err() error {
+	if err := c.runErr.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		instructions []byte
+		err          error
+		pc           uint16
+	}{
+		{"RET with an empty stack", []byte{0x00, 0xEE}, ErrStackUnderflow, 0x200},
+		{"recursive CALL", []byte{0x22, 0x00}, ErrStackOverflow, 0x200},
+		{"unknown ALU op", []byte{0x60, 0x01, 0x80, 0x18}, ErrInvalidOpcode, 0x202},
+		{"unknown F op", []byte{0xF0, 0xFF}, ErrInvalidOpcode, 0x200},
+		// LD I, 0xFFF; LD [I], V1
+		{"store past memory", []byte{0xAF, 0xFF, 0xF1, 0x55}, ErrMemoryOutOfBounds, 0x202},
+		{"jump past memory", []byte{0x1F, 0xFF}, ErrPCOutOfBounds, 0xFFF},
+	}
+
+	for _, test := range tests {
+		c, assert := opcodeTest(t, test.instructions)
+
+		var err error
+		for range STACK_SIZE + 2 {
+			if err = c.Cycle(); err != nil {
+				break
+			}
+		}
+
+		assert.ErrorIs(err, test.err, test.name)
+		assert.Equal(test.pc, c.pc, test.name)
+
+		var fault *Fault
+		if assert.ErrorAs(err, &fault, test.name) {
+			assert.Equal(test.pc, fault.PC, test.name)
+		}
+	}
+}
+
+func TestInvalidOpcodes(t *testing.T) {
+	// Skipped before faults existed, and faults now
+	for _, opcode := range []uint16{0x8128, 0x812D, 0x812F, 0xE100, 0xE19F, 0xF100, 0xF1FF} {
+		c, assert := opcodeTest(t, []byte{byte(opcode >> 8), byte(opcode)})
+		before := c.State()
+
+		err := c.Cycle()
+		assert.ErrorIs(err, ErrInvalidOpcode, "%04X", opcode)
+		assert.Equal(before, c.State(), "%04X", opcode)
+	}
+}
+
+func TestPartialFault(t *testing.T) {
+	// LD V0, 0xAA; LD I, 0xFFF; LD [I], V1 stores V0 before V1 runs past
+	// memory
+	c, assert := opcodeTest(t, []byte{0x60, 0xAA, 0xAF, 0xFF, 0xF1, 0x55})
+	assert.NoError(c.Cycle())
+	assert.NoError(c.Cycle())
+
+	assert.ErrorIs(c.Cycle(), ErrMemoryOutOfBounds)
+	assert.Equal(uint16(0x204), c.pc)
+	assert.Equal(uint8(0xAA), c.mem[0xFFF])
+}
+
+func TestFaultString(t *testing.T) {
+	assert := assert.New(t)
+
+	f := &Fault{PC: 0x202, Opcode: 0xF155, Addr: 0x1000, Err: ErrMemoryOutOfBounds}
+	assert.Equal("fault at 0x202 (F155): memory access out of bounds (0x1000)", f.Error())
+
+	f = &Fault{PC: 0x200, Opcode: 0x00EE, Err: ErrStackUnderflow}
+	assert.Equal("fault at 0x200 (00EE): return with an empty stack", f.Error())
+}
+
+func TestHistory(t *testing.T) {
+	// 0x200 LD V0, 1; 0x202 ADD V0, 1; 0x204 JMP 0x202
+	c, assert := opcodeTest(t, []byte{0x60, 0x01, 0x70, 0x01, 0x12, 0x02})
+	assert.Empty(c.History())
+
+	assert.NoError(c.Cycle())
+	assert.NoError(c.Cycle())
+
+	history := c.History()
+	assert.Len(history, 2)
+	assert.Equal(uint16(0x200), history[0].PC)
+	assert.Equal(uint16(0x6001), history[0].Opcode)
+	assert.Equal(uint64(1), history[1].Cycle)
+	assert.Equal(uint8(1), history[1].V[0], "entries hold the registers before the instruction ran")
+
+	for range HISTORY_SIZE * 2 {
+		assert.NoError(c.Cycle())
+	}
+
+	history = c.History()
+	assert.Len(history, HISTORY_SIZE)
+	for idx := 1; idx < len(history); idx++ {
+		assert.Equal(history[idx-1].Cycle+1, history[idx].Cycle)
+	}
+	assert.Equal(c.cycles-1, history[len(history)-1].Cycle)
+}
+
+func TestRunStopsOnFault(t *testing.T) {
+	c, assert := opcodeTest(t, []byte{0x00, 0xEE})
+	assert.NoError(c.Err())
+
+	done := make(chan struct{})
+	go func() {
+		c.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		c.Stop()
+		t.Fatal("Run didn't return after faulting")
+	}
+
+	var fault *Fault
+	assert.ErrorAs(c.Err(), &fault)
+	assert.ErrorIs(c.Err(), ErrStackUnderflow)
+	assert.Equal(uint16(0x00EE), c.History()[0].Opcode)
+}
@@ -0,0 +1,160 @@
+package emulator
+
+import (
+	"image"
+	"sync"
+)
+
+// frames hands rendered frames from Run to the reader of Frame without
+// allocating. Run renders into back and swaps it with ready, and Frame swaps
+// ready with front, so each side always owns a buffer of its own.
+type frames struct {
+	mu    sync.Mutex
+	back  *image.RGBA
+	ready *image.RGBA
+	front *image.RGBA
+
+	// Numbers of the frames drawn into each buffer
+	backFrame, readyFrame, frontFrame uint64
+
+	// Whether ready holds a frame that Frame hasn't returned yet
+	fresh bool
+
+	// Published at the end of every frame, whether the display changed or not
+	stats Stats
+}
+
+// Stats is a snapshot of the emulator at the end of a frame, for reading on
+// another goroutine while Run is running.
+type Stats struct {
+	// Frames begun, and instructions run including those skipped in idle
+	// loops
+	Frame   uint64
+	Cycles  uint64
+	Skipped uint64
+
+	State State
+
+	// Keys latched for the frame, with bit n set while key n was held
+	Keys uint16
+}
+
+func newFrameImage() *image.RGBA {
+	return image.NewRGBA(image.Rect(0, 0, DISPLAY_WIDTH, DISPLAY_HEIGHT))
+}
+
+func (f *frames) init() {
+	f.back, f.ready, f.front = newFrameImage(), newFrameImage(), newFrameImage()
+}
+
+// publish makes the back buffer, drawn in the given frame, the most recent
+// frame. A frame that was never read is dropped.
+func (f *frames) publish(frame uint64) {
+	f.mu.Lock()
+	f.backFrame = frame
+	f.back, f.ready = f.ready, f.back
+	f.backFrame, f.readyFrame = f.readyFrame, f.backFrame
+	f.fresh = true
+	f.mu.Unlock()
+}
+
+// Frame returns the most recent frame, or nil if the display hasn't changed
+// since the last call. The frame is reused, and is only valid until the next
+// call to Frame.
+func (c *chip8) Frame() *image.RGBA {
+	f := &c.frames
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.fresh {
+		return nil
+	}
+
+	f.front, f.ready = f.ready, f.front
+	f.frontFrame, f.readyFrame = f.readyFrame, f.frontFrame
+	f.fresh = false
+	return f.front
+}
+
+// FrameNumber returns the number of the frame Frame last returned, so that it
+// can be told apart from frames Run has begun since.
+func (c *chip8) FrameNumber() uint64 {
+	f := &c.frames
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.frontFrame
+}
+
+// Stats returns the snapshot Run published at the end of the last frame it
+// ran. Unlike the other getters, it's safe to call while Run is running.
+func (c *chip8) Stats() Stats {
+	c.frames.mu.Lock()
+	defer c.frames.mu.Unlock()
+
+	s := c.frames.stats
+	s.State.Stack = append([]uint16(nil), s.State.Stack...)
+	return s
+}
+
+// publishStats makes a snapshot of the emulator available to Stats. The
+// snapshot's stack is reused so that publishing doesn't allocate.
+func (c *chip8) publishStats() {
+	c.frames.mu.Lock()
+	defer c.frames.mu.Unlock()
+
+	s := &c.frames.stats
+	s.Frame = c.frame
+	s.Cycles = c.cycles
+	s.Skipped = c.skipped
+	s.Keys = uint16(c.frameKeys)
+	s.State = State{
+		PC:         c.pc,
+		I:          c.i,
+		V:          c.v,
+		Stack:      append(s.State.Stack[:0], c.stack...),
+		DelayTimer: c.delayTimer,
+		SoundTimer: c.soundTimer,
+	}
+}
+
+// render draws the display into img, which must be DISPLAY_WIDTH by
+// DISPLAY_HEIGHT, if it changed since the last render.
+func (c *chip8) render(img *image.RGBA) bool {
+	if !c.dirty {
+		return false
+	}
+	c.dirty = false
+
+	idx := 0
+	for _, word := range c.display.Bits {
+		for range 64 {
+			v := uint8(0x00)
+			if word&(1<<63) != 0 {
+				v = 0xFF
+			}
+			word <<= 1
+
+			p := img.Pix[idx*4 : idx*4+4 : idx*4+4]
+			p[0], p[1], p[2], p[3] = v, v, v, 0xFF
+			idx++
+		}
+	}
+
+	return true
+}
+
+// tick runs one frame, publishes its stats and publishes the frame itself
+// if the display changed.
+func (c *chip8) tick() error {
+	if err := c.RunFrame(); err != nil {
+		return err
+	}
+
+	c.publishStats()
+
+	if c.render(c.frames.back) {
+		c.frames.publish(c.frame)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package emulator
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Draws the top left corner of the font's 0, once per frame
+var drawLoop = []byte{
+	0xA0, 0x50, // LD I, 0x050
+	0xD0, 0x01, // DRW V0, V0, 1
+	0x12, 0x02, // JMP 0x202
+}
+
+// frameTest loads drawLoop with display wait, so that it draws once a frame.
+func frameTest(t *testing.T) (*chip8, *assert.Assertions) {
+	c, assert := opcodeTest(t, drawLoop)
+	c.SetQuirks(QuirksCHIP8)
+
+	return c, assert
+}
+
+func TestFrame(t *testing.T) {
+	c, assert := frameTest(t)
+
+	// Nothing is rendered before the first frame
+	assert.Nil(c.Frame())
+
+	assert.NoError(c.tick())
+	frame := c.Frame()
+	assert.NotNil(frame)
+	assert.Equal(uint64(1), c.FrameNumber())
+	assert.Equal(color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, frame.RGBAAt(0, 0))
+	assert.Equal(color.RGBA{0x00, 0x00, 0x00, 0xFF}, frame.RGBAAt(4, 0))
+
+	// Frames that weren't read are dropped for the newest one
+	assert.NoError(c.tick())
+	assert.NoError(c.tick())
+	latest := c.Frame()
+	assert.Equal(uint64(3), c.FrameNumber())
+	assert.Equal(color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, latest.RGBAAt(0, 0))
+	assert.Nil(c.Frame())
+
+	// Unchanged displays aren't rendered again
+	c.SetQuirks(Quirks{})
+	c.pc = ROM_START + 4
+	c.WriteMemory(ROM_START+4, []byte{0x12, 0x04})
+	assert.NoError(c.tick())
+	assert.NoError(c.tick())
+	assert.Nil(c.Frame())
+}
+
+func TestFrameAllocations(t *testing.T) {
+	c, assert := frameTest(t)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		c.tick()
+		c.Frame()
+	})
+	assert.Zero(allocs)
+}
+
+func BenchmarkFrame(b *testing.B) {
+	c, _ := frameTest(&testing.T{})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		c.tick()
+		c.Frame()
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	c, _ := frameTest(&testing.T{})
+	img := newFrameImage()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		c.dirty = true
+		c.render(img)
+	}
+}
+
+func TestStats(t *testing.T) {
+	c, assert := frameTest(t)
+
+	// Nothing is published before the first frame
+	assert.Equal(Stats{}, c.Stats())
+
+	c.Press(0xA)
+	assert.NoError(c.tick())
+	stats := c.Stats()
+	assert.Equal(uint64(1), stats.Frame)
+	assert.Equal(c.cycles, stats.Cycles)
+	assert.Equal(uint16(1<<0xA), stats.Keys)
+	assert.Equal(c.State(), stats.State)
+}
@@ -0,0 +1,123 @@
+package emulator
+
+import "slices"
+
+// Idle detection ends frames early for ROMs that are only waiting for the
+// next one: polling the delay timer in a loop such as
+//
+//	wait: LD V0, DT
+//	      SE V0, 0
+//	      JP wait
+//
+// or stalling on Fx0A until a key is pressed. Keys and timers only change
+// between frames, so once a loop comes back around to the same instruction
+// with the same machine state in the same frame, it would keep doing so until
+// the frame ends. Whole trips around the loop are skipped instead of run, and
+// their history is filled in, so that every frame ends in exactly the state
+// it would have anyway.
+
+// idleState is the machine state compared at loop points, a jump or a stalled
+// Fx0A. Memory, the display and the RNG are too large to compare, so changes
+// to them are counted instead.
+type idleState struct {
+	frame   uint64
+	pc      uint16
+	i       uint16
+	v       [16]byte
+	stack   [STACK_SIZE]uint16
+	sp      int
+	delay   byte
+	sound   byte
+	effects uint64
+}
+
+// loopPoint reports whether opcode can bring a loop back around: a jump, or
+// Fx0A, which runs again until a key is pressed.
+func loopPoint(opcode uint16) bool {
+	return opcode&0xF000 == 0x1000 || opcode&0xF0FF == 0xF00A
+}
+
+// saveIdle stores the machine state in s.
+func (c *chip8) saveIdle(s *idleState) {
+	s.frame, s.pc, s.i, s.v = c.frame, c.pc, c.i, c.v
+	s.sp = copy(s.stack[:], c.stack)
+	s.delay, s.sound, s.effects = c.delayTimer, c.soundTimer, c.effects
+}
+
+// matchesIdle reports whether the machine is in state s.
+func (c *chip8) matchesIdle(s *idleState) bool {
+	return c.effects == s.effects && c.v == s.v && c.i == s.i &&
+		c.pc == s.pc && c.frame == s.frame &&
+		c.delayTimer == s.delay && c.soundTimer == s.sound &&
+		slices.Equal(c.stack, s.stack[:s.sp])
+}
+
+// Loop points passed before idle detection stops watching for the one it
+// anchored at to come around again, and anchors at the next one instead
+const IDLE_ANCHOR_POINTS = 8
+
+// anchorIdle starts watching the current loop point.
+func (c *chip8) anchorIdle() {
+	c.saveIdle(&c.idle)
+	c.idleCycles, c.idlePoints = c.cycles, 0
+}
+
+// skipIdle is called after each loop point. When the loop point it's
+// watching comes around again with the machine in the same state, it skips as
+// many more trips around the loop as fit in the frame. Loops can pass through
+// several loop points, so the others are only counted.
+func (c *chip8) skipIdle() {
+	if c.frame != c.idle.frame {
+		c.anchorIdle()
+		return
+	}
+	if c.pc != c.idle.pc {
+		c.idlePoints++
+		if c.idlePoints >= IDLE_ANCHOR_POINTS {
+			c.anchorIdle()
+		}
+		return
+	}
+
+	if !c.matchesIdle(&c.idle) {
+		c.anchorIdle()
+		return
+	}
+	lastCycles := c.idleCycles
+	c.idleCycles, c.idlePoints = c.cycles, 0
+
+	// The history of one trip is repeated for each skipped one
+	period := c.cycles - lastCycles
+	remaining := uint64(c.ipf - c.frameCycles)
+	if period == 0 || period > HISTORY_SIZE || remaining < period {
+		return
+	}
+	skip := remaining / period * period
+
+	var trip [HISTORY_SIZE]HistoryEntry
+	for n := range period {
+		trip[n] = c.history[(lastCycles+n)%HISTORY_SIZE]
+	}
+	for cycle := c.cycles + skip - min(skip, HISTORY_SIZE); cycle < c.cycles+skip; cycle++ {
+		entry := trip[(cycle-lastCycles)%period]
+		entry.Cycle = cycle
+		c.history[cycle%HISTORY_SIZE] = entry
+	}
+
+	c.cycles += skip
+	c.frameCycles += int(skip)
+	c.idleCycles = c.cycles
+	c.skipped += skip
+}
+
+// Skipped returns the number of instructions skipped in idle loops so far.
+func (c *chip8) Skipped() uint64 {
+	return c.skipped
+}
+
+// SetIdleSkip turns idle detection on or off. It's on by default, and only
+// affects speed.
+func (c *chip8) SetIdleSkip(enabled bool) {
+	c.idleSkip = enabled
+	c.idle = idleState{}
+}
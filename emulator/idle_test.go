@@ -0,0 +1,74 @@
+package emulator
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Waits on the delay timer twice, then halts
+var delayWait = []byte{
+	0x60, 0x05, // 0x200 LD V0, 0x05
+	0xF0, 0x15, // 0x202 LD DT, V0
+	0xF1, 0x07, // 0x204 LD V1, DT
+	0x31, 0x00, // 0x206 SE V1, 0x00
+	0x12, 0x04, // 0x208 JP 0x204
+	0x70, 0x01, // 0x20A ADD V0, 0x01
+	0x40, 0x07, // 0x20C SNE V0, 0x07
+	0x12, 0x10, // 0x20E JP 0x210
+	0x12, 0x02, // 0x210 JP 0x202, and halts once V0 is 7 by jumping to itself
+}
+
+// Waits for a key, then draws its digit
+var keyWait = []byte{
+	0xF0, 0x0A, // 0x200 LD V0, K
+	0xF0, 0x29, // 0x202 LD F, V0
+	0xD0, 0x05, // 0x204 DRW V0, V0, 5
+	0x12, 0x00, // 0x206 JP 0x200
+}
+
+func TestIdleSkip(t *testing.T) {
+	tests := []struct {
+		name string
+		rom  []byte
+		idle bool
+	}{
+		{"delay", delayWait, true},
+		{"key", keyWait, true},
+		// The RNG changes every time around, so the loop isn't idle
+		{"random", []byte{0xC0, 0xFF, 0x12, 0x00}, false},
+		// Nor is a loop that writes memory, even if it writes the same byte
+		{"store", []byte{0xA3, 0x00, 0xF0, 0x55, 0x12, 0x00}, false},
+	}
+
+	for _, test := range tests {
+		c, assert := opcodeTest(t, test.rom)
+		for range 10 {
+			assert.NoError(c.RunFrame())
+		}
+
+		assert.Equal(uint64(10*DEFAULT_IPF), c.cycles, test.name)
+		assert.Equal(test.idle, c.Skipped() > 0, test.name)
+	}
+}
+
+func TestIdleLockstep(t *testing.T) {
+	tests := append(lockstepROMs[:len(lockstepROMs):len(lockstepROMs)], []struct {
+		name string
+		rom  []byte
+	}{
+		{"delay-wait", delayWait},
+		{"key-wait", keyWait},
+	}...)
+
+	for _, test := range tests {
+		for _, preset := range QuirksPresetNames() {
+			for _, ipf := range []int{7, DEFAULT_IPF} {
+				t.Run(fmt.Sprintf("%s/%s/ipf=%d", test.name, preset, ipf), func(t *testing.T) {
+					lockstep(t, test.rom, QuirksPresets[preset], ipf, 200, func(c *chip8) {
+						c.SetIdleSkip(true)
+					})
+				})
+			}
+		}
+	}
+}
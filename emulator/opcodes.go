@@ -1,314 +1,266 @@
 package emulator
 
 import (
-	"fmt"
+	"errors"
+	"slices"
 )
 
-var ErrInvalidOpcode = fmt.Errorf("invalid opcode")
+// Raised by opcodes the emulator doesn't implement, such as 8xy8, ExFF or
+// Fx00. Before faults existed they were silently skipped, so ROMs that run
+// into data now stop on a fault where they used to carry on.
+var ErrInvalidOpcode = errors.New("invalid opcode")
 
-func (c *chip8) dispatch(opcode uint16) error {
-	switch opcode & 0xF000 {
-	case 0x0000:
-		switch opcode {
-		case 0x00E0:
-			c.CLS()
-		case 0x00EE:
-			c.RET()
-		}
-	case 0x1000:
-		c.JMP()
-	case 0x2000:
-		c.CALL()
-	case 0x3000:
-		c.SEVx()
-	case 0x4000:
-		c.SNEVx()
-	case 0x5000:
-		c.SEVxVy()
-	case 0x6000:
-		c.LD()
-	case 0x7000:
-		c.ADD()
-	case 0x8000:
-		switch opcode & 0xF00F {
-		case 0x8000:
-			c.LDVxVy()
-		case 0x8001:
-			c.ORVxVy()
-		case 0x8002:
-			c.ANDVxVy()
-		case 0x8003:
-			c.XORVxVy()
-		case 0x8004:
-			c.ADDVxVy()
-		case 0x8005:
-			c.SUBVxVy()
-		case 0x8006:
-			c.SHRVx()
-		case 0x8007:
-			c.SUBNVxVy()
-		case 0x800E:
-			c.SHLVx()
-		}
-	case 0x9000:
-		c.SNEVxVy()
-	case 0xA000:
-		c.LDI()
-	case 0xB000:
-		c.JPV()
-	case 0xC000:
-		c.RNDVx()
-	case 0xD000:
-		c.DRW()
-	case 0xE000:
-		switch opcode & 0xF0FF {
-		case 0xE09E:
-			c.SKPVx()
-		case 0xE0A1:
-			c.SKNPVx()
-		}
-	case 0xF000:
-		switch opcode & 0xF0FF {
-		case 0xF007:
-			c.LDVxDT()
-		case 0xF00A:
-			c.LDVxK()
-		case 0xF015:
-			c.LDDTVx()
-		case 0xF018:
-			c.LDSTVx()
-		case 0xF01E:
-			c.ADDIVx()
-		case 0xF029:
-			c.LDFVx()
-		case 0xF033:
-			c.LDBVx()
-		case 0xF055:
-			c.LDIVx()
-		case 0xF065:
-			c.LDVxI()
-		}
-	default:
-		return fmt.Errorf("parsing opcodes: %w (%X)", ErrInvalidOpcode, opcode)
+// dispatch runs a decoded instruction.
+func (c *chip8) dispatch(in instruction) error {
+	handler := handlers[in.op]
+	if handler == nil {
+		return ErrInvalidOpcode
 	}
 
+	handler(c, in)
 	return nil
 }
 
+// SYS calls a machine code routine at nnn, which is ignored.
+func (c *chip8) SYS(instruction) {}
+
 // CLS clears the display.
-func (c *chip8) CLS() {
-	for i := range DISPLAY_SIZE {
-		c.display[i] = false
-	}
+func (c *chip8) CLS(instruction) {
+	c.display.Clear()
+	c.dirty = true
+	c.effects++
 }
 
 // RET returns from subroutine.
-func (c *chip8) RET() {
+func (c *chip8) RET(instruction) {
+	if len(c.stack) == 0 {
+		c.fail(ErrStackUnderflow, 0)
+		return
+	}
 	c.pc, c.stack = c.stack[len(c.stack)-1]-2, c.stack[:len(c.stack)-1]
 }
 
 // JMP jumps to nnn.
-func (c *chip8) JMP() {
-	c.pc = c.nnn() - 2
+func (c *chip8) JMP(in instruction) {
+	c.pc = in.nnn - 2
 }
 
 // CALL calls subroutine at nnn.
-func (c *chip8) CALL() {
+func (c *chip8) CALL(in instruction) {
+	if len(c.stack) >= STACK_SIZE {
+		c.fail(ErrStackOverflow, 0)
+		return
+	}
 	c.stack = append(c.stack, c.pc+2)
-	c.pc = c.nnn() - 2
+	c.pc = in.nnn - 2
 }
 
 // SEVx skips next instruction if Vx == nn.
-func (c *chip8) SEVx() {
-	if c.vx() == c.nn() {
+func (c *chip8) SEVx(in instruction) {
+	if c.reg(in.x) == in.nn {
 		c.pc += 2
 	}
 }
 
 // SNEVx skips next instruction if Vx != nn.
-func (c *chip8) SNEVx() {
-	if c.vx() != c.nn() {
+func (c *chip8) SNEVx(in instruction) {
+	if c.reg(in.x) != in.nn {
 		c.pc += 2
 	}
 }
 
 // SEVxVy skips next instruction if Vx == Vy.
-func (c *chip8) SEVxVy() {
-	if c.vx() == c.vy() {
+func (c *chip8) SEVxVy(in instruction) {
+	if c.reg(in.x) == c.reg(in.y) {
 		c.pc += 2
 	}
 }
 
 // LD loads nn into Vx.
-func (c *chip8) LD() {
-	c.v[c.x()] = c.nn()
+func (c *chip8) LD(in instruction) {
+	c.setReg(in.x, in.nn)
 }
 
 // ADD adds nn to Vx.
-func (c *chip8) ADD() {
-	c.v[c.x()] += c.nn()
+func (c *chip8) ADD(in instruction) {
+	c.setReg(in.x, c.reg(in.x)+in.nn)
 }
 
 // LDVxVy sets Vx = Vy.
-func (c *chip8) LDVxVy() {
-	c.v[c.x()] = c.vy()
+func (c *chip8) LDVxVy(in instruction) {
+	c.setReg(in.x, c.reg(in.y))
 }
 
 // ORVxVy sets Vx |= Vy.
-func (c *chip8) ORVxVy() {
-	c.v[c.x()] |= c.vy()
-	c.v[0xF] = 0
+func (c *chip8) ORVxVy(in instruction) {
+	c.setReg(in.x, c.reg(in.x)|c.reg(in.y))
+	if c.quirks.VFReset {
+		c.setReg(0xF, 0)
+	}
 }
 
 // ANDVxVy sets Vx &= Vy.
-func (c *chip8) ANDVxVy() {
-	c.v[c.x()] &= c.vy()
-	c.v[0xF] = 0
+func (c *chip8) ANDVxVy(in instruction) {
+	c.setReg(in.x, c.reg(in.x)&c.reg(in.y))
+	if c.quirks.VFReset {
+		c.setReg(0xF, 0)
+	}
 }
 
 // XORVxVy sets Vx ^= Vy.
-func (c *chip8) XORVxVy() {
-	c.v[c.x()] ^= c.vy()
-	c.v[0xF] = 0
+func (c *chip8) XORVxVy(in instruction) {
+	c.setReg(in.x, c.reg(in.x)^c.reg(in.y))
+	if c.quirks.VFReset {
+		c.setReg(0xF, 0)
+	}
 }
 
 // ADDVxVy sets Vx += Vy, sets VF on carry.
-func (c *chip8) ADDVxVy() {
-	result := int(c.vx()) + int(c.vy())
+func (c *chip8) ADDVxVy(in instruction) {
+	result := int(c.reg(in.x)) + int(c.reg(in.y))
 	carry := result > 255
 
-	c.v[c.x()] = byte(result)
+	c.setReg(in.x, byte(result))
 	if carry {
-		c.v[0xF] = 1
+		c.setReg(0xF, 1)
 	} else {
-		c.v[0xF] = 0
+		c.setReg(0xF, 0)
 	}
 }
 
 // SUBVxVy sets Vx -= Vy, sets VF if NOT borrow.
-func (c *chip8) SUBVxVy() {
-	borrow := c.vx() < c.vy()
+func (c *chip8) SUBVxVy(in instruction) {
+	vx, vy := c.reg(in.x), c.reg(in.y)
+	borrow := vx < vy
 
-	c.v[c.x()] = max(0, c.vx()-c.vy())
+	c.setReg(in.x, vx-vy)
 	if !borrow {
-		c.v[0xF] = 1
+		c.setReg(0xF, 1)
 	} else {
-		c.v[0xF] = 0
+		c.setReg(0xF, 0)
 	}
 }
 
-// SHRVx sets Vx=Vx>>1, sets VF if least-significant bit is 1.
-func (c *chip8) SHRVx() {
-	c.v[c.x()] = c.vy()
-	flag := c.vx() & 0x01
-	c.v[c.x()] = c.vx() >> 1
-	c.v[0xF] = flag
+// SHRVx sets Vx=Vy>>1, sets VF if least-significant bit is 1. With the
+// shifting quirk Vx is shifted in place.
+func (c *chip8) SHRVx(in instruction) {
+	v := c.shiftSource(in)
+	c.setReg(in.x, v>>1)
+	c.setReg(0xF, v&0x01)
 }
 
 // SUBNVxVy sets Vx = Vy - Vx, sets VF if NOT carry.
-func (c *chip8) SUBNVxVy() {
-	carry := c.vy() < c.vx()
+func (c *chip8) SUBNVxVy(in instruction) {
+	vx, vy := c.reg(in.x), c.reg(in.y)
+	carry := vy < vx
 
-	c.v[c.x()] = c.vy() - c.vx()
+	c.setReg(in.x, vy-vx)
 	if !carry {
-		c.v[0xF] = 1
+		c.setReg(0xF, 1)
 	} else {
-		c.v[0xF] = 0
+		c.setReg(0xF, 0)
 	}
 }
 
-// SHLVx sets Vx=Vx<<1, sets VF if most-significant bit is 1.
-func (c *chip8) SHLVx() {
-	c.v[c.x()] = c.vy()
-	flag := c.vx() & 0x80
-	c.v[c.x()] = c.vx() << 1
-	if flag != 0 {
-		c.v[0xF] = 1
+// SHLVx sets Vx=Vy<<1, sets VF if most-significant bit is 1. With the
+// shifting quirk Vx is shifted in place.
+func (c *chip8) SHLVx(in instruction) {
+	v := c.shiftSource(in)
+	c.setReg(in.x, v<<1)
+	if v&0x80 != 0 {
+		c.setReg(0xF, 1)
 	} else {
-		c.v[0xF] = 0
+		c.setReg(0xF, 0)
+	}
+}
+
+// shiftSource returns the register shifted by SHRVx and SHLVx.
+func (c *chip8) shiftSource(in instruction) uint8 {
+	if c.quirks.Shifting {
+		return c.reg(in.x)
 	}
+	return c.reg(in.y)
 }
 
 // SNEVxVy skips next instruction if Vx != Vy.
-func (c *chip8) SNEVxVy() {
-	if c.vx() != c.vy() {
+func (c *chip8) SNEVxVy(in instruction) {
+	if c.reg(in.x) != c.reg(in.y) {
 		c.pc += 2
 	}
 }
 
 // LDI sets i to nnn.
-func (c *chip8) LDI() {
-	c.i = c.nnn()
+func (c *chip8) LDI(in instruction) {
+	c.setIndex(in.nnn)
 }
 
-// JPV jumps to location nnn + V0.
-func (c *chip8) JPV() {
-	c.pc = c.nnn() + uint16(c.v[0]) - 2
+// JPV jumps to location nnn + V0, or nnn + Vx with the jumping quirk.
+func (c *chip8) JPV(in instruction) {
+	reg := uint8(0)
+	if c.quirks.Jumping {
+		reg = in.x
+	}
+	c.pc = in.nnn + uint16(c.reg(reg)) - 2
 }
 
 // RNDVx sets Vx = (random) & nn.
-func (c *chip8) RNDVx() {
-	c.v[c.x()] = uint8(c.rng.Uint32()) & c.nn()
+func (c *chip8) RNDVx(in instruction) {
+	c.setReg(in.x, uint8(c.rng.Uint32())&in.nn)
+	c.effects++
 }
 
 // DRW draws n-byte sprite starting at memory location i at (Vx, Vy)
-// and sets VF on collision.
-func (c *chip8) DRW() {
-	// Coordinates of the sprite on screen
-	x, y := c.vx()%64, c.vy()%32
-
-	// Clear collision flag
-	c.v[0xF] = 0
-
-	// Iterate over the bytes of the sprite
-	for offset := range c.n() {
-		row := c.mem[c.i+uint16(offset)]
-
-		// Draw each bit in the row to the screen
-		for col := range 8 {
-			// Convert coordinate to linear index
-			index := (int(y+offset) * DISPLAY_WIDTH) + int(x) + col
-			spritePixel := row&(0x80>>col) != 0
-
-			// If we try to draw past the right edge or out of bounds,
-			// stop processing this row
-			for index >= DISPLAY_SIZE {
-				index -= DISPLAY_SIZE
-			}
-
-			displayPixel := c.display[index]
-			if displayPixel && spritePixel {
-				c.v[0xF] = 1
-			}
-			c.display[index] = displayPixel != spritePixel
-		}
+// and sets VF on collision. Sprites start on screen, but past the edges they
+// are clipped or wrap around depending on the clipping quirk.
+func (c *chip8) DRW(in instruction) {
+	var sprite [15]byte
+	i := c.index()
+	for offset := range int(in.n) {
+		sprite[offset] = c.load(i + uint16(offset))
+	}
+
+	rows := sprite[:in.n]
+	collided := c.display.Draw(int(c.reg(in.x)), int(c.reg(in.y)), rows, !c.quirks.Clipping)
+	if collided {
+		c.setReg(0xF, 1)
+	} else {
+		c.setReg(0xF, 0)
+	}
+	if slices.ContainsFunc(rows, func(row byte) bool { return row != 0 }) {
+		c.dirty = true
+		c.effects++
+	}
+
+	// The rest of the frame is spent waiting for the display
+	if c.quirks.DisplayWait {
+		c.frameCycles = c.ipf
 	}
 }
 
 // SKPVx skips next instruction if key with the value of Vx is pressed.
-func (c *chip8) SKPVx() {
-	if c.frameKeys.Pressed(c.vx()) {
+func (c *chip8) SKPVx(in instruction) {
+	if c.frameKeys.Pressed(c.reg(in.x)) {
 		c.pc += 2
 	}
 }
 
 // SKNPVx skips next instruction if key with the value of Vx is NOT pressed.
-func (c *chip8) SKNPVx() {
-	if !c.frameKeys.Pressed(c.vx()) {
+func (c *chip8) SKNPVx(in instruction) {
+	if !c.frameKeys.Pressed(c.reg(in.x)) {
 		c.pc += 2
 	}
 }
 
 // LDVxDT sets Vx = delay timer.
-func (c *chip8) LDVxDT() {
-	c.v[c.x()] = c.delayTimer
+func (c *chip8) LDVxDT(in instruction) {
+	c.setReg(in.x, c.delay())
 }
 
 // LDVxK waits for a keypress and stores the value of the key in Vx.
-func (c *chip8) LDVxK() {
+func (c *chip8) LDVxK(in instruction) {
 	for key := range uint8(16) {
 		if c.frameKeys.Pressed(key) && !c.lastFrameKeys.Pressed(key) {
-			c.v[c.x()] = key
+			c.setReg(in.x, key)
 			return
 		}
 	}
@@ -318,42 +270,51 @@ func (c *chip8) LDVxK() {
 }
 
 // LDDTVx sets delay timer = Vx.
-func (c *chip8) LDDTVx() {
-	c.delayTimer = c.vx()
+func (c *chip8) LDDTVx(in instruction) {
+	c.setDelay(c.reg(in.x))
 }
 
 // LDSTVx sets sound timer = Vx.
-func (c *chip8) LDSTVx() {
-	c.soundTimer = c.vx()
+func (c *chip8) LDSTVx(in instruction) {
+	c.setSound(c.reg(in.x))
 }
 
 // ADDIVx sets i += Vx.
-func (c *chip8) ADDIVx() {
-	c.i += uint16(c.vx())
+func (c *chip8) ADDIVx(in instruction) {
+	c.setIndex(c.index() + uint16(c.reg(in.x)))
 }
 
 // LDFVx sets i = address for sprite to digit Vx.
-func (c *chip8) LDFVx() {
-	c.i = uint16(FONT_START + c.vx()*5)
+func (c *chip8) LDFVx(in instruction) {
+	c.setIndex(uint16(FONT_START + c.reg(in.x)*5))
 }
 
 // LDBVx stores the decimal digits of Vx in memory locations [i:i+2] (BCD).
-func (c *chip8) LDBVx() {
-	copy(c.mem[c.i:], []byte{
-		c.vx() / 100,
-		(c.vx() % 100) / 10,
-		c.vx() % 10,
-	})
+func (c *chip8) LDBVx(in instruction) {
+	vx, i := c.reg(in.x), c.index()
+	c.store(i, vx/100)
+	c.store(i+1, (vx%100)/10)
+	c.store(i+2, vx%10)
 }
 
 // LDIVx stores registers V0-Vx in memory starting at i.
-func (c *chip8) LDIVx() {
-	copy(c.mem[c.i:], c.v[:c.x()+1])
-	c.i += uint16(c.x() + 1)
+func (c *chip8) LDIVx(in instruction) {
+	x, i := in.x, c.index()
+	for n := range x + 1 {
+		c.store(i+uint16(n), c.reg(n))
+	}
+	if c.quirks.Memory {
+		c.setIndex(i + uint16(x+1))
+	}
 }
 
 // LDVxI stores memory starting at i into register V0-Vx.
-func (c *chip8) LDVxI() {
-	copy(c.v[:], c.mem[c.i:c.i+uint16(c.x()+1)])
-	c.i += uint16(c.x() + 1)
+func (c *chip8) LDVxI(in instruction) {
+	x, i := in.x, c.index()
+	for n := range x + 1 {
+		c.setReg(n, c.load(i+uint16(n)))
+	}
+	if c.quirks.Memory {
+		c.setIndex(i + uint16(x+1))
+	}
 }
@@ -3,6 +3,7 @@ package emulator
 import (
 	"bytes"
 	"fmt"
+	"math/bits"
 	"math/rand/v2"
 	"testing"
 
@@ -19,15 +20,15 @@ func opcodeTest(t *testing.T, instructions []byte) (*chip8, *assert.Assertions)
 func TestCLS(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x00, 0xE0})
 	// Turn on all pixels
-	for i := range DISPLAY_SIZE {
-		c.display[i] = true
+	for i := range c.display.Bits {
+		c.display.Bits[i] = ^uint64(0)
 	}
 
-	assert.Equal(true, c.display[0])
+	assert.Equal(true, c.display.Pixel(0, 0))
 	c.Cycle()
 
 	// Ensure all pixels are cleared
-	assert.ElementsMatch(make([]bool, DISPLAY_SIZE), c.display)
+	assert.Equal(NewBitplane(DISPLAY_WIDTH, DISPLAY_HEIGHT), c.display)
 }
 
 // 0x00EE
@@ -47,7 +48,8 @@ func TestRET(t *testing.T) {
 // 0x1nnn
 func TestJMP(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x1B, 0xEE})
-	newAddr := c.nnn()
+	in := decode(c.opcodeAt(c.pc))
+	newAddr := in.nnn
 
 	assert.Equal(uint16(ROM_START), c.pc)
 	assert.NotEqual(newAddr, c.pc)
@@ -58,7 +60,8 @@ func TestJMP(t *testing.T) {
 // 0x2nnn
 func TestCALL(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x2B, 0xEE})
-	newAddr := c.nnn()
+	in := decode(c.opcodeAt(c.pc))
+	newAddr := in.nnn
 
 	assert.Equal(0, len(c.stack))
 	c.Cycle()
@@ -70,7 +73,8 @@ func TestCALL(t *testing.T) {
 // 0x3xnn
 func TestSEVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x30, 0x22})
-	c.v[c.x()] = 0x22
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0x22
 
 	c.Cycle()
 	assert.Equal(ROM_START+4, c.pc)
@@ -79,7 +83,8 @@ func TestSEVx(t *testing.T) {
 // 0x4xnn
 func TestSNEVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x40, 0x22})
-	c.v[c.x()] = 0x33
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0x33
 
 	c.Cycle()
 	assert.Equal(ROM_START+4, c.pc)
@@ -88,8 +93,9 @@ func TestSNEVx(t *testing.T) {
 // 0x5xy0
 func TestSEVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x50, 0x10})
-	c.v[c.x()] = 0x33
-	c.v[c.y()] = 0x33
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0x33
+	c.v[in.y] = 0x33
 
 	c.Cycle()
 	assert.Equal(ROM_START+4, c.pc)
@@ -116,11 +122,12 @@ func TestADD(t *testing.T) {
 // 0x8xy0
 func TestLDVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x10})
-	c.v[c.x()] = 255
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 255
 
-	initialX := c.vy()
+	initialX := c.v[in.y]
 
-	assert.NotEqual(c.vx(), c.vy())
+	assert.NotEqual(c.v[in.x], c.v[in.y])
 	c.Cycle()
 	assert.Equal(c.v[0], c.v[1])
 
@@ -131,10 +138,11 @@ func TestLDVxVy(t *testing.T) {
 // 0x8xy1
 func TestORVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x11})
-	c.v[c.x()] = 0b01010101
-	c.v[c.y()] = 0b10101010
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b01010101
+	c.v[in.y] = 0b10101010
 
-	assert.NotEqual(0xFF, c.vx())
+	assert.NotEqual(0xFF, c.v[in.x])
 	c.Cycle()
 	assert.Equal(uint8(0xFF), c.v[0])
 }
@@ -142,10 +150,11 @@ func TestORVxVy(t *testing.T) {
 // 0x8xy2
 func TestANDVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x12})
-	c.v[c.x()] = 0b01010101
-	c.v[c.y()] = 0b10101010
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b01010101
+	c.v[in.y] = 0b10101010
 
-	assert.NotEqual(0xFF, c.vx())
+	assert.NotEqual(0xFF, c.v[in.x])
 	c.Cycle()
 	assert.Equal(uint8(0x00), c.v[0])
 }
@@ -153,10 +162,11 @@ func TestANDVxVy(t *testing.T) {
 // 0x8xy3
 func TestXORVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x13})
-	c.v[c.x()] = 0b01010101
-	c.v[c.y()] = 0b10101010
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b01010101
+	c.v[in.y] = 0b10101010
 
-	assert.NotEqual(0xFF, c.vx())
+	assert.NotEqual(0xFF, c.v[in.x])
 	c.Cycle()
 	assert.Equal(uint8(0xFF), c.v[0])
 }
@@ -164,35 +174,38 @@ func TestXORVxVy(t *testing.T) {
 // 0x8xy4
 func TestADDVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x14})
-	c.v[c.x()] = 0b01010101
-	c.v[c.y()] = 0b10101010
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b01010101
+	c.v[in.y] = 0b10101010
 
-	assert.NotEqual(0xFF, c.vx())
+	assert.NotEqual(0xFF, c.v[in.x])
 	c.Cycle()
 	assert.Equal(uint8(0xFF), c.v[0])
-	assert.Equal(uint8(0), c.vf())
+	assert.Equal(uint8(0), c.v[0xF])
 }
 
 // 0x8xy5
 func TestSUBVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x15})
-	c.v[c.x()] = 0b10101010
-	c.v[c.y()] = 0b01010101
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b10101010
+	c.v[in.y] = 0b01010101
 
-	assert.NotEqual(0xFF, c.vx())
+	assert.NotEqual(0xFF, c.v[in.x])
 	c.Cycle()
 	assert.Equal(uint8(0x55), c.v[0])
-	assert.Equal(uint8(0x1), c.vf())
+	assert.Equal(uint8(0x1), c.v[0xF])
 }
 
 // 0x8xy6
 func TestSHRVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x16})
+	in := decode(c.opcodeAt(c.pc))
 	var initial uint8 = 0b01010101
-	c.v[c.x()] = initial
-	c.v[c.y()] = initial
+	c.v[in.x] = initial
+	c.v[in.y] = initial
 
-	assert.Equal(uint8(0), c.vf())
+	assert.Equal(uint8(0), c.v[0xF])
 	c.Cycle()
 
 	assert.Equal(initial>>1, c.v[0])
@@ -201,33 +214,36 @@ func TestSHRVx(t *testing.T) {
 // 0x8xy7
 func TestSUBNVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x17})
-	c.v[c.x()] = 0b01010101
-	c.v[c.y()] = 0b10101010
-	expected := c.vy() - c.vx()
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0b01010101
+	c.v[in.y] = 0b10101010
+	expected := c.v[in.y] - c.v[in.x]
 
 	c.Cycle()
-	assert.Equal(uint8(0x1), c.vf())
+	assert.Equal(uint8(0x1), c.v[0xF])
 	assert.Equal(uint8(expected), c.v[0])
 }
 
 // 0x8xyE
 func TestSHLVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x80, 0x1E})
+	in := decode(c.opcodeAt(c.pc))
 	var initial uint8 = 0b01010101
-	c.v[c.x()] = initial
-	c.v[c.y()] = initial
+	c.v[in.x] = initial
+	c.v[in.y] = initial
 
-	assert.Equal(uint8(0), c.vf())
+	assert.Equal(uint8(0), c.v[0xF])
 	c.Cycle()
-	assert.Equal(uint8(0), c.vf())
+	assert.Equal(uint8(0), c.v[0xF])
 	assert.Equal(initial<<1, c.v[0])
 }
 
 // 0x9xy0
 func TestSNEVxVy(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0x90, 0x10})
-	c.v[c.x()] = 0
-	c.v[c.y()] = 1
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 0
+	c.v[in.y] = 1
 
 	c.Cycle()
 	assert.Equal(ROM_START+4, c.pc)
@@ -245,8 +261,9 @@ func TestLDI(t *testing.T) {
 // 0xBnnn
 func TestJPV(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xB1, 0x23})
+	in := decode(c.opcodeAt(c.pc))
 	c.v[0] = 0x0001
-	expected := uint16(c.v[0]) + c.nnn()
+	expected := uint16(c.v[0]) + in.nnn
 
 	assert.Equal(ROM_START, c.pc)
 	c.Cycle()
@@ -256,23 +273,22 @@ func TestJPV(t *testing.T) {
 // 0xCxnn
 func TestRNDVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xC0, 0x23})
+	in := decode(c.opcodeAt(c.pc))
 	rng := rand.New(rand.NewPCG(0, 1))
 	c.rng = rand.New(rand.NewPCG(0, 1))
 
-	expected := uint8(rng.Uint32()) & c.nn()
+	expected := uint8(rng.Uint32()) & in.nn
 	c.Cycle()
 	assert.Equal(expected, c.v[0])
 }
 
 // 0xDxyn
 func TestDRW(t *testing.T) {
-	var trueElements = func(l []bool) int {
+	var trueElements = func(b Bitplane) int {
 		total := 0
 
-		for _, el := range l {
-			if el {
-				total += 1
-			}
+		for _, word := range b.Bits {
+			total += bits.OnesCount64(word)
 		}
 
 		return total
@@ -286,24 +302,26 @@ func TestDRW(t *testing.T) {
 	c, assert := opcodeTest(t, append([]byte{0xD0, 0x02}, sprite...))
 	c.i = ROM_START + 2
 
-	expected := make([]bool, spriteSize*8)
-	for idx := range expected {
-		expected[idx] = true
+	expected := NewBitplane(DISPLAY_WIDTH, DISPLAY_HEIGHT)
+	for y := range spriteSize {
+		for x := range 8 {
+			expected.Set(x, y, true)
+		}
 	}
-	expected = append(expected, make([]bool, DISPLAY_SIZE-(spriteSize*8))...)
 
-	assert.ElementsMatch(make([]bool, DISPLAY_SIZE), c.display)
+	assert.Equal(NewBitplane(DISPLAY_WIDTH, DISPLAY_HEIGHT), c.display)
 	c.Cycle()
-	assert.ElementsMatch(expected, c.display)
+	assert.Equal(expected, c.display)
 	fmt.Println(trueElements(expected))
 }
 
 // 0xEx9E
 func TestSKPVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xE0, 0x9E})
+	in := decode(c.opcodeAt(c.pc))
 
 	c.Press(1)
-	c.v[c.x()] = 1
+	c.v[in.x] = 1
 
 	c.frameKeys = c.keypad
 
@@ -315,9 +333,10 @@ func TestSKPVx(t *testing.T) {
 // 0xExA1
 func TestSKNPVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xE0, 0xA1})
+	in := decode(c.opcodeAt(c.pc))
 
 	c.Press(1)
-	c.v[c.x()] = 1
+	c.v[in.x] = 1
 
 	c.frameKeys = c.keypad
 
@@ -329,9 +348,10 @@ func TestSKNPVx(t *testing.T) {
 // 0xFx07
 func TestLDVxDT(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x07})
+	in := decode(c.opcodeAt(c.pc))
 	c.delayTimer = 42
 
-	assert.NotEqual(c.vx(), c.delayTimer)
+	assert.NotEqual(c.v[in.x], c.delayTimer)
 	c.Cycle()
 	assert.Equal(c.v[0], c.delayTimer)
 }
@@ -339,17 +359,18 @@ func TestLDVxDT(t *testing.T) {
 // 0xFx0A
 func TestLDVxK(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x0A})
+	in := decode(c.opcodeAt(c.pc))
 
 	c.frameKeys = c.keypad
 
 	// At ROM_START, no keys pressed, Vx empty
-	assert.Equal(uint8(0), c.vx())
+	assert.Equal(uint8(0), c.v[in.x])
 	assert.Equal(ROM_START, c.pc)
 	assert.Equal(keypad(0), c.keypad)
 	c.Cycle()
 
 	// Same check again
-	assert.Equal(uint8(0), c.vx())
+	assert.Equal(uint8(0), c.v[in.x])
 	assert.Equal(ROM_START, c.pc)
 
 	// Press a key, try again
@@ -365,27 +386,30 @@ func TestLDVxK(t *testing.T) {
 // 0xFx15
 func TestLDDTVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x15})
+	in := decode(c.opcodeAt(c.pc))
 	c.delayTimer = 42
 
-	assert.NotEqual(c.vx(), c.delayTimer)
+	assert.NotEqual(c.v[in.x], c.delayTimer)
 	c.Cycle()
-	assert.Equal(c.vx(), c.delayTimer)
+	assert.Equal(c.v[in.x], c.delayTimer)
 }
 
 // 0xFx18
 func TestLDSTVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x18})
+	in := decode(c.opcodeAt(c.pc))
 	c.soundTimer = 42
 
-	assert.NotEqual(c.vx(), c.soundTimer)
+	assert.NotEqual(c.v[in.x], c.soundTimer)
 	c.Cycle()
-	assert.Equal(c.vx(), c.soundTimer)
+	assert.Equal(c.v[in.x], c.soundTimer)
 }
 
 // 0xFx1E
 func TestADDIVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x1E})
-	c.v[c.x()] = 255
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 255
 
 	c.Cycle()
 	assert.Equal(uint16(c.v[0]), c.i)
@@ -394,7 +418,8 @@ func TestADDIVx(t *testing.T) {
 // 0xFx29
 func TestLDFVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x29})
-	c.v[c.x()] = 2
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 2
 	assert.Equal(c.i, uint16(0))
 
 	c.Cycle()
@@ -404,7 +429,8 @@ func TestLDFVx(t *testing.T) {
 // 0xFx33
 func TestLDBVx(t *testing.T) {
 	c, assert := opcodeTest(t, []byte{0xF0, 0x33})
-	c.v[c.x()] = 123
+	in := decode(c.opcodeAt(c.pc))
+	c.v[in.x] = 123
 
 	c.Cycle()
 	assert.ElementsMatch(c.mem[0:3], []byte{1, 2, 3})
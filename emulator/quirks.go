@@ -0,0 +1,81 @@
+package emulator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Quirks selects between the behaviors that differ across CHIP-8 platforms.
+type Quirks struct {
+	// 8xy1, 8xy2 and 8xy3 reset VF to 0
+	VFReset bool
+
+	// Fx55 and Fx65 leave I pointing past the last register
+	Memory bool
+
+	// DRW waits for the display to refresh, ending the frame
+	DisplayWait bool
+
+	// Sprites are clipped at the edges of the screen instead of wrapping
+	Clipping bool
+
+	// 8xy6 and 8xyE shift Vx in place, ignoring Vy
+	Shifting bool
+
+	// Bnnn jumps to nnn + Vx, where x is the first digit of nnn, instead of
+	// nnn + V0
+	Jumping bool
+}
+
+// gr8's own behavior, and the default. Like the COSMAC VIP it resets VF and
+// increments I, but it doesn't wait for the display and sprites wrap.
+var QuirksGR8 = Quirks{VFReset: true, Memory: true}
+
+// The original COSMAC VIP interpreter
+var QuirksCHIP8 = Quirks{VFReset: true, Memory: true, DisplayWait: true, Clipping: true}
+
+// SUPER-CHIP 1.1, as found on the HP48
+var QuirksSCHIP = Quirks{Clipping: true, Shifting: true, Jumping: true}
+
+// Octo's XO-CHIP
+var QuirksXOCHIP = Quirks{Memory: true}
+
+// QuirksPresets names the quirks of each platform.
+var QuirksPresets = map[string]Quirks{
+	"gr8":    QuirksGR8,
+	"chip8":  QuirksCHIP8,
+	"schip":  QuirksSCHIP,
+	"xochip": QuirksXOCHIP,
+}
+
+// QuirksPresetNames returns the names of the presets, sorted.
+func QuirksPresetNames() []string {
+	names := make([]string, 0, len(QuirksPresets))
+	for name := range QuirksPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// ParseQuirks returns the preset with the given name.
+func ParseQuirks(name string) (Quirks, error) {
+	q, ok := QuirksPresets[strings.ToLower(name)]
+	if !ok {
+		return q, fmt.Errorf("unknown quirks preset %q, expected one of %s", name, strings.Join(QuirksPresetNames(), ", "))
+	}
+
+	return q, nil
+}
+
+// Quirks returns the quirks the emulator runs with.
+func (c *chip8) Quirks() Quirks {
+	return c.quirks
+}
+
+// SetQuirks changes the quirks the emulator runs with.
+func (c *chip8) SetQuirks(q Quirks) {
+	c.quirks = q
+}
@@ -0,0 +1,136 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuirks(t *testing.T) {
+	tests := []struct {
+		name   string
+		rom    []byte
+		setup  func(c *chip8)
+		check  func(assert *assert.Assertions, c *chip8)
+		quirks Quirks
+	}{
+		{
+			name:   "vf reset",
+			rom:    []byte{0x80, 0x11},
+			setup:  func(c *chip8) { c.setReg(0xF, 1) },
+			check:  func(assert *assert.Assertions, c *chip8) { assert.Equal(uint8(0), c.reg(0xF)) },
+			quirks: Quirks{VFReset: true},
+		},
+		{
+			name:  "no vf reset",
+			rom:   []byte{0x80, 0x11},
+			setup: func(c *chip8) { c.setReg(0xF, 1) },
+			check: func(assert *assert.Assertions, c *chip8) { assert.Equal(uint8(1), c.reg(0xF)) },
+		},
+		{
+			name:   "memory",
+			rom:    []byte{0xF2, 0x55},
+			setup:  func(c *chip8) { c.setIndex(0x300) },
+			check:  func(assert *assert.Assertions, c *chip8) { assert.Equal(uint16(0x303), c.index()) },
+			quirks: Quirks{Memory: true},
+		},
+		{
+			name:  "no memory",
+			rom:   []byte{0xF2, 0x65},
+			setup: func(c *chip8) { c.setIndex(0x300) },
+			check: func(assert *assert.Assertions, c *chip8) { assert.Equal(uint16(0x300), c.index()) },
+		},
+		{
+			name:  "shifting",
+			rom:   []byte{0x80, 0x16},
+			setup: func(c *chip8) { c.setReg(0, 0x04); c.setReg(1, 0x03) },
+			check: func(assert *assert.Assertions, c *chip8) {
+				assert.Equal(uint8(0x02), c.reg(0))
+				assert.Equal(uint8(0), c.reg(0xF))
+			},
+			quirks: Quirks{Shifting: true},
+		},
+		{
+			name:  "no shifting",
+			rom:   []byte{0x80, 0x1E},
+			setup: func(c *chip8) { c.setReg(0, 0x04); c.setReg(1, 0x81) },
+			check: func(assert *assert.Assertions, c *chip8) {
+				assert.Equal(uint8(0x02), c.reg(0))
+				assert.Equal(uint8(1), c.reg(0xF))
+			},
+		},
+		{
+			name:   "jumping",
+			rom:    []byte{0xB3, 0x00},
+			setup:  func(c *chip8) { c.setReg(0, 0x10); c.setReg(3, 0x20) },
+			check:  func(assert *assert.Assertions, c *chip8) { assert.Equal(uint16(0x320), c.pc) },
+			quirks: Quirks{Jumping: true},
+		},
+		{
+			name:  "no jumping",
+			rom:   []byte{0xB3, 0x00},
+			setup: func(c *chip8) { c.setReg(0, 0x10); c.setReg(3, 0x20) },
+			check: func(assert *assert.Assertions, c *chip8) { assert.Equal(uint16(0x310), c.pc) },
+		},
+		{
+			name:  "clipping",
+			rom:   []byte{0xD0, 0x12, 0xFF, 0xFF},
+			setup: func(c *chip8) { c.setIndex(0x202); c.setReg(0, 60); c.setReg(1, 31) },
+			check: func(assert *assert.Assertions, c *chip8) {
+				assert.True(c.Pixel(63, 31))
+				assert.False(c.Pixel(0, 31))
+				assert.False(c.Pixel(60, 0))
+			},
+			quirks: Quirks{Clipping: true},
+		},
+		{
+			name:  "wrapping",
+			rom:   []byte{0xD0, 0x12, 0xFF, 0xFF},
+			setup: func(c *chip8) { c.setIndex(0x202); c.setReg(0, 60); c.setReg(1, 31) },
+			check: func(assert *assert.Assertions, c *chip8) {
+				assert.True(c.Pixel(63, 31))
+				assert.True(c.Pixel(0, 31))
+				assert.True(c.Pixel(3, 0))
+				assert.False(c.Pixel(4, 0))
+			},
+		},
+		{
+			name:   "display wait",
+			rom:    []byte{0xD0, 0x11, 0x12, 0x02},
+			setup:  func(c *chip8) { c.setIndex(0x300) },
+			check:  func(assert *assert.Assertions, c *chip8) { assert.Equal(c.ipf+1, c.frameCycles) },
+			quirks: Quirks{DisplayWait: true},
+		},
+		{
+			name:  "no display wait",
+			rom:   []byte{0xD0, 0x11, 0x12, 0x02},
+			setup: func(c *chip8) { c.setIndex(0x300) },
+			check: func(assert *assert.Assertions, c *chip8) { assert.Equal(1, c.frameCycles) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, assert := opcodeTest(t, tt.rom)
+			c.SetQuirks(tt.quirks)
+			tt.setup(c)
+
+			assert.NoError(c.Step())
+			tt.check(assert, c)
+		})
+	}
+}
+
+func TestParseQuirks(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := ParseQuirks("SCHIP")
+	assert.NoError(err)
+	assert.Equal(QuirksSCHIP, q)
+
+	_, err = ParseQuirks("chip48")
+	assert.EqualError(err, `unknown quirks preset "chip48", expected one of chip8, gr8, schip, xochip`)
+
+	c, _ := setup(t)
+	assert.Equal(QuirksGR8, c.Quirks())
+}
@@ -1,5 +1,7 @@
 package emulator
 
+// timerTick counts the timers down once a frame. It writes them directly
+// rather than through setDelay and setSound, so watchpoints don't see it.
 func (c *chip8) timerTick() {
 	if c.delayTimer > 0 {
 		c.delayTimer -= 1
@@ -0,0 +1,202 @@
+package emulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Access is a set of ways a watchpoint can be triggered.
+type Access byte
+
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+	AccessExecute
+)
+
+func (a Access) String() string {
+	var parts []string
+	if a&AccessRead != 0 {
+		parts = append(parts, "r")
+	}
+	if a&AccessWrite != 0 {
+		parts = append(parts, "w")
+	}
+	if a&AccessExecute != 0 {
+		parts = append(parts, "x")
+	}
+
+	return strings.Join(parts, "")
+}
+
+// Register names a register that can be watched. V0-VF are Register(0) to
+// Register(15). Watchpoints only report accesses made by instructions, so DT
+// and ST counting down once a frame doesn't set them off.
+type Register byte
+
+const (
+	RegI Register = 16 + iota
+	RegDT
+	RegST
+)
+
+func (r Register) String() string {
+	switch r {
+	case RegI:
+		return "I"
+	case RegDT:
+		return "DT"
+	case RegST:
+		return "ST"
+	}
+
+	return fmt.Sprintf("V%X", byte(r))
+}
+
+// Watchpoint watches a range of memory or a register.
+type Watchpoint struct {
+	// Whether a memory range is watched rather than a register
+	Memory bool
+
+	// Watched memory, from Start up to but not including End
+	Start, End uint16
+
+	// Watched register
+	Register Register
+
+	// Accesses that trigger the watchpoint. Registers can't be executed.
+	Access Access
+}
+
+// WatchMemory returns a watchpoint on memory from start up to but not including end.
+func WatchMemory(start, end uint16, access Access) Watchpoint {
+	return Watchpoint{Memory: true, Start: start, End: end, Access: access}
+}
+
+// WatchRegister returns a watchpoint on a register.
+func WatchRegister(reg Register, access Access) Watchpoint {
+	return Watchpoint{Register: reg, Access: access}
+}
+
+func (w Watchpoint) String() string {
+	switch {
+	case !w.Memory:
+		return fmt.Sprintf("%s %s", w.Register, w.Access)
+	case w.End-w.Start <= 1:
+		return fmt.Sprintf("0x%03X %s", w.Start, w.Access)
+	}
+
+	return fmt.Sprintf("0x%03X-0x%03X %s", w.Start, w.End, w.Access)
+}
+
+// WatchHit describes an access that triggered a watchpoint.
+type WatchHit struct {
+	// ID returned by Watch, and the watchpoint itself
+	ID         int
+	Watchpoint Watchpoint
+
+	// Instruction that made the access
+	PC     uint16
+	Opcode uint16
+
+	// The access that was made, and the memory address for memory watchpoints
+	Access Access
+	Addr   uint16
+
+	// Value before and after the access, which are the same for reads
+	Old, New uint16
+}
+
+func (h WatchHit) String() string {
+	what := h.Watchpoint.Register.String()
+	if h.Watchpoint.Memory {
+		what = fmt.Sprintf("0x%03X", h.Addr)
+	}
+
+	prefix := fmt.Sprintf("0x%03X (%04X)", h.PC, h.Opcode)
+	switch h.Access {
+	case AccessExecute:
+		return fmt.Sprintf("%s executed %s", prefix, what)
+	case AccessWrite:
+		return fmt.Sprintf("%s wrote %s: 0x%02X -> 0x%02X", prefix, what, h.Old, h.New)
+	}
+
+	return fmt.Sprintf("%s read %s: 0x%02X", prefix, what, h.Old)
+}
+
+// WatchHook receives every watchpoint hit, during the instruction that caused it.
+type WatchHook func(hit WatchHit)
+
+type watch struct {
+	id int
+	Watchpoint
+}
+
+// Watch adds a watchpoint and returns its ID.
+func (c *chip8) Watch(w Watchpoint) int {
+	c.lastWatchID++
+	c.watchpoints = append(c.watchpoints, watch{c.lastWatchID, w})
+
+	return c.lastWatchID
+}
+
+// Unwatch removes the watchpoint with the given ID.
+func (c *chip8) Unwatch(id int) {
+	for idx, w := range c.watchpoints {
+		if w.id == id {
+			c.watchpoints = append(c.watchpoints[:idx], c.watchpoints[idx+1:]...)
+			return
+		}
+	}
+}
+
+// OnWatch registers a hook that is called whenever a watchpoint is hit.
+func (c *chip8) OnWatch(hook WatchHook) {
+	c.watchHooks = append(c.watchHooks, hook)
+}
+
+func (c *chip8) hit(w watch, access Access, addr, old, new uint16) {
+	hit := WatchHit{
+		ID:         w.id,
+		Watchpoint: w.Watchpoint,
+		PC:         c.pc,
+		Opcode:     c.opcodeAt(c.pc),
+		Access:     access,
+		Addr:       addr,
+		Old:        old,
+		New:        new,
+	}
+
+	for _, hook := range c.watchHooks {
+		hook(hit)
+	}
+}
+
+// watchMemory reports an access to addr to the watchpoints covering it.
+func (c *chip8) watchMemory(addr uint16, access Access, old, new byte) {
+	for _, w := range c.watchpoints {
+		if w.Memory && w.Access&access != 0 && addr >= w.Start && addr < w.End {
+			c.hit(w, access, addr, uint16(old), uint16(new))
+		}
+	}
+}
+
+// watchRegister reports an access to reg to the watchpoints on it.
+func (c *chip8) watchRegister(reg Register, access Access, old, new uint16) {
+	for _, w := range c.watchpoints {
+		if !w.Memory && w.Register == reg && w.Access&access != 0 {
+			c.hit(w, access, 0, old, new)
+		}
+	}
+}
+
+// watchExecute reports the instruction at pc to the execute watchpoints
+// covering either of its bytes.
+func (c *chip8) watchExecute() {
+	op := c.opcodeAt(c.pc)
+	for _, w := range c.watchpoints {
+		if w.Memory && w.Access&AccessExecute != 0 && c.pc+1 >= w.Start && c.pc < w.End {
+			c.hit(w, AccessExecute, c.pc, op, op)
+		}
+	}
+}
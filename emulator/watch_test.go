@@ -0,0 +1,94 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchMemory(t *testing.T) {
+	// 0x200 LD I, 0x300; 0x202 LD V0, 0x2A; 0x204 LD [I], V0
+	// 0x206 LD I, 0x300; 0x208 LD V0, [I]
+	c, assert := opcodeTest(t, []byte{0xA3, 0x00, 0x60, 0x2A, 0xF0, 0x55, 0xA3, 0x00, 0xF0, 0x65})
+	c.mem[0x300] = 0
+
+	var hits []WatchHit
+	c.OnWatch(func(hit WatchHit) { hits = append(hits, hit) })
+	id := c.Watch(WatchMemory(0x300, 0x301, AccessRead|AccessWrite))
+
+	for range 5 {
+		assert.Nil(c.Cycle())
+	}
+
+	assert.Len(hits, 2)
+	assert.Equal(WatchHit{
+		ID:         id,
+		Watchpoint: WatchMemory(0x300, 0x301, AccessRead|AccessWrite),
+		PC:         0x204,
+		Opcode:     0xF055,
+		Access:     AccessWrite,
+		Addr:       0x300,
+		Old:        0,
+		New:        0x2A,
+	}, hits[0])
+	assert.Equal("0x204 (F055) wrote 0x300: 0x00 -> 0x2A", hits[0].String())
+	assert.Equal(AccessRead, hits[1].Access)
+	assert.Equal("0x208 (F065) read 0x300: 0x2A", hits[1].String())
+
+	c.Unwatch(id)
+	c.pc = 0x204
+	assert.Nil(c.Cycle())
+	assert.Len(hits, 2)
+}
+
+func TestWatchExecute(t *testing.T) {
+	// 0x200 LD V0, 1; 0x202 LD V1, 2
+	c, assert := opcodeTest(t, []byte{0x60, 0x01, 0x61, 0x02})
+
+	var hits []WatchHit
+	c.OnWatch(func(hit WatchHit) { hits = append(hits, hit) })
+	c.Watch(WatchMemory(0x203, 0x204, AccessExecute))
+
+	assert.Nil(c.Cycle())
+	assert.Empty(hits)
+	assert.Nil(c.Cycle())
+	assert.Len(hits, 1)
+	assert.Equal(uint16(0x202), hits[0].Addr)
+	assert.Equal("0x202 (6102) executed 0x202", hits[0].String())
+}
+
+func TestWatchRegister(t *testing.T) {
+	// 0x200 LD V3, 5; 0x202 ADD V3, V3; 0x204 LD DT, V3; 0x206 ADD I, V3
+	c, assert := opcodeTest(t, []byte{0x63, 0x05, 0x83, 0x34, 0xF3, 0x15, 0xF3, 0x1E})
+
+	var hits []WatchHit
+	c.OnWatch(func(hit WatchHit) { hits = append(hits, hit) })
+	c.Watch(WatchRegister(3, AccessWrite))
+	c.Watch(WatchRegister(RegDT, AccessWrite))
+	c.Watch(WatchRegister(RegI, AccessRead|AccessWrite))
+
+	for range 4 {
+		assert.Nil(c.Cycle())
+	}
+
+	assert.Len(hits, 5)
+	assert.Equal("0x200 (6305) wrote V3: 0x00 -> 0x05", hits[0].String())
+	assert.Equal("0x202 (8334) wrote V3: 0x05 -> 0x0A", hits[1].String())
+	assert.Equal("0x204 (F315) wrote DT: 0x00 -> 0x0A", hits[2].String())
+	assert.Equal("0x206 (F31E) read I: 0x00", hits[3].String())
+	assert.Equal("0x206 (F31E) wrote I: 0x00 -> 0x0A", hits[4].String())
+
+	// Timers counting down aren't instructions, so they aren't reported
+	c.beginFrame()
+	assert.Equal(uint8(0x09), c.delayTimer)
+	assert.Len(hits, 5)
+}
+
+func TestWatchpointString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("0x300 rw", WatchMemory(0x300, 0x301, AccessRead|AccessWrite).String())
+	assert.Equal("0x300-0x310 x", WatchMemory(0x300, 0x310, AccessExecute).String())
+	assert.Equal("VF w", WatchRegister(0xF, AccessWrite).String())
+	assert.Equal("ST r", WatchRegister(RegST, AccessRead).String())
+}
@@ -3,25 +3,34 @@ module github.com/aricodes-oss/gr8
 go 1.24.3
 
 require (
-	github.com/gammazero/deque v1.0.0
+	github.com/gdamore/tcell/v2 v2.9.0
+	github.com/google/go-dap v0.12.0
+	github.com/google/pprof v0.0.0-20260115054156-294ebfa9ad83
+	github.com/gopxl/glhf/v2 v2.0.0
+	github.com/gopxl/mainthread/v2 v2.1.1
 	github.com/gopxl/pixel/v2 v2.3.0
 	github.com/spf13/cobra v1.9.1
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/image v0.19.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20240506104042-037f3cc74f2a // indirect
 	github.com/go-gl/mathgl v1.1.0 // indirect
-	github.com/gopxl/glhf/v2 v2.0.0 // indirect
-	github.com/gopxl/mainthread/v2 v2.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/image v0.19.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/term v0.34.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -21,11 +21,8 @@ THE SOFTWARE.
 */
 package main
 
-import (
-	"github.com/aricodes-oss/gr8/cmd"
-	"github.com/gopxl/pixel/v2/backends/opengl"
-)
+import "github.com/aricodes-oss/gr8/cmd"
 
 func main() {
-	opengl.Run(cmd.Execute)
+	cmd.Execute()
 }
@@ -0,0 +1,138 @@
+// Package movie plays back scripted keypad input, so headless runs can reach
+// parts of a ROM that need a player.
+//
+// A movie is a text file with one line per frame that changes the keypad.
+// Each line holds the frame number, counting from 1, followed by the keys
+// pressed (+) or released (-) before that frame begins. Keys are hexadecimal,
+// and blank lines and lines starting with # are ignored:
+//
+//	# pick the second test from the menu
+//	60 +2
+//	64 -2
+//	120 +5 +6
+//	124 -5 -6
+package movie
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Event presses or releases a key before a frame begins.
+type Event struct {
+	Frame   uint64
+	Key     uint8
+	Pressed bool
+}
+
+func (e Event) String() string {
+	if e.Pressed {
+		return fmt.Sprintf("%d +%X", e.Frame, e.Key)
+	}
+	return fmt.Sprintf("%d -%X", e.Frame, e.Key)
+}
+
+// Movie is a list of input events, in frame order.
+type Movie struct {
+	Events []Event
+}
+
+// Parse reads a movie.
+func Parse(r io.Reader) (*Movie, error) {
+	m := &Movie{}
+	s := bufio.NewScanner(r)
+
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		frame, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil || frame == 0 {
+			return nil, fmt.Errorf("line %d: invalid frame %q", line, fields[0])
+		}
+		if len(m.Events) > 0 && frame < m.Events[len(m.Events)-1].Frame {
+			return nil, fmt.Errorf("line %d: frame %d is before frame %d", line, frame, m.Events[len(m.Events)-1].Frame)
+		}
+
+		for _, field := range fields[1:] {
+			pressed := strings.HasPrefix(field, "+")
+			if !pressed && !strings.HasPrefix(field, "-") {
+				return nil, fmt.Errorf("line %d: expected +key or -key, got %q", line, field)
+			}
+
+			key, err := strconv.ParseUint(field[1:], 16, 4)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid key %q", line, field[1:])
+			}
+
+			m.Events = append(m.Events, Event{Frame: frame, Key: uint8(key), Pressed: pressed})
+		}
+	}
+
+	return m, s.Err()
+}
+
+// Load reads a movie from a file.
+func Load(path string) (*Movie, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	m, err := Parse(fd)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Frames returns the frame of the last event, or 0 for an empty movie.
+func (m *Movie) Frames() uint64 {
+	if len(m.Events) == 0 {
+		return 0
+	}
+	return m.Events[len(m.Events)-1].Frame
+}
+
+// Apply presses and releases the keys for the given frame.
+func (m *Movie) Apply(emu emulator.Emulator, frame uint64) {
+	start, _ := slices.BinarySearchFunc(m.Events, frame, func(e Event, frame uint64) int {
+		return cmp.Compare(e.Frame, frame)
+	})
+
+	for _, e := range m.Events[start:] {
+		if e.Frame != frame {
+			break
+		}
+
+		if e.Pressed {
+			emu.Press(e.Key)
+		} else {
+			emu.Release(e.Key)
+		}
+	}
+}
+
+// Play runs emu for the given number of frames, applying the movie's input
+// before each one.
+func (m *Movie) Play(emu emulator.Emulator, frames int) error {
+	for range frames {
+		m.Apply(emu, emu.FrameCount()+1)
+		if err := emu.RunFrame(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
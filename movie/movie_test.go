@@ -0,0 +1,50 @@
+package movie
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Parse(strings.NewReader("# menu\n\n60 +2\n64 -2 +a\n"))
+	assert.NoError(err)
+	assert.Equal([]Event{
+		{Frame: 60, Key: 2, Pressed: true},
+		{Frame: 64, Key: 2},
+		{Frame: 64, Key: 0xA, Pressed: true},
+	}, m.Events)
+	assert.Equal(uint64(64), m.Frames())
+	assert.Equal("64 +A", m.Events[2].String())
+
+	for _, bad := range []string{"0 +1", "x +1", "5 1", "5 +g", "5 +10", "6 +1\n5 -1"} {
+		_, err := Parse(strings.NewReader(bad))
+		assert.Error(err, bad)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	assert := assert.New(t)
+
+	// Wait for a key, store it in V1 and halt
+	rom := []byte{0xF1, 0x0A, 0x12, 0x02}
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	assert.NoError(err)
+
+	m, err := Parse(strings.NewReader("3 +7\n5 -7\n"))
+	assert.NoError(err)
+
+	assert.NoError(m.Play(emu, 4))
+	assert.Equal(uint64(4), emu.FrameCount())
+	assert.True(emu.Pressed(7))
+	assert.Equal(uint8(7), emu.State().V[1])
+	assert.Equal(uint16(0x202), emu.State().PC)
+
+	assert.NoError(m.Play(emu, 1))
+	assert.False(emu.Pressed(7))
+}
@@ -0,0 +1,205 @@
+package octo
+
+import (
+	"math"
+	"strconv"
+)
+
+var binaryOps = map[string]func(a, b float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return math.Mod(a, b) },
+	"&":   func(a, b float64) float64 { return float64(int64(a) & int64(b)) },
+	"|":   func(a, b float64) float64 { return float64(int64(a) | int64(b)) },
+	"^":   func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int64(a) << uint64(b)) },
+	">>":  func(a, b float64) float64 { return float64(int64(a) >> uint64(b)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(a, b float64) float64 { return boolValue(a < b) },
+	">":   func(a, b float64) float64 { return boolValue(a > b) },
+	"<=":  func(a, b float64) float64 { return boolValue(a <= b) },
+	">=":  func(a, b float64) float64 { return boolValue(a >= b) },
+	"==":  func(a, b float64) float64 { return boolValue(a == b) },
+	"!=":  func(a, b float64) float64 { return boolValue(a != b) },
+}
+
+var unaryOps = map[string]func(a float64) float64{
+	"-":     func(a float64) float64 { return -a },
+	"~":     func(a float64) float64 { return float64(^int64(a)) },
+	"!":     func(a float64) float64 { return boolValue(a == 0) },
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"sign":  sign,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func sign(a float64) float64 {
+	switch {
+	case a > 0:
+		return 1
+	case a < 0:
+		return -1
+	}
+
+	return 0
+}
+
+// parseNumber parses decimal, 0x hexadecimal and 0b binary literals.
+func parseNumber(s string) (float64, bool) {
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return float64(n), true
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f, true
+	}
+
+	return 0, false
+}
+
+// calc evaluates the body of a { ... } block. Like Octo, expressions have no
+// operator precedence and are evaluated right to left, so 2 * 3 + 1 is 8.
+// Unary operators apply to the single term that follows them.
+type calc struct {
+	c      *compiler
+	tokens []token
+	idx    int
+	open   token
+}
+
+func (e *calc) eval() (float64, error) {
+	if len(e.tokens) == 0 {
+		return 0, errorf(e.open.pos, "empty expression")
+	}
+
+	value, err := e.expr()
+	if err != nil {
+		return 0, err
+	}
+
+	if e.idx < len(e.tokens) {
+		return 0, errorf(e.tokens[e.idx].pos, "unexpected '%s' in expression", e.tokens[e.idx].text)
+	}
+
+	return value, nil
+}
+
+func (e *calc) expr() (float64, error) {
+	lhs, err := e.term()
+	if err != nil {
+		return 0, err
+	}
+
+	if e.idx == len(e.tokens) || e.tokens[e.idx].text == ")" {
+		return lhs, nil
+	}
+
+	op := e.tokens[e.idx]
+	e.idx++
+
+	fn, ok := binaryOps[op.text]
+	if !ok {
+		return 0, errorf(op.pos, "unknown operator '%s'", op.text)
+	}
+
+	rhs, err := e.expr()
+	if err != nil {
+		return 0, err
+	}
+
+	if (op.text == "/" || op.text == "%") && rhs == 0 {
+		return 0, errorf(op.pos, "division by zero")
+	}
+
+	return fn(lhs, rhs), nil
+}
+
+func (e *calc) term() (float64, error) {
+	if e.idx == len(e.tokens) {
+		last := e.open
+		if len(e.tokens) > 0 {
+			last = e.tokens[len(e.tokens)-1]
+		}
+		return 0, errorf(last.pos, "expected a value after '%s'", last.text)
+	}
+
+	t := e.tokens[e.idx]
+	e.idx++
+
+	if t.text == "(" {
+		value, err := e.expr()
+		if err != nil {
+			return 0, err
+		}
+
+		if e.idx == len(e.tokens) {
+			return 0, errorf(t.pos, "unclosed '('")
+		}
+		e.idx++
+
+		return value, nil
+	}
+
+	if t.text == "@" {
+		addr, err := e.term()
+		if err != nil {
+			return 0, err
+		}
+
+		if addr < 0 || addr >= MEM_SIZE {
+			return 0, errorf(t.pos, "address %v is out of range", addr)
+		}
+
+		return float64(e.c.mem[int(addr)]), nil
+	}
+
+	if fn, ok := unaryOps[t.text]; ok {
+		value, err := e.term()
+		if err != nil {
+			return 0, err
+		}
+
+		return fn(value), nil
+	}
+
+	if n, ok := parseNumber(t.text); ok {
+		return n, nil
+	}
+
+	switch t.text {
+	case "HERE":
+		return float64(e.c.here), nil
+	case "PI":
+		return math.Pi, nil
+	case "E":
+		return math.E, nil
+	}
+
+	if value, ok := e.c.consts[t.text]; ok {
+		return value, nil
+	}
+
+	if addr, ok := e.c.labels[t.text]; ok {
+		return float64(addr), nil
+	}
+
+	return 0, errorf(t.pos, "undefined name '%s' in expression", t.text)
+}
@@ -0,0 +1,65 @@
+package octo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func calcValue(t *testing.T, expr string) float64 {
+	prog, err := Compile(strings.NewReader(": main :calc x { " + expr + " } :byte x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return float64(prog.ROM[0])
+}
+
+func TestCalcRightToLeft(t *testing.T) {
+	assert := assert.New(t)
+
+	// No precedence: 2 * (3 + 1)
+	assert.Equal(8.0, calcValue(t, "2 * 3 + 1"))
+	assert.Equal(7.0, calcValue(t, "( 2 * 3 ) + 1"))
+	assert.Equal(2.0, calcValue(t, "10 - 4 * 2"))
+}
+
+func TestCalcOperators(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(240.0, calcValue(t, "0xF0 | 0x0F & 0"))
+	assert.Equal(16.0, calcValue(t, "1 << 4"))
+	assert.Equal(3.0, calcValue(t, "floor 3.75"))
+	assert.Equal(255.0, calcValue(t, "- 1 & 0xFF"))
+	assert.Equal(1.0, calcValue(t, "2 > 1"))
+	assert.Equal(9.0, calcValue(t, "3 max 9"))
+}
+
+func TestCalcNames(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := Compile(strings.NewReader(`
+		: main
+		:const SPEED 3
+		:calc FAST { SPEED * 2 }
+		:calc ADDR { HERE }
+		:byte FAST
+		:byte { @ ADDR + 1 }
+	`))
+	assert.NoError(err)
+	assert.Equal([]byte{6, 7}, prog.ROM)
+}
+
+func TestCalcErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Compile(strings.NewReader(": main\n:calc x { 1 / 0 }"))
+	assert.EqualError(err, "2:13: division by zero")
+
+	_, err = Compile(strings.NewReader(": main\n:calc x { 1 + nope }"))
+	assert.EqualError(err, "2:15: undefined name 'nope' in expression")
+
+	_, err = Compile(strings.NewReader(": main\n:calc x { 1 + }"))
+	assert.EqualError(err, "2:13: expected a value after '+'")
+}
@@ -0,0 +1,1040 @@
+package octo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Limits runaway recursive macros.
+const MAX_MACRO_EXPANSIONS = 10000
+
+type fixupKind int
+
+const (
+	// The low 12 bits of the instruction at addr
+	fixAddress fixupKind = iota
+
+	// The pair of v0/v1 loads emitted by :unpack
+	fixUnpack
+)
+
+// fixup is a forward reference to a label, patched once compilation finishes.
+type fixup struct {
+	kind fixupKind
+	addr uint16
+	name token
+}
+
+type macro struct {
+	args []string
+	body []token
+}
+
+// loop is an open loop ... again block.
+type loop struct {
+	start  uint16
+	whiles []uint16
+	pos    Pos
+}
+
+// branch is an open if ... begin ... end block.
+type branch struct {
+	jump    uint16
+	pos     Pos
+	hasElse bool
+}
+
+// condition describes the skip instructions for an if/while test.
+type condition struct {
+	// Instructions that compute the flag being tested, if any
+	setup []uint16
+
+	// Skip instructions that skip if the condition is true or false
+	whenTrue, whenFalse uint16
+}
+
+type compiler struct {
+	filename string
+	tokens   []token
+	idx      int
+
+	// Position of the statement being compiled
+	pos Pos
+
+	// Memory image being assembled
+	mem  [MEM_SIZE]byte
+	here uint16
+	top  uint16
+
+	labels  map[string]uint16
+	lines   map[uint16]Pos
+	consts  map[string]float64
+	aliases map[string]byte
+	macros  map[string]*macro
+	fixups  []fixup
+
+	loops    []loop
+	branches []branch
+
+	// Whether 0x200 is reserved for a jump to main, which is dropped
+	// if main is defined before anything else
+	jumpToMain bool
+	pristine   bool
+
+	expansions int
+}
+
+var keywords = map[string]bool{
+	":": true, ":alias": true, ":const": true, ":calc": true, ":macro": true,
+	":org": true, ":byte": true, ":unpack": true, ":next": true, ":call": true,
+	"return": true, ";": true, "clear": true, "bcd": true, "save": true,
+	"load": true, "sprite": true, "jump": true, "jump0": true, "native": true,
+	"loop": true, "again": true, "while": true, "if": true, "then": true,
+	"begin": true, "else": true, "end": true, "i": true, "delay": true,
+	"buzzer": true, "key": true, "-key": true, "random": true, "hex": true,
+	":=": true, "+=": true, "-=": true, "=-": true, "|=": true, "&=": true,
+	"^=": true, ">>=": true, "<<=": true, "==": true, "!=": true, "<": true,
+	">": true, "<=": true, ">=": true, "{": true, "}": true,
+}
+
+func errorf(pos Pos, format string, args ...any) error {
+	return &Error{pos, fmt.Sprintf(format, args...)}
+}
+
+func compile(filename, src string) (*Program, error) {
+	c := &compiler{
+		filename:   filename,
+		tokens:     lex(filename, src),
+		here:       ROM_START + 2,
+		top:        ROM_START + 2,
+		labels:     map[string]uint16{},
+		lines:      map[uint16]Pos{},
+		consts:     map[string]float64{},
+		aliases:    map[string]byte{},
+		macros:     map[string]*macro{},
+		jumpToMain: true,
+		pristine:   true,
+	}
+
+	for c.idx < len(c.tokens) {
+		if err := c.statement(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.loops) > 0 {
+		return nil, errorf(c.loops[len(c.loops)-1].pos, "'loop' without matching 'again'")
+	}
+
+	if len(c.branches) > 0 {
+		return nil, errorf(c.branches[len(c.branches)-1].pos, "'begin' without matching 'end'")
+	}
+
+	for _, f := range c.fixups {
+		addr, ok := c.labels[f.name.text]
+		if !ok {
+			return nil, errorf(f.name.pos, "undefined name '%s'", f.name.text)
+		}
+
+		switch f.kind {
+		case fixAddress:
+			c.patch(f.addr, addr)
+		case fixUnpack:
+			c.mem[f.addr+1] |= byte(addr >> 8)
+			c.mem[f.addr+3] = byte(addr)
+		}
+	}
+
+	if c.jumpToMain {
+		main, ok := c.labels["main"]
+		if !ok {
+			return nil, errorf(Pos{filename, 1, 1}, "program does not define 'main'")
+		}
+
+		c.mem[ROM_START] = byte(0x10 | main>>8)
+		c.mem[ROM_START+1] = byte(main)
+	}
+
+	rom := make([]byte, c.top-ROM_START)
+	copy(rom, c.mem[ROM_START:c.top])
+
+	return &Program{ROM: rom, Labels: c.labels, Lines: c.lines}, nil
+}
+
+// -- Token stream
+
+func (c *compiler) eof() Pos {
+	if len(c.tokens) == 0 {
+		return Pos{c.filename, 1, 1}
+	}
+
+	return c.tokens[len(c.tokens)-1].pos
+}
+
+func (c *compiler) next() (token, error) {
+	if c.idx == len(c.tokens) {
+		return token{}, errorf(c.eof(), "unexpected end of file")
+	}
+
+	t := c.tokens[c.idx]
+	c.idx++
+
+	return t, nil
+}
+
+func (c *compiler) peek() string {
+	if c.idx == len(c.tokens) {
+		return ""
+	}
+
+	return c.tokens[c.idx].text
+}
+
+func (c *compiler) expect(text string) error {
+	t, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if t.text != text {
+		return errorf(t.pos, "expected '%s', found '%s'", text, t.text)
+	}
+
+	return nil
+}
+
+// -- Output
+
+func (c *compiler) emitByte(b byte) error {
+	if int(c.here) >= MEM_SIZE {
+		return errorf(c.pos, "program does not fit in memory")
+	}
+
+	c.mem[c.here] = b
+	c.here++
+	c.top = max(c.top, c.here)
+	c.pristine = false
+
+	return nil
+}
+
+func (c *compiler) emit(ops ...uint16) error {
+	for _, op := range ops {
+		c.lines[c.here] = c.pos
+		if err := c.emitByte(byte(op >> 8)); err != nil {
+			return err
+		}
+		if err := c.emitByte(byte(op)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patch points the instruction at addr to target.
+func (c *compiler) patch(addr, target uint16) {
+	c.mem[addr] = c.mem[addr]&0xF0 | byte(target>>8)&0x0F
+	c.mem[addr+1] = byte(target)
+}
+
+// -- Operands
+
+func (c *compiler) register(t token) (byte, bool) {
+	if reg, ok := c.aliases[t.text]; ok {
+		return reg, true
+	}
+
+	s := strings.ToLower(t.text)
+	if len(s) != 2 || s[0] != 'v' {
+		return 0, false
+	}
+
+	switch {
+	case s[1] >= '0' && s[1] <= '9':
+		return s[1] - '0', true
+	case s[1] >= 'a' && s[1] <= 'f':
+		return s[1] - 'a' + 10, true
+	}
+
+	return 0, false
+}
+
+func (c *compiler) nextRegister() (byte, error) {
+	t, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+
+	reg, ok := c.register(t)
+	if !ok {
+		return 0, errorf(t.pos, "expected a register, found '%s'", t.text)
+	}
+
+	return reg, nil
+}
+
+// isName reports whether t can be used as the name of a label, constant or macro.
+func (c *compiler) isName(t token) bool {
+	if keywords[t.text] {
+		return false
+	}
+
+	if _, ok := c.register(t); ok {
+		return false
+	}
+
+	if _, ok := parseNumber(t.text); ok {
+		return false
+	}
+
+	return t.text != "" && !strings.ContainsAny(t.text, "{}()")
+}
+
+// block collects the tokens of a { ... } block whose opening brace was just read.
+func (c *compiler) block(open token) ([]token, error) {
+	var body []token
+	depth := 1
+
+	for {
+		if c.idx == len(c.tokens) {
+			return nil, errorf(open.pos, "unclosed '{'")
+		}
+
+		t := c.tokens[c.idx]
+		c.idx++
+
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return body, nil
+			}
+		}
+
+		body = append(body, t)
+	}
+}
+
+// value reads a number, constant or { calc } block.
+func (c *compiler) value() (float64, token, error) {
+	t, err := c.next()
+	if err != nil {
+		return 0, t, err
+	}
+
+	if t.text == "{" {
+		body, err := c.block(t)
+		if err != nil {
+			return 0, t, err
+		}
+
+		v, err := (&calc{c: c, tokens: body, open: t}).eval()
+		return v, t, err
+	}
+
+	if n, ok := parseNumber(t.text); ok {
+		return n, t, nil
+	}
+
+	if n, ok := c.consts[t.text]; ok {
+		return n, t, nil
+	}
+
+	return 0, t, errorf(t.pos, "expected a number, found '%s'", t.text)
+}
+
+// integer reads a value and checks that it lies within [lo, hi].
+func (c *compiler) integer(lo, hi int) (int, error) {
+	v, t, err := c.value()
+	if err != nil {
+		return 0, err
+	}
+
+	n := int(math.Floor(v))
+	if n < lo || n > hi {
+		return 0, errorf(t.pos, "value %d out of range [%d, %d]", n, lo, hi)
+	}
+
+	return n, nil
+}
+
+func (c *compiler) byteValue() (byte, error) {
+	n, err := c.integer(-128, 255)
+	return byte(n), err
+}
+
+// addressOp emits op with a 12-bit address operand, which may be a label
+// that has not been defined yet.
+func (c *compiler) addressOp(op uint16) error {
+	if c.idx == len(c.tokens) {
+		return errorf(c.eof(), "unexpected end of file")
+	}
+
+	t := c.tokens[c.idx]
+	_, isConst := c.consts[t.text]
+	if !isConst && t.text != "{" && c.isName(t) {
+		c.idx++
+
+		if addr, ok := c.labels[t.text]; ok {
+			return c.emit(op | addr)
+		}
+
+		c.fixups = append(c.fixups, fixup{fixAddress, c.here, t})
+		return c.emit(op)
+	}
+
+	n, err := c.integer(0, 0xFFF)
+	if err != nil {
+		return err
+	}
+
+	return c.emit(op | uint16(n))
+}
+
+// -- Statements
+
+func (c *compiler) statement() error {
+	t, err := c.next()
+	if err != nil {
+		return err
+	}
+	c.pos = t.pos
+
+	if reg, ok := c.register(t); ok {
+		return c.assignment(reg)
+	}
+
+	switch t.text {
+	case ":":
+		return c.label()
+	case ":alias":
+		return c.alias()
+	case ":const":
+		return c.constant()
+	case ":calc":
+		return c.calcStatement()
+	case ":macro":
+		return c.macroDefinition()
+	case ":org":
+		n, err := c.integer(ROM_START, MEM_SIZE-1)
+		if err != nil {
+			return err
+		}
+		c.here = uint16(n)
+		c.pristine = false
+		return nil
+	case ":byte":
+		b, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+		return c.emitByte(b)
+	case ":unpack":
+		return c.unpack()
+	case ":next":
+		return c.nextLabel()
+	case ":call":
+		return c.addressOp(0x2000)
+	case "return", ";":
+		return c.emit(0x00EE)
+	case "clear":
+		return c.emit(0x00E0)
+	case "bcd":
+		return c.registerOp(0xF033)
+	case "save":
+		return c.registerOp(0xF055)
+	case "load":
+		return c.registerOp(0xF065)
+	case "sprite":
+		return c.sprite()
+	case "jump":
+		return c.addressOp(0x1000)
+	case "jump0":
+		return c.addressOp(0xB000)
+	case "native":
+		return c.addressOp(0x0000)
+	case "i":
+		return c.indexAssignment()
+	case "delay":
+		return c.timerAssignment(0xF015)
+	case "buzzer":
+		return c.timerAssignment(0xF018)
+	case "if":
+		return c.ifStatement(t)
+	case "else":
+		return c.elseStatement(t)
+	case "end":
+		return c.endStatement(t)
+	case "loop":
+		c.loops = append(c.loops, loop{start: c.here, pos: t.pos})
+		return nil
+	case "while":
+		return c.whileStatement(t)
+	case "again":
+		return c.again(t)
+	case "{":
+		// Bare { calc } block, emitted as a byte
+		c.idx--
+		b, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+		return c.emitByte(b)
+	}
+
+	if m, ok := c.macros[t.text]; ok {
+		return c.expand(t, m)
+	}
+
+	// Bare numbers are emitted as data
+	if _, ok := parseNumber(t.text); ok {
+		c.idx--
+		b, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+		return c.emitByte(b)
+	}
+	if _, ok := c.consts[t.text]; ok {
+		c.idx--
+		b, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+		return c.emitByte(b)
+	}
+
+	// Anything else is a subroutine call
+	if !c.isName(t) {
+		return errorf(t.pos, "unexpected '%s'", t.text)
+	}
+	c.idx--
+	return c.addressOp(0x2000)
+}
+
+func (c *compiler) define(t token, addr uint16) error {
+	if !c.isName(t) {
+		return errorf(t.pos, "'%s' is not a valid name", t.text)
+	}
+
+	if _, ok := c.labels[t.text]; ok {
+		return errorf(t.pos, "label '%s' is already defined", t.text)
+	}
+
+	if _, ok := c.consts[t.text]; ok {
+		return errorf(t.pos, "'%s' is already defined as a constant", t.text)
+	}
+
+	c.labels[t.text] = addr
+	return nil
+}
+
+func (c *compiler) label() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if name.text == "main" && c.jumpToMain && c.pristine {
+		// main comes first, so the program can start right at ROM_START
+		c.here, c.top = ROM_START, ROM_START
+		c.jumpToMain = false
+	}
+
+	if err := c.define(name, c.here); err != nil {
+		return err
+	}
+	c.pristine = false
+
+	return nil
+}
+
+func (c *compiler) nextLabel() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	// Points at the operand byte of the following instruction
+	return c.define(name, c.here+1)
+}
+
+func (c *compiler) alias() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if !c.isName(name) {
+		return errorf(name.pos, "'%s' is not a valid name", name.text)
+	}
+
+	reg, err := c.nextRegister()
+	if err != nil {
+		return err
+	}
+
+	c.aliases[name.text] = reg
+	return nil
+}
+
+func (c *compiler) setConstant(name token, value float64) error {
+	if !c.isName(name) {
+		return errorf(name.pos, "'%s' is not a valid name", name.text)
+	}
+
+	if _, ok := c.labels[name.text]; ok {
+		return errorf(name.pos, "'%s' is already defined as a label", name.text)
+	}
+
+	c.consts[name.text] = value
+	return nil
+}
+
+func (c *compiler) constant() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	value, _, err := c.value()
+	if err != nil {
+		return err
+	}
+
+	return c.setConstant(name, value)
+}
+
+func (c *compiler) calcStatement() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if c.peek() != "{" {
+		t, err := c.next()
+		if err != nil {
+			return err
+		}
+		return errorf(t.pos, "expected '{', found '%s'", t.text)
+	}
+
+	value, _, err := c.value()
+	if err != nil {
+		return err
+	}
+
+	return c.setConstant(name, value)
+}
+
+func (c *compiler) macroDefinition() error {
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if !c.isName(name) {
+		return errorf(name.pos, "'%s' is not a valid name", name.text)
+	}
+
+	m := &macro{}
+	for {
+		t, err := c.next()
+		if err != nil {
+			return err
+		}
+
+		if t.text == "{" {
+			m.body, err = c.block(t)
+			if err != nil {
+				return err
+			}
+			break
+		}
+
+		m.args = append(m.args, t.text)
+	}
+
+	c.macros[name.text] = m
+	return nil
+}
+
+// expand splices the body of a macro into the token stream, substituting
+// its arguments.
+func (c *compiler) expand(call token, m *macro) error {
+	c.expansions++
+	if c.expansions > MAX_MACRO_EXPANSIONS {
+		return errorf(call.pos, "too many macro expansions, is '%s' recursive?", call.text)
+	}
+
+	args := map[string]token{}
+	for _, name := range m.args {
+		t, err := c.next()
+		if err != nil {
+			return err
+		}
+		args[name] = t
+	}
+
+	body := make([]token, len(m.body))
+	for idx, t := range m.body {
+		if arg, ok := args[t.text]; ok {
+			t = arg
+		}
+		body[idx] = t
+	}
+
+	// Consumed tokens are dropped so expansion doesn't copy the whole program
+	c.tokens = append(body, c.tokens[c.idx:]...)
+	c.idx = 0
+
+	return nil
+}
+
+func (c *compiler) unpack() error {
+	nibble, err := c.integer(0, 0xF)
+	if err != nil {
+		return err
+	}
+
+	name, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if addr, ok := c.labels[name.text]; ok {
+		return c.emit(0x6000|uint16(nibble)<<4|addr>>8, 0x6100|addr&0xFF)
+	}
+
+	if !c.isName(name) {
+		return errorf(name.pos, "expected a label, found '%s'", name.text)
+	}
+
+	c.fixups = append(c.fixups, fixup{fixUnpack, c.here, name})
+	return c.emit(0x6000|uint16(nibble)<<4, 0x6100)
+}
+
+func (c *compiler) registerOp(op uint16) error {
+	reg, err := c.nextRegister()
+	if err != nil {
+		return err
+	}
+
+	return c.emit(op | uint16(reg)<<8)
+}
+
+func (c *compiler) sprite() error {
+	x, err := c.nextRegister()
+	if err != nil {
+		return err
+	}
+
+	y, err := c.nextRegister()
+	if err != nil {
+		return err
+	}
+
+	n, err := c.integer(0, 0xF)
+	if err != nil {
+		return err
+	}
+
+	return c.emit(0xD000 | uint16(x)<<8 | uint16(y)<<4 | uint16(n))
+}
+
+func (c *compiler) timerAssignment(op uint16) error {
+	if err := c.expect(":="); err != nil {
+		return err
+	}
+
+	return c.registerOp(op)
+}
+
+func (c *compiler) indexAssignment() error {
+	op, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	switch op.text {
+	case ":=":
+		if c.peek() == "hex" {
+			c.idx++
+			return c.registerOp(0xF029)
+		}
+		return c.addressOp(0xA000)
+	case "+=":
+		return c.registerOp(0xF01E)
+	}
+
+	return errorf(op.pos, "unknown operator '%s' for i", op.text)
+}
+
+var registerOps = map[string]uint16{
+	":=":  0x8000,
+	"|=":  0x8001,
+	"&=":  0x8002,
+	"^=":  0x8003,
+	"+=":  0x8004,
+	"-=":  0x8005,
+	">>=": 0x8006,
+	"=-":  0x8007,
+	"<<=": 0x800E,
+}
+
+func (c *compiler) assignment(x byte) error {
+	op, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	base, ok := registerOps[op.text]
+	if !ok {
+		return errorf(op.pos, "unknown operator '%s'", op.text)
+	}
+	vx := uint16(x) << 8
+
+	rhs, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if y, ok := c.register(rhs); ok {
+		return c.emit(base | vx | uint16(y)<<4)
+	}
+
+	switch {
+	case op.text == ":=" && rhs.text == "random":
+		nn, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+		return c.emit(0xC000 | vx | uint16(nn))
+	case op.text == ":=" && rhs.text == "delay":
+		return c.emit(0xF007 | vx)
+	case op.text == ":=" && rhs.text == "key":
+		return c.emit(0xF00A | vx)
+	}
+
+	// Everything else takes a constant
+	c.idx--
+	switch op.text {
+	case ":=", "+=", "-=":
+		nn, err := c.byteValue()
+		if err != nil {
+			return err
+		}
+
+		switch op.text {
+		case ":=":
+			return c.emit(0x6000 | vx | uint16(nn))
+		case "+=":
+			return c.emit(0x7000 | vx | uint16(nn))
+		default:
+			return c.emit(0x7000 | vx | uint16(-nn))
+		}
+	}
+
+	return errorf(rhs.pos, "operator '%s' needs a register, found '%s'", op.text, rhs.text)
+}
+
+// ge returns instructions that set vf to 1 if a >= b and 0 otherwise. At most
+// one side can be a constant.
+func ge(a, b operand) []uint16 {
+	switch {
+	case a.isConst:
+		// vf := a ; vf -= b
+		return []uint16{0x6F00 | uint16(a.value), 0x8F05 | uint16(b.value)<<4}
+	case b.isConst:
+		// vf := b ; vf =- a
+		return []uint16{0x6F00 | uint16(b.value), 0x8F07 | uint16(a.value)<<4}
+	}
+
+	// vf := a ; vf -= b
+	return []uint16{0x8F00 | uint16(a.value)<<4, 0x8F05 | uint16(b.value)<<4}
+}
+
+// operand is either a register or a constant byte.
+type operand struct {
+	value   byte
+	isConst bool
+}
+
+func (c *compiler) condition() (condition, error) {
+	x, err := c.nextRegister()
+	if err != nil {
+		return condition{}, err
+	}
+	vx := uint16(x) << 8
+
+	op, err := c.next()
+	if err != nil {
+		return condition{}, err
+	}
+
+	switch op.text {
+	case "key":
+		return condition{whenTrue: 0xE09E | vx, whenFalse: 0xE0A1 | vx}, nil
+	case "-key":
+		return condition{whenTrue: 0xE0A1 | vx, whenFalse: 0xE09E | vx}, nil
+	case "==", "!=", "<", ">", "<=", ">=":
+	default:
+		return condition{}, errorf(op.pos, "unknown comparison '%s'", op.text)
+	}
+
+	lhs := operand{value: x}
+	var rhs operand
+
+	if c.idx == len(c.tokens) {
+		return condition{}, errorf(c.eof(), "unexpected end of file")
+	}
+	if y, ok := c.register(c.tokens[c.idx]); ok {
+		c.idx++
+		rhs = operand{value: y}
+	} else {
+		nn, err := c.byteValue()
+		if err != nil {
+			return condition{}, err
+		}
+		rhs = operand{value: nn, isConst: true}
+	}
+
+	var cond condition
+	switch op.text {
+	case "==", "!=":
+		if rhs.isConst {
+			cond = condition{whenTrue: 0x3000 | vx | uint16(rhs.value), whenFalse: 0x4000 | vx | uint16(rhs.value)}
+		} else {
+			cond = condition{whenTrue: 0x5000 | vx | uint16(rhs.value)<<4, whenFalse: 0x9000 | vx | uint16(rhs.value)<<4}
+		}
+
+		if op.text == "!=" {
+			cond.whenTrue, cond.whenFalse = cond.whenFalse, cond.whenTrue
+		}
+	case ">=":
+		cond = condition{ge(lhs, rhs), 0x3F01, 0x4F01}
+	case "<":
+		cond = condition{ge(lhs, rhs), 0x3F00, 0x4F00}
+	case ">":
+		cond = condition{ge(rhs, lhs), 0x3F00, 0x4F00}
+	case "<=":
+		cond = condition{ge(rhs, lhs), 0x3F01, 0x4F01}
+	}
+
+	return cond, nil
+}
+
+func (c *compiler) ifStatement(t token) error {
+	cond, err := c.condition()
+	if err != nil {
+		return err
+	}
+
+	kind, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if err := c.emit(cond.setup...); err != nil {
+		return err
+	}
+
+	switch kind.text {
+	case "then":
+		if err := c.emit(cond.whenFalse); err != nil {
+			return err
+		}
+
+		if c.idx == len(c.tokens) {
+			return errorf(kind.pos, "expected a statement after 'then'")
+		}
+		return c.statement()
+	case "begin":
+		// Skip over the jump to else/end if the condition holds
+		if err := c.emit(cond.whenTrue); err != nil {
+			return err
+		}
+		c.branches = append(c.branches, branch{jump: c.here, pos: t.pos})
+		return c.emit(0x1000)
+	}
+
+	return errorf(kind.pos, "expected 'then' or 'begin', found '%s'", kind.text)
+}
+
+func (c *compiler) elseStatement(t token) error {
+	if len(c.branches) == 0 {
+		return errorf(t.pos, "'else' without matching 'begin'")
+	}
+
+	b := &c.branches[len(c.branches)-1]
+	if b.hasElse {
+		return errorf(t.pos, "'begin' block already has an 'else'")
+	}
+
+	jump := c.here
+	if err := c.emit(0x1000); err != nil {
+		return err
+	}
+
+	c.patch(b.jump, c.here)
+	b.jump = jump
+	b.hasElse = true
+
+	return nil
+}
+
+func (c *compiler) endStatement(t token) error {
+	if len(c.branches) == 0 {
+		return errorf(t.pos, "'end' without matching 'begin'")
+	}
+
+	b := c.branches[len(c.branches)-1]
+	c.branches = c.branches[:len(c.branches)-1]
+	c.patch(b.jump, c.here)
+
+	return nil
+}
+
+func (c *compiler) whileStatement(t token) error {
+	if len(c.loops) == 0 {
+		return errorf(t.pos, "'while' outside of a loop")
+	}
+
+	cond, err := c.condition()
+	if err != nil {
+		return err
+	}
+
+	// Skip over the jump out of the loop while the condition holds
+	if err := c.emit(append(cond.setup, cond.whenTrue)...); err != nil {
+		return err
+	}
+
+	l := &c.loops[len(c.loops)-1]
+	l.whiles = append(l.whiles, c.here)
+
+	return c.emit(0x1000)
+}
+
+func (c *compiler) again(t token) error {
+	if len(c.loops) == 0 {
+		return errorf(t.pos, "'again' without matching 'loop'")
+	}
+
+	l := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if err := c.emit(0x1000 | l.start); err != nil {
+		return err
+	}
+
+	for _, addr := range l.whiles {
+		c.patch(addr, c.here)
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package octo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func compileString(t *testing.T, src string) []byte {
+	prog, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return prog.ROM
+}
+
+func TestInstructions(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		: main
+		clear
+		return
+		v3 := 0x22
+		v3 += 1
+		v3 -= 1
+		v3 := v4
+		v3 |= v4
+		v3 &= v4
+		v3 ^= v4
+		v3 += v4
+		v3 -= v4
+		v3 >>= v4
+		v3 =- v4
+		v3 <<= v4
+		i := 0x123
+		jump0 0x300
+		v3 := random 0xFF
+		sprite v3 v4 5
+		v3 := delay
+		v3 := key
+		delay := v3
+		buzzer := v3
+		i += v3
+		i := hex v3
+		bcd v3
+		save v3
+		load v3
+	`)
+
+	assert.Equal([]byte{
+		0x00, 0xE0, 0x00, 0xEE,
+		0x63, 0x22, 0x73, 0x01, 0x73, 0xFF,
+		0x83, 0x40, 0x83, 0x41, 0x83, 0x42, 0x83, 0x43, 0x83, 0x44,
+		0x83, 0x45, 0x83, 0x46, 0x83, 0x47, 0x83, 0x4E,
+		0xA1, 0x23, 0xB3, 0x00, 0xC3, 0xFF, 0xD3, 0x45,
+		0xF3, 0x07, 0xF3, 0x0A, 0xF3, 0x15, 0xF3, 0x18, 0xF3, 0x1E,
+		0xF3, 0x29, 0xF3, 0x33, 0xF3, 0x55, 0xF3, 0x65,
+	}, rom)
+}
+
+func TestJumpToMain(t *testing.T) {
+	assert := assert.New(t)
+
+	// main first: no jump needed
+	assert.Equal([]byte{0x00, 0xE0}, compileString(t, ": main clear"))
+
+	// main later: a jump to it is placed at ROM_START
+	rom := compileString(t, ": sub return : main sub")
+	assert.Equal([]byte{0x12, 0x04, 0x00, 0xEE, 0x22, 0x02}, rom)
+
+	_, err := Compile(strings.NewReader(": sub return"))
+	assert.EqualError(err, "1:1: program does not define 'main'")
+}
+
+func TestLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := Compile(strings.NewReader(`
+		: main
+		i := logo
+		jump main
+		: logo
+		0xFF 0x81 0xFF
+	`))
+	assert.NoError(err)
+	assert.Equal([]byte{0xA2, 0x04, 0x12, 0x00, 0xFF, 0x81, 0xFF}, prog.ROM)
+	assert.Equal(uint16(0x204), prog.Labels["logo"])
+}
+
+func TestAliasAndConst(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		:alias score v7
+		:const START 10
+		: main
+		score := START
+	`)
+	assert.Equal([]byte{0x67, 0x0A}, rom)
+}
+
+func TestIfThen(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		: main
+		if v1 == 3 then clear
+		if v1 != v2 then clear
+		if v1 key then clear
+		if v1 -key then clear
+	`)
+	assert.Equal([]byte{
+		0x41, 0x03, 0x00, 0xE0,
+		0x51, 0x20, 0x00, 0xE0,
+		0xE1, 0xA1, 0x00, 0xE0,
+		0xE1, 0x9E, 0x00, 0xE0,
+	}, rom)
+}
+
+func TestIfComparisons(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		: main
+		if v1 >= v2 then clear
+		if v1 < 5 then clear
+	`)
+	assert.Equal([]byte{
+		// vf := v1 ; vf -= v2 ; if vf == 1 then
+		0x8F, 0x10, 0x8F, 0x25, 0x4F, 0x01, 0x00, 0xE0,
+		// vf := 5 ; vf =- v1 ; if vf == 0 then
+		0x6F, 0x05, 0x8F, 0x17, 0x4F, 0x00, 0x00, 0xE0,
+	}, rom)
+}
+
+func TestBeginElseEnd(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		: main
+		if v0 == 1 begin
+			v1 := 1
+		else
+			v1 := 2
+		end
+	`)
+	assert.Equal([]byte{
+		0x30, 0x01, // 200: skip the jump if v0 == 1
+		0x12, 0x08, // 202: jump to else
+		0x61, 0x01, // 204
+		0x12, 0x0A, // 206: jump to end
+		0x61, 0x02, // 208
+	}, rom)
+}
+
+func TestLoopWhileAgain(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		: main
+		loop
+			v0 += 1
+			while v0 != 10
+		again
+	`)
+	assert.Equal([]byte{
+		0x70, 0x01, // 200
+		0x40, 0x0A, // 202: skip the exit jump while v0 != 10
+		0x12, 0x08, // 204: exit
+		0x12, 0x00, // 206: again
+	}, rom)
+}
+
+func TestMacro(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, `
+		:macro swap A B { vf := A A := B B := vf }
+		: main
+		swap v1 v2
+	`)
+	assert.Equal([]byte{0x8F, 0x10, 0x81, 0x20, 0x82, 0xF0}, rom)
+
+	_, err := Compile(strings.NewReader(":macro forever { forever } : main forever"))
+	assert.ErrorContains(err, "too many macro expansions")
+}
+
+func TestUnpackAndNext(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := Compile(strings.NewReader(`
+		: main
+		:unpack 0xA data
+		: data
+		:next target
+		v0 := 0
+	`))
+	assert.NoError(err)
+	assert.Equal([]byte{0x60, 0xA2, 0x61, 0x04, 0x60, 0x00}, prog.ROM)
+	assert.Equal(uint16(0x205), prog.Labels["target"])
+}
+
+func TestOrg(t *testing.T) {
+	assert := assert.New(t)
+
+	rom := compileString(t, ": main jump 0x300 :org 0x204 :byte 1")
+	assert.Equal([]byte{0x13, 0x00, 0x00, 0x00, 0x01}, rom)
+}
+
+func TestCompileErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]string{
+		": main\n  jump nowhere":        "2:8: undefined name 'nowhere'",
+		": main\nv0 := 300":             "2:7: value 300 out of range [-128, 255]",
+		": main\nsprite v0 v1 16":       "2:14: value 16 out of range [0, 15]",
+		": main\nloop clear":            "2:1: 'loop' without matching 'again'",
+		": main\nif v0 == 1 begin":      "2:1: 'begin' without matching 'end'",
+		": main\nend":                   "2:1: 'end' without matching 'begin'",
+		": main\nv0 :: 1":               "2:4: unknown operator '::'",
+		": main\n: main":                "2:3: label 'main' is already defined",
+		": main\nif v0 ~= 1 then clear": "2:7: unknown comparison '~='",
+		": main\nv0 |= 4":               "2:7: operator '|=' needs a register, found '4'",
+		": main\n:calc x { ( 1 + 2 }":   "2:11: unclosed '('",
+		": main\n:const x":              "2:8: unexpected end of file",
+		": main\n:macro m {":            "2:10: unclosed '{'",
+		": main\n)":                     "2:1: unexpected ')'",
+		": main\nif v0 == 1 then":       "2:12: expected a statement after 'then'",
+		": main\nif v0 == 1 clear":      "2:12: expected 'then' or 'begin', found 'clear'",
+		": main\nwhile v0 == 1":         "2:1: 'while' outside of a loop",
+		": main\n:alias v1 v2":          "2:8: 'v1' is not a valid name",
+		": main\n:org 0x100":            "2:6: value 256 out of range [512, 4095]",
+		": main\n:org 0xFFF\nclear":     "3:1: program does not fit in memory",
+		": main\ni -= v0":               "2:3: unknown operator '-=' for i",
+		": main\nsave 3":                "2:6: expected a register, found '3'",
+	}
+
+	for src, msg := range cases {
+		_, err := Compile(strings.NewReader(src))
+		assert.EqualError(err, msg, src)
+	}
+}
+
+func TestCompileFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "game.8o")
+	assert.NoError(os.WriteFile(path, []byte(": main\njump nowhere\n"), 0o644))
+
+	_, err := CompileFile(path)
+	assert.EqualError(err, path+":2:6: undefined name 'nowhere'")
+
+	var compileErr *Error
+	assert.ErrorAs(err, &compileErr)
+	assert.Equal(2, compileErr.Pos.Line)
+}
+
+func TestLines(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := Compile(strings.NewReader(": main\n\tclear\n\tif v0 == 1 then v1 := 2\n: data 0x12 0x34\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(map[uint16]Pos{
+		0x200: {"", 2, 2},
+		0x202: {"", 3, 2},
+		0x204: {"", 3, 18},
+	}, prog.Lines)
+}
@@ -0,0 +1,58 @@
+package octo
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is a single whitespace-delimited word of source.
+type token struct {
+	text string
+	pos  Pos
+}
+
+// lex splits source code into tokens. Octo tokens are separated by whitespace
+// and # starts a comment that runs to the end of the line.
+func lex(filename, src string) []token {
+	var tokens []token
+
+	line, col := 1, 1
+	var word strings.Builder
+	var start Pos
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, token{word.String(), start})
+			word.Reset()
+		}
+	}
+
+	comment := false
+	for _, r := range src {
+		switch {
+		case r == '\n':
+			flush()
+			comment = false
+		case comment:
+		case r == '#':
+			flush()
+			comment = true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if word.Len() == 0 {
+				start = Pos{filename, line, col}
+			}
+			word.WriteRune(r)
+		}
+
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	flush()
+
+	return tokens
+}
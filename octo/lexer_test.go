@@ -0,0 +1,30 @@
+package octo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexSplitsOnWhitespace(t *testing.T) {
+	assert := assert.New(t)
+	tokens := lex("", "v0 := 5\n\tclear")
+
+	var texts []string
+	for _, tok := range tokens {
+		texts = append(texts, tok.text)
+	}
+
+	assert.Equal([]string{"v0", ":=", "5", "clear"}, texts)
+	assert.Equal(Pos{"", 2, 2}, tokens[3].pos)
+}
+
+func TestLexSkipsComments(t *testing.T) {
+	assert := assert.New(t)
+	tokens := lex("game.8o", "# header\nclear # wipe the screen\nreturn")
+
+	assert.Len(tokens, 2)
+	assert.Equal("clear", tokens[0].text)
+	assert.Equal(Pos{"game.8o", 2, 1}, tokens[0].pos)
+	assert.Equal(Pos{"game.8o", 3, 1}, tokens[1].pos)
+}
@@ -0,0 +1,77 @@
+// Package octo compiles Octo assembly language into CHIP-8 ROM images.
+//
+// Octo is the de facto standard language for modern CHIP-8 homebrew. This
+// package implements the subset that runs on a plain CHIP-8: labels, :alias,
+// :const, loop/again/while, if ... then and if ... begin/else/end, :macro,
+// :calc, :byte, :org, :next, :unpack and the full CHIP-8 instruction syntax.
+// See https://johnearnest.github.io/Octo/docs/Manual.html for the language.
+package octo
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ROM_START is where compiled programs are loaded into memory.
+const ROM_START = 0x200
+
+// MEM_SIZE is the amount of addressable CHIP-8 memory.
+const MEM_SIZE = 4 * 1024
+
+// Program is the result of a successful compilation.
+type Program struct {
+	// ROM image, to be loaded at ROM_START
+	ROM []byte
+
+	// Addresses of every label defined in the source
+	Labels map[string]uint16
+
+	// Source position of the statement that compiled to each instruction
+	Lines map[uint16]Pos
+}
+
+// Pos is a position in Octo source code.
+type Pos struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Pos) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// Error is a compile error with the source position it occurred at.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// CompileFile reads and compiles an Octo source file.
+func CompileFile(path string) (*Program, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return compile(path, string(src))
+}
+
+// Compile compiles Octo source code from a buffer.
+func Compile(buf io.Reader) (*Program, error) {
+	src, err := io.ReadAll(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return compile("", string(src))
+}
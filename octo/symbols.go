@@ -0,0 +1,59 @@
+package octo
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+type symbolLine struct {
+	Address uint16 `json:"address"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+type symbolFile struct {
+	Labels map[string]uint16 `json:"labels"`
+	Lines  []symbolLine      `json:"lines"`
+}
+
+// WriteSymbols writes the program's labels and line table as JSON, so that
+// debuggers can map a compiled ROM back to its source.
+func (p *Program) WriteSymbols(w io.Writer) error {
+	out := symbolFile{Labels: p.Labels, Lines: []symbolLine{}}
+	if out.Labels == nil {
+		out.Labels = map[string]uint16{}
+	}
+
+	for addr, pos := range p.Lines {
+		out.Lines = append(out.Lines, symbolLine{addr, pos.Filename, pos.Line, pos.Column})
+	}
+	slices.SortFunc(out.Lines, func(a, b symbolLine) int {
+		return int(a.Address) - int(b.Address)
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ReadSymbols reads a symbol file written by WriteSymbols. The returned
+// program has no ROM.
+func ReadSymbols(r io.Reader) (*Program, error) {
+	var in symbolFile
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+
+	p := &Program{Labels: in.Labels, Lines: map[uint16]Pos{}}
+	if p.Labels == nil {
+		p.Labels = map[string]uint16{}
+	}
+
+	for _, l := range in.Lines {
+		p.Lines[l.Address] = Pos{l.File, l.Line, l.Column}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,31 @@
+package octo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbols(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := Compile(strings.NewReader(": main\n\tsub\n: sub\n\treturn\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(prog.WriteSymbols(&buf))
+	assert.Contains(buf.String(), `"address": 514`)
+
+	syms, err := ReadSymbols(&buf)
+	assert.Nil(err)
+	assert.Nil(syms.ROM)
+	assert.Equal(prog.Labels, syms.Labels)
+	assert.Equal(prog.Lines, syms.Lines)
+
+	_, err = ReadSymbols(strings.NewReader("{"))
+	assert.NotNil(err)
+}
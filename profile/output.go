@@ -0,0 +1,168 @@
+package profile
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+
+	pprof "github.com/google/pprof/profile"
+)
+
+// percent returns n as a percentage of the profile's cycles.
+func (p *Profile) percent(n uint64) float64 {
+	if p.Cycles == 0 {
+		return 0
+	}
+
+	return 100 * float64(n) / float64(p.Cycles)
+}
+
+// WriteText writes a report of the subroutines, instructions and the top
+// addresses by executions.
+func (p *Profile) WriteText(w io.Writer, top int) error {
+	fmt.Fprintf(w, "%d frames, %d cycles\n", p.Frames, p.Cycles)
+	fmt.Fprintf(
+		w, "busy-waiting: %.1f%% of cycles (delay timer %.1f%%, keys %.1f%%), in %d of %d frames\n",
+		100*p.BusyWait(), p.percent(p.DelayWait), p.percent(p.KeyWait), p.WaitFrames, p.Frames,
+	)
+
+	subs := slices.SortedFunc(maps.Values(p.Subroutines), func(a, b *Subroutine) int {
+		return cmp.Or(cmp.Compare(b.Inclusive, a.Inclusive), cmp.Compare(a.Addr, b.Addr))
+	})
+	fmt.Fprintf(w, "\nsubroutines:\n%12s %7s %12s %7s %8s  %s\n", "inclusive", "", "exclusive", "", "calls", "name")
+	for _, sub := range subs {
+		fmt.Fprintf(
+			w, "%12d %6.1f%% %12d %6.1f%% %8d  %s\n",
+			sub.Inclusive, p.percent(sub.Inclusive), sub.Exclusive, p.percent(sub.Exclusive), sub.Calls, p.name(sub.Addr),
+		)
+	}
+
+	classes := slices.SortedFunc(maps.Keys(p.Classes), func(a, b string) int {
+		return cmp.Or(cmp.Compare(p.Classes[b], p.Classes[a]), cmp.Compare(a, b))
+	})
+	fmt.Fprintf(w, "\ninstructions:\n%12s %7s  %s\n", "count", "", "mnemonic")
+	for _, class := range classes {
+		fmt.Fprintf(w, "%12d %6.1f%%  %s\n", p.Classes[class], p.percent(p.Classes[class]), class)
+	}
+
+	var addrs []uint16
+	for addr, count := range p.PCs {
+		if count > 0 {
+			addrs = append(addrs, uint16(addr))
+		}
+	}
+	slices.SortFunc(addrs, func(a, b uint16) int {
+		return cmp.Or(cmp.Compare(p.PCs[b], p.PCs[a]), cmp.Compare(a, b))
+	})
+	if top > 0 && len(addrs) > top {
+		addrs = addrs[:top]
+	}
+
+	fmt.Fprintf(w, "\naddresses:\n%12s %7s  %-5s  %-4s  %s\n", "count", "", "addr", "op", "instruction")
+	for _, addr := range addrs {
+		op := p.Opcodes[addr]
+		_, err := fmt.Fprintf(w, "%12d %6.1f%%  0x%03X  %04X  %s\n", p.PCs[addr], p.percent(p.PCs[addr]), addr, op, disasm.Decode(op))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pprof converts the profile to pprof's format. Each subroutine becomes a
+// function, and each address a location whose line number is the address.
+// file names the ROM the profile was taken from.
+func (p *Profile) Pprof(file string) *pprof.Profile {
+	prof := &pprof.Profile{
+		SampleType: []*pprof.ValueType{{Type: "instructions", Unit: "count"}},
+		PeriodType: &pprof.ValueType{Type: "instructions", Unit: "count"},
+		Period:     1,
+		Comments: []string{
+			fmt.Sprintf("%d frames, %d cycles", p.Frames, p.Cycles),
+			fmt.Sprintf("busy-waiting: %.1f%% of cycles", 100*p.BusyWait()),
+		},
+	}
+
+	mapping := &pprof.Mapping{
+		ID:             1,
+		Limit:          emulator.MEM_SIZE,
+		File:           file,
+		HasFunctions:   true,
+		HasLineNumbers: true,
+	}
+	prof.Mapping = []*pprof.Mapping{mapping}
+
+	functions := map[uint16]*pprof.Function{}
+	function := func(addr uint16) *pprof.Function {
+		fn, ok := functions[addr]
+		if !ok {
+			fn = &pprof.Function{
+				ID:         uint64(len(prof.Function) + 1),
+				Name:       p.name(addr),
+				SystemName: fmt.Sprintf("0x%03X", addr),
+				Filename:   file,
+				StartLine:  int64(addr),
+			}
+			functions[addr] = fn
+			prof.Function = append(prof.Function, fn)
+		}
+
+		return fn
+	}
+
+	// The same address can be reached in several subroutines
+	type site struct{ pc, sub uint16 }
+	locations := map[site]*pprof.Location{}
+	location := func(pc, sub uint16) *pprof.Location {
+		loc, ok := locations[site{pc, sub}]
+		if !ok {
+			loc = &pprof.Location{
+				ID:      uint64(len(prof.Location) + 1),
+				Mapping: mapping,
+				Address: uint64(pc),
+				Line:    []pprof.Line{{Function: function(sub), Line: int64(pc)}},
+			}
+			locations[site{pc, sub}] = loc
+			prof.Location = append(prof.Location, loc)
+		}
+
+		return loc
+	}
+
+	keys := slices.SortedFunc(maps.Keys(p.samples), func(a, b stackKey) int {
+		return cmp.Or(
+			cmp.Compare(a.depth, b.depth),
+			slices.CompareFunc(a.calls[:a.depth], b.calls[:b.depth], func(x, y call) int {
+				return cmp.Or(cmp.Compare(x.addr, y.addr), cmp.Compare(x.site, y.site))
+			}),
+			cmp.Compare(a.pc, b.pc),
+		)
+	})
+	for _, key := range keys {
+		// Innermost first: the running instruction, then each call site
+		calls := key.calls[:key.depth]
+		locs := []*pprof.Location{location(key.pc, calls[len(calls)-1].addr)}
+		for idx := len(calls) - 1; idx > 0; idx-- {
+			locs = append(locs, location(calls[idx].site, calls[idx-1].addr))
+		}
+
+		prof.Sample = append(prof.Sample, &pprof.Sample{
+			Location: locs,
+			Value:    []int64{int64(p.samples[key])},
+		})
+	}
+
+	return prof
+}
+
+// WritePprof writes the profile as a gzipped pprof protobuf, which `go tool
+// pprof` can read.
+func (p *Profile) WritePprof(w io.Writer, file string) error {
+	return p.Pprof(file).Write(w)
+}
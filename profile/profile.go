@@ -0,0 +1,258 @@
+// Package profile measures where a ROM spends its cycles.
+//
+// A Profiler counts every instruction an emulator executes by address and by
+// instruction, and attributes it to the subroutine it ran in by following
+// CALL and RET. Exclusive cycles are those spent in a subroutine itself, and
+// inclusive cycles add those of everything it called. Code outside any
+// subroutine is attributed to main, at ROM_START.
+//
+// It also measures busy-waiting: loops that poll the delay timer (Fx07) or
+// wait for a key (Fx0A) can't make progress until the next frame, so once such
+// an instruction runs a second time in the same frame, the cycles spent
+// looping back to it are counted as waiting.
+package profile
+
+import (
+	"fmt"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Wait is what a busy-wait loop is waiting for.
+type Wait byte
+
+const (
+	WaitNone Wait = iota
+	WaitDelay
+	WaitKey
+)
+
+// Subroutine holds the cycles spent in one subroutine.
+type Subroutine struct {
+	Addr  uint16
+	Calls uint64
+
+	// Cycles spent in the subroutine itself, and including its callees.
+	// Recursive calls are only counted once towards inclusive cycles.
+	Exclusive uint64
+	Inclusive uint64
+}
+
+// call is a subroutine on the profiler's call stack.
+type call struct {
+	addr uint16
+
+	// Address of the CALL instruction, or 0 for main
+	site uint16
+}
+
+// The deepest call stack kept for samples, including main
+const MAX_DEPTH = emulator.STACK_SIZE + 1
+
+// stackKey identifies a call stack and the instruction running in it.
+type stackKey struct {
+	depth int
+	calls [MAX_DEPTH]call
+	pc    uint16
+}
+
+// Profile is the result of profiling a ROM.
+type Profile struct {
+	Frames uint64
+	Cycles uint64
+
+	// Executions of each address, and of each instruction by mnemonic
+	PCs     [emulator.MEM_SIZE]uint64
+	Classes map[string]uint64
+
+	// The opcode last executed at each address
+	Opcodes [emulator.MEM_SIZE]uint16
+
+	Subroutines map[uint16]*Subroutine
+
+	// Cycles spent busy-waiting on the delay timer and on key presses, and
+	// the number of frames with any busy-waiting
+	DelayWait  uint64
+	KeyWait    uint64
+	WaitFrames uint64
+
+	// Names subroutines in reports, if set. Unnamed subroutines are called
+	// main or sub_XXX.
+	Name func(addr uint16) string
+
+	// Cycles by call stack, for pprof
+	samples map[stackKey]uint64
+}
+
+// Profiler profiles an emulator as it runs.
+type Profiler struct {
+	*Profile
+
+	emu   emulator.Emulator
+	frame uint64
+	stack []call
+
+	// The polling instruction being watched for a repeat, and the range of
+	// addresses run since it first ran
+	pollPC  uint16
+	polling bool
+	loopLo  uint16
+	loopHi  uint16
+	waiting Wait
+
+	waitedThisFrame bool
+}
+
+// Attach starts profiling every instruction emu executes.
+func Attach(emu emulator.Emulator) *Profiler {
+	p := &Profiler{
+		Profile: &Profile{
+			Classes:     map[string]uint64{},
+			Subroutines: map[uint16]*Subroutine{},
+			samples:     map[stackKey]uint64{},
+		},
+		emu:   emu,
+		stack: []call{{addr: emulator.ROM_START}},
+	}
+	p.subroutine(emulator.ROM_START).Calls = 1
+
+	emu.OnExecute(p.execute)
+	return p
+}
+
+// Run profiles emu for the given number of frames.
+func Run(emu emulator.Emulator, frames int) (*Profile, error) {
+	p := Attach(emu)
+	for range frames {
+		if err := emu.RunFrame(); err != nil {
+			return p.Profile, err
+		}
+	}
+
+	return p.Profile, nil
+}
+
+func (p *Profiler) subroutine(addr uint16) *Subroutine {
+	sub, ok := p.Subroutines[addr]
+	if !ok {
+		sub = &Subroutine{Addr: addr}
+		p.Subroutines[addr] = sub
+	}
+
+	return sub
+}
+
+func (p *Profiler) execute(pc, opcode uint16) {
+	if frame := p.emu.FrameCount(); frame != p.frame {
+		p.frame = frame
+		p.Frames++
+		p.polling, p.waiting, p.waitedThisFrame = false, WaitNone, false
+	}
+
+	p.Cycles++
+	p.PCs[pc]++
+	p.Opcodes[pc] = opcode
+
+	in := disasm.Decode(opcode)
+	class := in.Mnemonic
+	if class == "" {
+		class = "invalid"
+	}
+	p.Classes[class]++
+
+	p.busyWait(pc, opcode)
+	p.attribute(pc)
+
+	switch in.Mnemonic {
+	case "CALL":
+		p.subroutine(in.NNN).Calls++
+		p.stack = append(p.stack, call{addr: in.NNN, site: pc})
+	case "RET":
+		// Returns from main are faults, and leave the stack as it is
+		if len(p.stack) > 1 {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+	}
+}
+
+// busyWait tracks loops around polling instructions.
+func (p *Profiler) busyWait(pc, opcode uint16) {
+	var wait Wait
+	switch opcode & 0xF0FF {
+	case 0xF007:
+		wait = WaitDelay
+	case 0xF00A:
+		wait = WaitKey
+	}
+
+	switch {
+	case p.waiting != WaitNone && (pc < p.loopLo || pc > p.loopHi):
+		// Left the loop
+		p.waiting, p.polling = WaitNone, false
+	case p.polling && wait != WaitNone && pc == p.pollPC:
+		p.waiting = wait
+	case p.polling && p.waiting == WaitNone:
+		p.loopLo, p.loopHi = min(p.loopLo, pc), max(p.loopHi, pc)
+	}
+
+	if p.waiting == WaitNone && wait != WaitNone && (!p.polling || pc != p.pollPC) {
+		p.pollPC, p.polling = pc, true
+		p.loopLo, p.loopHi = pc, pc
+	}
+
+	switch p.waiting {
+	case WaitDelay:
+		p.DelayWait++
+	case WaitKey:
+		p.KeyWait++
+	}
+	if p.waiting != WaitNone && !p.waitedThisFrame {
+		p.waitedThisFrame = true
+		p.WaitFrames++
+	}
+}
+
+// attribute counts an instruction towards the subroutines on the stack.
+func (p *Profiler) attribute(pc uint16) {
+	p.subroutine(p.stack[len(p.stack)-1].addr).Exclusive++
+
+	for idx, c := range p.stack {
+		recursive := false
+		for _, outer := range p.stack[:idx] {
+			recursive = recursive || outer.addr == c.addr
+		}
+		if !recursive {
+			p.subroutine(c.addr).Inclusive++
+		}
+	}
+
+	// Keep the innermost calls of stacks too deep to sample
+	key := stackKey{pc: pc}
+	calls := p.stack[max(0, len(p.stack)-MAX_DEPTH):]
+	key.depth = copy(key.calls[:], calls)
+	p.samples[key]++
+}
+
+// BusyWait returns the fraction of cycles spent busy-waiting.
+func (p *Profile) BusyWait() float64 {
+	if p.Cycles == 0 {
+		return 0
+	}
+
+	return float64(p.DelayWait+p.KeyWait) / float64(p.Cycles)
+}
+
+// name returns the name of the subroutine at addr.
+func (p *Profile) name(addr uint16) string {
+	if p.Name != nil {
+		if name := p.Name(addr); name != "" {
+			return name
+		}
+	}
+
+	if addr == emulator.ROM_START {
+		return "main"
+	}
+	return fmt.Sprintf("sub_%03X", addr)
+}
@@ -0,0 +1,138 @@
+package profile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/stretchr/testify/assert"
+
+	pprof "github.com/google/pprof/profile"
+)
+
+var rom = []byte{
+	0x22, 0x0E, // 0x200 CALL 0x20E
+	0x60, 0x02, // 0x202 LD V0, 2
+	0xF0, 0x15, // 0x204 LD DT, V0
+	0xF0, 0x07, // 0x206 LD V0, DT
+	0x30, 0x00, // 0x208 SE V0, 0
+	0x12, 0x06, // 0x20A JMP 0x206
+	0x12, 0x00, // 0x20C JMP 0x200
+	0x22, 0x12, // 0x20E CALL 0x212
+	0x00, 0xEE, // 0x210 RET
+	0x00, 0xEE, // 0x212 RET
+}
+
+func run(t *testing.T, frames int) *Profile {
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Run(emu, frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProfile(t *testing.T) {
+	assert := assert.New(t)
+	p := run(t, 3)
+
+	assert.Equal(uint64(3), p.Frames)
+	assert.Equal(uint64(3*emulator.DEFAULT_IPF), p.Cycles)
+	assert.Equal(uint64(2), p.PCs[0x20E])
+	assert.Equal(uint16(0x220E), p.Opcodes[0x200])
+	assert.Equal(uint64(4), p.Classes["RET"])
+
+	main := p.Subroutines[emulator.ROM_START]
+	assert.Equal(uint64(1), main.Calls)
+	assert.Equal(p.Cycles, main.Inclusive)
+
+	// Each call to 0x20E runs CALL and RET, and 0x212 runs RET
+	outer, inner := p.Subroutines[0x20E], p.Subroutines[0x212]
+	assert.Equal(Subroutine{Addr: 0x20E, Calls: 2, Exclusive: 4, Inclusive: 6}, *outer)
+	assert.Equal(Subroutine{Addr: 0x212, Calls: 2, Exclusive: 2, Inclusive: 2}, *inner)
+	assert.Equal(p.Cycles, main.Exclusive+outer.Exclusive+inner.Exclusive)
+}
+
+func TestBusyWait(t *testing.T) {
+	assert := assert.New(t)
+	p := run(t, 3)
+
+	// Waiting starts when LD V0, DT runs a second time in a frame. The first
+	// frame runs 6 instructions before the loop and 3 in its first pass. The
+	// second begins 2 instructions before the end of the loop. The third
+	// finishes the loop, leaves it when DT reaches 0 and runs 10 instructions
+	// before the second read.
+	assert.Equal(uint64(3), p.WaitFrames)
+	assert.Equal(uint64(0), p.KeyWait)
+	assert.Equal(p.Cycles-(6+3)-(2+3)-10, p.DelayWait)
+	assert.InDelta(0.98, p.BusyWait(), 0.01)
+}
+
+func TestWriteText(t *testing.T) {
+	assert := assert.New(t)
+	p := run(t, 3)
+	p.Name = func(addr uint16) string {
+		if addr == 0x212 {
+			return "draw"
+		}
+		return ""
+	}
+
+	var b strings.Builder
+	assert.NoError(p.WriteText(&b, 2))
+
+	report := b.String()
+	assert.Contains(report, "3 frames, 2100 cycles\n")
+	assert.Contains(report, "        2100  100.0%")
+	assert.Contains(report, "main\n")
+	assert.Contains(report, "sub_20E\n")
+	assert.Contains(report, "draw\n")
+	assert.Contains(report, "0x206  F007  LDVxDT V0\n")
+
+	_, addresses, _ := strings.Cut(report, "addresses:\n")
+	assert.Equal(3, strings.Count(addresses, "\n"), "header and the top 2 addresses")
+}
+
+func TestPprof(t *testing.T) {
+	assert := assert.New(t)
+	p := run(t, 3)
+
+	var b bytes.Buffer
+	assert.NoError(p.WritePprof(&b, "test.ch8"))
+
+	prof, err := pprof.Parse(&b)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(prof.CheckValid())
+
+	var total int64
+	for _, s := range prof.Sample {
+		total += s.Value[0]
+	}
+	assert.Equal(int64(p.Cycles), total)
+
+	var names []string
+	for _, fn := range prof.Function {
+		names = append(names, fn.Name)
+	}
+	assert.ElementsMatch([]string{"main", "sub_20E", "sub_212"}, names)
+
+	// RET in 0x212, called from 0x20E, called from main
+	var deepest *pprof.Sample
+	for _, s := range prof.Sample {
+		if deepest == nil || len(s.Location) > len(deepest.Location) {
+			deepest = s
+		}
+	}
+	var stack []uint64
+	for _, loc := range deepest.Location {
+		stack = append(stack, loc.Address)
+	}
+	assert.Equal([]uint64{0x212, 0x20E, 0x200}, stack)
+}
@@ -0,0 +1,96 @@
+// Package record captures the frames a ROM runs, as animated GIFs or as raw
+// video streams for other tools such as ffmpeg to encode.
+//
+// Frames are drawn with the screenshot package, so recordings are enlarged
+// and coloured the same way as screenshots.
+package record
+
+import (
+	"errors"
+	"image/gif"
+	"io"
+	"slices"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+)
+
+// Frames per second that recordings are timed by
+const FRAME_RATE = 60
+
+// Shortest delay between GIF frames, in hundredths of a second. Browsers and
+// most viewers play shorter delays as 10.
+const MIN_DELAY = 2
+
+// GIF collects frames for an animated GIF. Runs of identical frames are kept
+// once and shown for as long as the whole run.
+type GIF struct {
+	opts screenshot.Options
+
+	// The distinct frames, and the frame each was first shown in
+	frames []emulator.Bitplane
+	starts []uint64
+
+	// The frame after the last one added
+	end uint64
+}
+
+// NewGIF starts an empty GIF drawn as set by opts.
+func NewGIF(opts screenshot.Options) *GIF {
+	return &GIF{opts: opts}
+}
+
+// Add adds the display at the end of a frame. Frames must be added in order,
+// and a gap between them holds the last frame added.
+func (g *GIF) Add(frame uint64, display emulator.Bitplane) {
+	last := len(g.frames) - 1
+	if last < 0 || !slices.Equal(g.frames[last].Bits, display.Bits) || g.frames[last].Width != display.Width {
+		g.frames = append(g.frames, display.Clone())
+		g.starts = append(g.starts, frame)
+	}
+	g.end = frame + 1
+}
+
+// Len returns the number of distinct frames added.
+func (g *GIF) Len() int {
+	return len(g.frames)
+}
+
+// centiseconds returns the time from the first frame until a frame is shown,
+// rounded to the hundredths of a second GIFs are timed in. Rounding the time
+// rather than each delay keeps them from drifting.
+func (g *GIF) centiseconds(frame uint64) int {
+	return int(((frame-g.starts[0])*100 + FRAME_RATE/2) / FRAME_RATE)
+}
+
+// Encode writes the GIF to w, looping forever.
+func (g *GIF) Encode(w io.Writer) error {
+	if len(g.frames) == 0 {
+		return errors.New("no frames recorded")
+	}
+
+	// Frames that would be shown for less than MIN_DELAY are merged into the
+	// next one, which is shown from when they would have started
+	anim := &gif.GIF{}
+	shown := 0
+	for idx, display := range g.frames {
+		next := g.end
+		if idx+1 < len(g.frames) {
+			next = g.starts[idx+1]
+		}
+
+		delay := g.centiseconds(next) - shown
+		if delay < MIN_DELAY {
+			if idx+1 < len(g.frames) {
+				continue
+			}
+			delay = MIN_DELAY
+		}
+
+		anim.Image = append(anim.Image, screenshot.Image(display, g.opts))
+		anim.Delay = append(anim.Delay, delay)
+		shown += delay
+	}
+
+	return gif.EncodeAll(w, anim)
+}
@@ -0,0 +1,83 @@
+package record
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+	"github.com/stretchr/testify/assert"
+)
+
+// lit returns a display with only the pixel at (x, 0) lit.
+func lit(x int) emulator.Bitplane {
+	b := emulator.NewBitplane(emulator.DISPLAY_WIDTH, emulator.DISPLAY_HEIGHT)
+	b.Set(x, 0, true)
+	return b
+}
+
+func TestGIF(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := screenshot.Options{Scale: 2, Palette: screenshot.Palettes["green"]}
+	g := NewGIF(opts)
+	for frame, x := range []int{0, 0, 0, 1, 1, 0} {
+		g.Add(uint64(frame+10), lit(x))
+	}
+	assert.Equal(3, g.Len())
+
+	var buf bytes.Buffer
+	assert.NoError(g.Encode(&buf))
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(anim.Image, 3)
+
+	// Six frames at 60Hz last 10cs, split at the nearest hundredth to when
+	// each one starts
+	assert.Equal([]int{5, 3, 2}, anim.Delay)
+
+	img := anim.Image[1]
+	assert.Equal(2*emulator.DISPLAY_WIDTH, img.Bounds().Dx())
+	assert.Equal(opts.Palette.On, img.At(2, 1))
+	assert.Equal(opts.Palette.Off, img.At(0, 0))
+}
+
+func TestGIFTiming(t *testing.T) {
+	assert := assert.New(t)
+
+	// A second of frames that all differ lasts a second, whatever the
+	// rounding of each one
+	g := NewGIF(screenshot.Options{})
+	for frame := range FRAME_RATE {
+		g.Add(uint64(frame), lit(frame))
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(g.Encode(&buf))
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames too short for viewers to honour are merged with the next one, so
+	// delays alternate evenly between 2cs and 3cs
+	total := 0
+	for _, delay := range anim.Delay {
+		assert.Contains([]int{2, 3}, delay)
+		total += delay
+	}
+	assert.Equal(100, total)
+	assert.Equal([]int{2, 3, 2, 3, 2, 3}, anim.Delay[:6])
+	assert.Len(anim.Image, 40)
+}
+
+func TestGIFEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.ErrorContains(NewGIF(screenshot.Options{}).Encode(&buf), "no frames recorded")
+}
@@ -0,0 +1,115 @@
+package record
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+)
+
+// Format is a raw video format.
+type Format uint8
+
+const (
+	// Binary PPM images one after the other, for ffmpeg's image2pipe
+	PPM Format = iota
+
+	// YUV4MPEG2 with full range 4:4:4 colour, which keeps the palette's
+	// colours exact and carries the frame rate
+	Y4M
+)
+
+// Formats names the formats.
+var Formats = map[string]Format{
+	"ppm": PPM,
+	"y4m": Y4M,
+}
+
+func (f Format) String() string {
+	for name, format := range Formats {
+		if format == f {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Format(%d)", uint8(f))
+}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	f, ok := Formats[strings.ToLower(name)]
+	if !ok {
+		return f, fmt.Errorf("unknown stream format %q, expected ppm or y4m", name)
+	}
+
+	return f, nil
+}
+
+// Stream writes every frame to a writer as raw video, without leaving any
+// out.
+type Stream struct {
+	w      io.Writer
+	format Format
+	opts   screenshot.Options
+
+	// Size of the frames, set by the first one
+	width, height int
+
+	buf []byte
+}
+
+// NewStream returns a stream of frames drawn as set by opts, written to w in
+// format.
+func NewStream(w io.Writer, format Format, opts screenshot.Options) *Stream {
+	return &Stream{w: w, format: format, opts: opts}
+}
+
+// Write writes the display at the end of a frame. Every frame must be the
+// same size.
+func (s *Stream) Write(display emulator.Bitplane) error {
+	img := screenshot.Image(display, s.opts)
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	if s.width == 0 {
+		s.width, s.height = width, height
+		if s.format == Y4M {
+			header := fmt.Sprintf("YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C444 XCOLORRANGE=FULL\n", width, height, FRAME_RATE)
+			if _, err := io.WriteString(s.w, header); err != nil {
+				return err
+			}
+		}
+	} else if width != s.width || height != s.height {
+		return fmt.Errorf("frame size changed from %dx%d to %dx%d", s.width, s.height, width, height)
+	}
+
+	s.buf = s.buf[:0]
+	switch s.format {
+	case PPM:
+		s.buf = fmt.Appendf(s.buf, "P6\n%d %d\n255\n", width, height)
+		for _, idx := range img.Pix {
+			c := img.Palette[idx].(color.RGBA)
+			s.buf = append(s.buf, c.R, c.G, c.B)
+		}
+
+	case Y4M:
+		// The palette in Y, Cb and Cr, and each plane of the frame in turn
+		var planes [3][2]uint8
+		for idx, c := range img.Palette {
+			c := c.(color.RGBA)
+			planes[0][idx], planes[1][idx], planes[2][idx] = color.RGBToYCbCr(c.R, c.G, c.B)
+		}
+
+		s.buf = append(s.buf, "FRAME\n"...)
+		for _, plane := range planes {
+			for _, idx := range img.Pix {
+				s.buf = append(s.buf, plane[idx])
+			}
+		}
+	}
+
+	_, err := s.w.Write(s.buf)
+	return err
+}
@@ -0,0 +1,84 @@
+package record
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/screenshot"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPPM(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	opts := screenshot.Options{Palette: screenshot.Palettes["amber"]}
+	s := NewStream(&buf, PPM, opts)
+	for x := range 2 {
+		assert.NoError(s.Write(lit(x)))
+	}
+
+	// Every frame is a whole image of its own
+	header := fmt.Sprintf("P6\n%d %d\n255\n", emulator.DISPLAY_WIDTH, emulator.DISPLAY_HEIGHT)
+	size := len(header) + 3*emulator.DISPLAY_WIDTH*emulator.DISPLAY_HEIGHT
+	data := buf.Bytes()
+	assert.Len(data, 2*size)
+
+	for frame := range 2 {
+		image := data[frame*size:]
+		assert.Equal(header, string(image[:len(header)]))
+
+		pixels := image[len(header):]
+		on, off := opts.Palette.On, opts.Palette.Off
+		for x := range 2 {
+			want := []byte{off.R, off.G, off.B}
+			if x == frame {
+				want = []byte{on.R, on.G, on.B}
+			}
+			assert.Equal(want, pixels[3*x:3*x+3], "frame %d pixel %d", frame, x)
+		}
+	}
+}
+
+func TestStreamY4M(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	s := NewStream(&buf, Y4M, screenshot.Options{Scale: 2})
+	for x := range 2 {
+		assert.NoError(s.Write(lit(x)))
+	}
+
+	// One header, then each frame's Y, Cb and Cr planes
+	width, height := 2*emulator.DISPLAY_WIDTH, 2*emulator.DISPLAY_HEIGHT
+	header := fmt.Sprintf("YUV4MPEG2 W%d H%d F60:1 Ip A1:1 C444 XCOLORRANGE=FULL\n", width, height)
+	frameSize := len("FRAME\n") + 3*width*height
+	data := buf.Bytes()
+	assert.Len(data, len(header)+2*frameSize)
+	assert.Equal(header, string(data[:len(header)]))
+
+	frame := data[len(header)+frameSize:]
+	assert.Equal("FRAME\n", string(frame[:6]))
+
+	// White and black are full brightness and none, with no colour
+	y, cb := frame[6:6+width*height], frame[6+width*height:]
+	assert.Equal([]byte{0x00, 0x00, 0xFF, 0xFF, 0x00}, y[:5])
+	assert.Equal([]byte{0x80, 0x80, 0x80}, cb[:3])
+
+	// Frames can't change size partway through
+	assert.ErrorContains(s.Write(emulator.NewBitplane(128, 64)), "frame size changed")
+}
+
+func TestParseFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := ParseFormat("Y4M")
+	assert.NoError(err)
+	assert.Equal(Y4M, f)
+	assert.Equal("y4m", f.String())
+
+	_, err = ParseFormat("mp4")
+	assert.ErrorContains(err, `unknown stream format "mp4"`)
+}
@@ -0,0 +1,80 @@
+package screenshot
+
+import (
+	"fmt"
+	"image/color"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Palette colours the display.
+type Palette struct {
+	Off, On color.RGBA
+}
+
+// Palettes are the built-in palettes, by name.
+var Palettes = map[string]Palette{
+	// White on black, as the window draws it by default
+	"classic": {Off: rgb(0x000000), On: rgb(0xFFFFFF)},
+
+	// Phosphor screens
+	"amber": {Off: rgb(0x1A0F00), On: rgb(0xFFB000)},
+	"green": {Off: rgb(0x001A00), On: rgb(0x33FF33)},
+
+	// Octo's default colours
+	"octo": {Off: rgb(0x996600), On: rgb(0xFFCC00)},
+
+	// The HP 48's LCD, matching its shader
+	"hp48": {Off: rgb(0xB0BF9E), On: rgb(0x29332B)},
+}
+
+// DefaultPalette is the palette used unless another is chosen.
+var DefaultPalette = Palettes["classic"]
+
+func rgb(hex uint32) color.RGBA {
+	return color.RGBA{R: uint8(hex >> 16), G: uint8(hex >> 8), B: uint8(hex), A: 0xFF}
+}
+
+// PaletteNames returns the names of the built-in palettes.
+func PaletteNames() []string {
+	return slices.Sorted(maps.Keys(Palettes))
+}
+
+// ParsePalette returns the built-in palette with the given name, or reads a
+// palette given as the hexadecimal colours of unlit and lit pixels, such as
+// "000000,ffffff".
+func ParsePalette(s string) (Palette, error) {
+	if p, ok := Palettes[strings.ToLower(s)]; ok {
+		return p, nil
+	}
+
+	off, on, ok := strings.Cut(s, ",")
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown palette %q, expected %s or two colours such as 000000,ffffff", s, strings.Join(PaletteNames(), ", "))
+	}
+
+	var p Palette
+	var err error
+	if p.Off, err = parseColour(off); err != nil {
+		return p, fmt.Errorf("palette %q: %w", s, err)
+	}
+	if p.On, err = parseColour(on); err != nil {
+		return p, fmt.Errorf("palette %q: %w", s, err)
+	}
+
+	return p, nil
+}
+
+// parseColour reads a colour given as six hexadecimal digits, with or
+// without a leading #.
+func parseColour(s string) (color.RGBA, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	hex, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+
+	return rgb(uint32(hex)), nil
+}
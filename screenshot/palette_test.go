@@ -0,0 +1,29 @@
+package screenshot
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePalette(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := ParsePalette("Amber")
+	assert.NoError(err)
+	assert.Equal(Palettes["amber"], p)
+
+	p, err = ParsePalette("#102030, a0b0c0")
+	assert.NoError(err)
+	assert.Equal(Palette{
+		Off: color.RGBA{0x10, 0x20, 0x30, 0xFF},
+		On:  color.RGBA{0xA0, 0xB0, 0xC0, 0xFF},
+	}, p)
+
+	_, err = ParsePalette("sepia")
+	assert.ErrorContains(err, `unknown palette "sepia"`)
+
+	_, err = ParsePalette("000000,fff")
+	assert.ErrorContains(err, `invalid colour "fff"`)
+}
@@ -0,0 +1,173 @@
+// Package screenshot encodes the display as PNG images, enlarged with the
+// upscale package and coloured with a palette.
+//
+// Screenshots carry the name and SHA-256 hash of the ROM and the frame they
+// were taken in as PNG text chunks, so that one attached to a bug report can
+// be traced back to the ROM and the moment in it.
+package screenshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/upscale"
+)
+
+// Options controls how screenshots are drawn and labelled.
+type Options struct {
+	// Times to enlarge the display, and the filter to do it with
+	Scale  int
+	Filter upscale.Filter
+
+	// DefaultPalette if unset
+	Palette Palette
+
+	// Name of the ROM, the SHA-256 hash of its contents and the frame the
+	// screenshot was taken in, written as text chunks. Empty names and
+	// hashes are left out.
+	ROM   string
+	Hash  string
+	Frame uint64
+}
+
+// Image draws b enlarged and coloured as set by opts. Unlit pixels are index
+// 0 of its palette and lit ones index 1.
+func Image(b emulator.Bitplane, opts Options) *image.Paletted {
+	b = upscale.Scale(b, opts.Filter, opts.Scale)
+	if opts.Palette == (Palette{}) {
+		opts.Palette = DefaultPalette
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, b.Width, b.Height), color.Palette{opts.Palette.Off, opts.Palette.On})
+	for y := range b.Height {
+		row := img.Pix[y*img.Stride:]
+		for x := range b.Width {
+			if b.Pixel(x, y) {
+				row[x] = 1
+			}
+		}
+	}
+
+	return img
+}
+
+// Encode writes b to w as a PNG image drawn as set by opts.
+func Encode(w io.Writer, b emulator.Bitplane, opts Options) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Image(b, opts)); err != nil {
+		return err
+	}
+
+	// Text chunks go after the header, which is always the first chunk
+	data := buf.Bytes()
+	header := len(pngSignature) + 12 + int(binary.BigEndian.Uint32(data[len(pngSignature):]))
+	if _, err := w.Write(data[:header]); err != nil {
+		return err
+	}
+
+	for _, text := range [][2]string{
+		{"Software", "gr8"},
+		{"ROM", opts.ROM},
+		{"ROM SHA-256", opts.Hash},
+		{"Frame", strconv.FormatUint(opts.Frame, 10)},
+	} {
+		if text[1] == "" {
+			continue
+		}
+		if err := writeText(w, text[0], text[1]); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(data[header:])
+	return err
+}
+
+// The bytes every PNG file starts with
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// writeText writes a tEXt chunk holding a keyword and its text.
+func writeText(w io.Writer, keyword, text string) error {
+	data := append(append([]byte(keyword), 0), text...)
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	_, err := w.Write(chunk)
+	return err
+}
+
+// Text reads the text chunks of a PNG image, by keyword.
+func Text(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return nil, fmt.Errorf("not a PNG image")
+	}
+
+	text := map[string]string{}
+	for data = data[len(pngSignature):]; len(data) > 0; {
+		if len(data) < 12 || int(binary.BigEndian.Uint32(data)) > len(data)-12 {
+			return nil, fmt.Errorf("truncated PNG image")
+		}
+		size := int(binary.BigEndian.Uint32(data))
+
+		if string(data[4:8]) == "tEXt" {
+			keyword, value, _ := strings.Cut(string(data[8:8+size]), "\x00")
+			text[keyword] = value
+		}
+		data = data[12+size:]
+	}
+
+	return text, nil
+}
+
+// Filename names a file of a ROM made at time t in the given frame, such as a
+// screenshot with the extension ".png", after the ROM's file name without its
+// extension.
+func Filename(rom string, t time.Time, frame uint64, ext string) string {
+	base := filepath.Base(rom)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	return fmt.Sprintf("%s-%s-f%d%s", base, t.Format("20060102-150405.000"), frame, ext)
+}
+
+// Save writes a screenshot to dir, named by Filename, and returns its path.
+// It never overwrites a file: if the name is taken, a number is added to it.
+func Save(dir string, display emulator.Bitplane, opts Options) (string, error) {
+	name := Filename(opts.ROM, time.Now(), opts.Frame, "")
+
+	path := filepath.Join(dir, name+".png")
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	for n := 2; errors.Is(err, fs.ErrExist); n++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.png", name, n))
+		fd, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = Encode(fd, display, opts)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	return path, err
+}
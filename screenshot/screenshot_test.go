@@ -0,0 +1,122 @@
+package screenshot
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/upscale"
+	"github.com/stretchr/testify/assert"
+)
+
+// checker returns a bitplane with every other pixel lit.
+func checker(width, height int) emulator.Bitplane {
+	b := emulator.NewBitplane(width, height)
+	for y := range height {
+		for x := range width {
+			b.Set(x, y, (x+y)%2 == 0)
+		}
+	}
+
+	return b
+}
+
+func TestEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	b := checker(emulator.DISPLAY_WIDTH, emulator.DISPLAY_HEIGHT)
+	opts := Options{
+		Scale:   3,
+		Filter:  upscale.Nearest,
+		Palette: Palettes["amber"],
+		ROM:     "pong.ch8",
+		Hash:    "abc123",
+		Frame:   600,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(Encode(&buf, b, opts))
+
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(3*emulator.DISPLAY_WIDTH, img.Bounds().Dx())
+	assert.Equal(3*emulator.DISPLAY_HEIGHT, img.Bounds().Dy())
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			want := opts.Palette.Off
+			if b.Pixel(x/3, y/3) {
+				want = opts.Palette.On
+			}
+			if !assert.Equal(color.RGBAModel.Convert(want), color.RGBAModel.Convert(img.At(x, y)), "(%d, %d)", x, y) {
+				return
+			}
+		}
+	}
+
+	text, err := Text(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"Software":    "gr8",
+		"ROM":         "pong.ch8",
+		"ROM SHA-256": "abc123",
+		"Frame":       "600",
+	}, text)
+}
+
+func TestImage(t *testing.T) {
+	assert := assert.New(t)
+
+	// Images are drawn in the default palette unless told otherwise
+	img := Image(checker(4, 2), Options{})
+	assert.Equal(4, img.Bounds().Dx())
+	assert.Equal(color.Palette{DefaultPalette.Off, DefaultPalette.On}, img.Palette)
+	assert.Equal([]uint8{1, 0, 1, 0, 0, 1, 0, 1}, img.Pix)
+}
+
+func TestText(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Text(bytes.NewReader([]byte("GIF89a")))
+	assert.ErrorContains(err, "not a PNG image")
+
+	var buf bytes.Buffer
+	assert.NoError(Encode(&buf, checker(8, 8), Options{}))
+	_, err = Text(bytes.NewReader(buf.Bytes()[:40]))
+	assert.ErrorContains(err, "truncated")
+}
+
+func TestFilename(t *testing.T) {
+	assert := assert.New(t)
+
+	at := time.Date(2025, 3, 14, 15, 9, 26, 535_000_000, time.UTC)
+	assert.Equal("pong-20250314-150926.535-f60.png", Filename("/roms/pong.ch8", at, 60, ".png"))
+	assert.Equal("game-20250314-150926.535-f0.gif", Filename("game", at, 0, ".gif"))
+}
+
+func TestSave(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	// Screenshots taken in a row, even of the same frame, get files of their
+	// own
+	var paths []string
+	for _, frame := range []uint64{60, 61, 61} {
+		path, err := Save(dir, checker(8, 8), Options{ROM: "pong.ch8", Frame: frame})
+		assert.NoError(err)
+		assert.FileExists(path)
+		paths = append(paths, path)
+	}
+	assert.Contains(paths[0], "-f60.png")
+	assert.Contains(paths[1], "-f61")
+	assert.NotEqual(paths[1], paths[2])
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(entries, 3)
+}
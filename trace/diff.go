@@ -0,0 +1,110 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Fields compared by Diff, in the order they are reported. Mnemonics aren't
+// compared, since emulators disassemble differently.
+var Fields = []string{
+	"frame", "cycle", "pc", "op",
+	"v0", "v1", "v2", "v3", "v4", "v5", "v6", "v7",
+	"v8", "v9", "va", "vb", "vc", "vd", "ve", "vf",
+	"i", "sp", "dt", "st",
+}
+
+// Divergence is the first point where two traces differ.
+type Divergence struct {
+	// Index of the differing records, counting from 0
+	Index int
+
+	// Line numbers of the records in each trace
+	LineA, LineB int
+
+	// The differing records. One is nil if its trace ended first.
+	A, B *Record
+
+	// The last records that matched, if any
+	Prev *Record
+
+	// Names of the fields that differ, from Fields
+	Fields []string
+}
+
+// Diff compares two traces record by record, ignoring the named fields, and
+// returns the first divergence, or nil if they match.
+func Diff(a, b io.Reader, ignore ...string) (*Divergence, error) {
+	for _, field := range ignore {
+		if !slices.Contains(Fields, field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+
+	ra, rb := NewReader(a), NewReader(b)
+	var prev *Record
+
+	for idx := 0; ; idx++ {
+		recA, errA := ra.Next()
+		if errA != nil && !errors.Is(errA, io.EOF) {
+			return nil, fmt.Errorf("first trace: %w", errA)
+		}
+		recB, errB := rb.Next()
+		if errB != nil && !errors.Is(errB, io.EOF) {
+			return nil, fmt.Errorf("second trace: %w", errB)
+		}
+
+		endA, endB := errA != nil, errB != nil
+		if endA && endB {
+			return nil, nil
+		}
+
+		d := &Divergence{Index: idx, LineA: ra.Line(), LineB: rb.Line(), Prev: prev}
+		if !endA {
+			d.A = &recA
+		}
+		if !endB {
+			d.B = &recB
+		}
+		if endA || endB {
+			return d, nil
+		}
+
+		for _, field := range Compare(recA, recB) {
+			if !slices.Contains(ignore, field) {
+				d.Fields = append(d.Fields, field)
+			}
+		}
+		if len(d.Fields) > 0 {
+			return d, nil
+		}
+
+		prev = &recA
+	}
+}
+
+// Compare returns the names of the fields that differ between a and b.
+func Compare(a, b Record) []string {
+	var fields []string
+	add := func(differ bool, name string) {
+		if differ {
+			fields = append(fields, name)
+		}
+	}
+
+	add(a.Frame != b.Frame, "frame")
+	add(a.Cycle != b.Cycle, "cycle")
+	add(a.PC != b.PC, "pc")
+	add(a.Opcode != b.Opcode, "op")
+	for reg := range a.V {
+		add(a.V[reg] != b.V[reg], fmt.Sprintf("v%x", reg))
+	}
+	add(a.I != b.I, "i")
+	add(a.SP != b.SP, "sp")
+	add(a.DT != b.DT, "dt")
+	add(a.ST != b.ST, "st")
+
+	return fields
+}
@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// traceOf returns a trace of records in the text format.
+func traceOf(recs ...Record) *strings.Reader {
+	var b strings.Builder
+	for _, r := range recs {
+		b.WriteString(r.String() + "\n")
+	}
+
+	return strings.NewReader(b.String())
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	first := Record{Frame: 1, PC: 0x200, Opcode: 0x6005}
+	second := Record{Frame: 1, Cycle: 1, PC: 0x202, Opcode: 0x7301, V: [16]byte{5}}
+	changed := second
+	changed.V[3] = 1
+	changed.Mnemonic = "add v3, 1"
+
+	d, err := Diff(traceOf(first, second), traceOf(first, second))
+	assert.Nil(err)
+	assert.Nil(d)
+
+	d, err = Diff(traceOf(first, second), traceOf(first, changed))
+	assert.Nil(err)
+	assert.Equal(1, d.Index)
+	assert.Equal(2, d.LineA)
+	assert.Equal(&first, d.Prev)
+	assert.Equal([]string{"v3"}, d.Fields)
+
+	d, err = Diff(traceOf(first, second), traceOf(first, changed), "v3")
+	assert.Nil(err)
+	assert.Nil(d)
+
+	// One trace ending first
+	d, err = Diff(traceOf(first, second), traceOf(first))
+	assert.Nil(err)
+	assert.Equal(1, d.Index)
+	assert.NotNil(d.A)
+	assert.Nil(d.B)
+
+	// Formats can be mixed
+	d, err = Diff(traceOf(first), strings.NewReader(`{"frame":1,"cycle":0,"pc":512,"opcode":24581,"v":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"i":0,"sp":0,"dt":0,"st":0}`))
+	assert.Nil(err)
+	assert.Nil(d)
+
+	_, err = Diff(traceOf(first), traceOf(first), "nope")
+	assert.NotNil(err)
+
+	_, err = Diff(strings.NewReader("garbage"), traceOf(first))
+	assert.ErrorContains(err, "first trace: line 1")
+}
@@ -0,0 +1,133 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader reads records from a trace file in either format. Blank lines are
+// skipped, and the format is detected line by line.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewReader returns a Reader that reads records from r.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	return &Reader{s: s}
+}
+
+// Line returns the line number of the last record read, counting from 1.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Next returns the next record, or io.EOF at the end of the trace.
+func (r *Reader) Next() (Record, error) {
+	for r.s.Scan() {
+		r.line++
+
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" {
+			continue
+		}
+
+		rec, err := Parse(line)
+		if err != nil {
+			return rec, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return rec, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// Parse parses a record in the text or JSON Lines format.
+func Parse(line string) (Record, error) {
+	var r Record
+	if strings.HasPrefix(line, "{") {
+		err := json.Unmarshal([]byte(line), &r)
+		return r, err
+	}
+
+	fields := strings.Fields(line)
+	seen := map[string]bool{}
+	for idx, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			// The rest of the line is the mnemonic
+			r.Mnemonic = strings.Join(fields[idx:], " ")
+			break
+		}
+
+		if err := r.set(key, value); err != nil {
+			return r, err
+		}
+		seen[key] = true
+	}
+
+	for _, key := range []string{"frame", "cycle", "pc", "op", "v", "i", "sp", "dt", "st"} {
+		if !seen[key] {
+			return r, fmt.Errorf("missing field %q", key)
+		}
+	}
+
+	return r, nil
+}
+
+// set parses a single field of the text format.
+func (r *Record) set(key, value string) error {
+	var err error
+	var n uint64
+
+	switch key {
+	case "frame":
+		r.Frame, err = strconv.ParseUint(value, 10, 64)
+	case "cycle":
+		r.Cycle, err = strconv.ParseUint(value, 10, 64)
+	case "pc", "op", "i":
+		n, err = strconv.ParseUint(value, 16, 16)
+		switch key {
+		case "pc":
+			r.PC = uint16(n)
+		case "op":
+			r.Opcode = uint16(n)
+		default:
+			r.I = uint16(n)
+		}
+	case "v":
+		var v []byte
+		v, err = hex.DecodeString(value)
+		if err == nil && len(v) != len(r.V) {
+			err = fmt.Errorf("expected %d registers, got %d", len(r.V), len(v))
+		}
+		copy(r.V[:], v)
+	case "sp":
+		r.SP, err = strconv.Atoi(value)
+	case "dt", "st":
+		n, err = strconv.ParseUint(value, 16, 8)
+		if key == "dt" {
+			r.DT = byte(n)
+		} else {
+			r.ST = byte(n)
+		}
+	default:
+		return fmt.Errorf("unknown field %q", key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("field %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package trace logs the instructions an emulator executes, for comparing
+// gr8 with other emulators.
+//
+// Every record holds the machine state just before an instruction runs. The
+// text format puts each record on one line of key=value fields in a fixed
+// order, followed by the disassembled instruction:
+//
+//	frame=1 cycle=0 pc=200 op=00E0 v=00000000000000000000000000000000 i=000 sp=0 dt=00 st=00 CLS
+//
+// V0-VF are printed together as 32 hexadecimal digits. The JSON Lines format
+// holds the same fields as numbers, one object per line.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aricodes-oss/gr8/disasm"
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Record is the state of the machine before an instruction runs.
+type Record struct {
+	// Frame the instruction ran in, counting from 1
+	Frame uint64
+
+	// Instructions run before this one, across all frames
+	Cycle uint64
+
+	PC       uint16
+	Opcode   uint16
+	Mnemonic string
+
+	V  [16]byte
+	I  uint16
+	SP int
+	DT byte
+	ST byte
+}
+
+// String returns the record in the text format, without a newline.
+func (r Record) String() string {
+	var v strings.Builder
+	for _, reg := range r.V {
+		fmt.Fprintf(&v, "%02X", reg)
+	}
+
+	return fmt.Sprintf(
+		"frame=%d cycle=%d pc=%03X op=%04X v=%s i=%03X sp=%d dt=%02X st=%02X %s",
+		r.Frame, r.Cycle, r.PC, r.Opcode, v.String(), r.I, r.SP, r.DT, r.ST, r.Mnemonic,
+	)
+}
+
+// jsonRecord is the JSON Lines form of a record. V0-VF are an array rather
+// than the base64 string encoding/json makes of byte arrays.
+type jsonRecord struct {
+	Frame    uint64  `json:"frame"`
+	Cycle    uint64  `json:"cycle"`
+	PC       uint16  `json:"pc"`
+	Opcode   uint16  `json:"opcode"`
+	Mnemonic string  `json:"mnemonic"`
+	V        [16]int `json:"v"`
+	I        uint16  `json:"i"`
+	SP       int     `json:"sp"`
+	DT       byte    `json:"dt"`
+	ST       byte    `json:"st"`
+}
+
+// MarshalJSON encodes the record as a JSON object.
+func (r Record) MarshalJSON() ([]byte, error) {
+	j := jsonRecord{
+		Frame:    r.Frame,
+		Cycle:    r.Cycle,
+		PC:       r.PC,
+		Opcode:   r.Opcode,
+		Mnemonic: r.Mnemonic,
+		I:        r.I,
+		SP:       r.SP,
+		DT:       r.DT,
+		ST:       r.ST,
+	}
+	for idx, reg := range r.V {
+		j.V[idx] = int(reg)
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a record from a JSON object.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var j jsonRecord
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*r = Record{
+		Frame:    j.Frame,
+		Cycle:    j.Cycle,
+		PC:       j.PC,
+		Opcode:   j.Opcode,
+		Mnemonic: j.Mnemonic,
+		I:        j.I,
+		SP:       j.SP,
+		DT:       j.DT,
+		ST:       j.ST,
+	}
+	for idx, reg := range j.V {
+		if reg < 0 || reg > 0xFF {
+			return fmt.Errorf("V%X value %d out of range", idx, reg)
+		}
+		r.V[idx] = byte(reg)
+	}
+
+	return nil
+}
+
+// Format is a trace file format.
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSONL
+)
+
+// ParseFormat parses "text" or "jsonl".
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "text":
+		return FormatText, nil
+	case "jsonl":
+		return FormatJSONL, nil
+	}
+
+	return 0, fmt.Errorf("unknown trace format %q", s)
+}
+
+// -- Filters
+
+// Range is an inclusive range of addresses or frames.
+type Range struct {
+	Start, End uint64
+}
+
+func (r Range) contains(n uint64) bool {
+	return n >= r.Start && n <= r.End
+}
+
+// ParseRanges parses comma-separated ranges such as "200-2ff,300" in the
+// given base. Ranges with no end, such as "600-", run forever.
+func ParseRanges(s string, base int) ([]Range, error) {
+	var ranges []Range
+	for _, part := range strings.Split(s, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		first, err := strconv.ParseUint(start, base, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+
+		r := Range{Start: first, End: first}
+		if isRange && end == "" {
+			r.End = ^uint64(0)
+		} else if isRange {
+			r.End, err = strconv.ParseUint(end, base, 64)
+			if err != nil || r.End < r.Start {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+// Filter selects the instructions to trace. Empty lists don't filter.
+type Filter struct {
+	Addrs  []Range
+	Frames []Range
+}
+
+func matchRanges(ranges []Range, n uint64) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	for _, r := range ranges {
+		if r.contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether an instruction at pc in frame should be traced.
+func (f Filter) Match(pc uint16, frame uint64) bool {
+	return matchRanges(f.Addrs, uint64(pc)) && matchRanges(f.Frames, frame)
+}
+
+// -- Writing
+
+// Writer writes records to a trace file. It is safe to close from another
+// goroutine than the one writing.
+type Writer struct {
+	Filter Filter
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	format Format
+	err    error
+	closed bool
+}
+
+// NewWriter returns a Writer that writes records to w in format.
+func NewWriter(w io.Writer, format Format) *Writer {
+	return &Writer{w: bufio.NewWriter(w), format: format}
+}
+
+// Write writes r if it passes the filter. Writes after an error or Close
+// are dropped, and the first error is returned by every later call.
+func (w *Writer) Write(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil || w.closed || !w.Filter.Match(r.PC, r.Frame) {
+		return w.err
+	}
+
+	switch w.format {
+	case FormatJSONL:
+		var line []byte
+		line, w.err = json.Marshal(r)
+		if w.err == nil {
+			line = append(line, '\n')
+			_, w.err = w.w.Write(line)
+		}
+	default:
+		_, w.err = fmt.Fprintln(w.w, r)
+	}
+
+	return w.err
+}
+
+// Close flushes buffered records and returns the first error writing them.
+// It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed && w.err == nil {
+		w.err = w.w.Flush()
+	}
+	w.closed = true
+
+	return w.err
+}
+
+// Attach traces every instruction emu executes to w.
+func Attach(emu emulator.Emulator, w *Writer) {
+	var cycle uint64
+
+	emu.OnExecute(func(pc, opcode uint16) {
+		frame := emu.FrameCount()
+		r := Record{Frame: frame, Cycle: cycle, PC: pc, Opcode: opcode}
+		cycle++
+
+		// Skip building the record for instructions that won't be written
+		if !w.Filter.Match(pc, frame) {
+			return
+		}
+
+		s := emu.State()
+		r.Mnemonic = disasm.Decode(opcode).String()
+		r.V = s.V
+		r.I = s.I
+		r.SP = len(s.Stack)
+		r.DT = s.DelayTimer
+		r.ST = s.SoundTimer
+
+		w.Write(r)
+	})
+}
@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var record = Record{
+	Frame:    3,
+	Cycle:    1402,
+	PC:       0x2A4,
+	Opcode:   0x7301,
+	Mnemonic: "ADD V3, 0x01",
+	V:        [16]byte{0x00, 0x01, 0x02, 0x33, 15: 0xFF},
+	I:        0x300,
+	SP:       2,
+	DT:       0x3C,
+	ST:       0x01,
+}
+
+const recordLine = "frame=3 cycle=1402 pc=2A4 op=7301 v=000102330000000000000000000000FF i=300 sp=2 dt=3C st=01 ADD V3, 0x01"
+
+func TestRecordText(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(recordLine, record.String())
+
+	r, err := Parse(recordLine)
+	assert.Nil(err)
+	assert.Equal(record, r)
+
+	_, err = Parse("frame=3 cycle=1402 pc=2A4")
+	assert.NotNil(err)
+	_, err = Parse(strings.Replace(recordLine, "v=00", "v=0", 1))
+	assert.NotNil(err)
+	_, err = Parse(strings.Replace(recordLine, "sp=2", "sp=x", 1))
+	assert.NotNil(err)
+}
+
+func TestRecordJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatJSONL)
+	assert.Nil(w.Write(record))
+	assert.Nil(w.Close())
+
+	line := buf.String()
+	assert.True(strings.HasPrefix(line, `{"frame":3,"cycle":1402,"pc":676,"opcode":29441,"mnemonic":"ADD V3, 0x01","v":[0,1,2,51,`))
+	assert.True(strings.HasSuffix(line, "}\n"))
+
+	r, err := Parse(strings.TrimSpace(line))
+	assert.Nil(err)
+	assert.Equal(record, r)
+}
+
+func TestRanges(t *testing.T) {
+	assert := assert.New(t)
+
+	ranges, err := ParseRanges("200-2ff,300", 16)
+	assert.Nil(err)
+	assert.Equal([]Range{{0x200, 0x2FF}, {0x300, 0x300}}, ranges)
+
+	ranges, err = ParseRanges("600-", 10)
+	assert.Nil(err)
+	assert.Equal([]Range{{600, ^uint64(0)}}, ranges)
+
+	for _, bad := range []string{"", "x", "300-200", "1-2-3"} {
+		_, err = ParseRanges(bad, 16)
+		assert.NotNil(err, bad)
+	}
+
+	f := Filter{Addrs: []Range{{0x200, 0x2FF}}, Frames: []Range{{2, 3}}}
+	assert.True(f.Match(0x2A4, 2))
+	assert.False(f.Match(0x300, 2))
+	assert.False(f.Match(0x2A4, 4))
+	assert.True(Filter{}.Match(0xFFF, 100))
+}
+
+func TestAttach(t *testing.T) {
+	assert := assert.New(t)
+
+	// 0x200 LD V0, 5; 0x202 CALL 0x206; 0x204 JMP 0x204; 0x206 LD I, 0x300; 0x208 RET
+	rom := []byte{0x60, 0x05, 0x22, 0x06, 0x12, 0x04, 0xA3, 0x00, 0x00, 0xEE}
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(rom), emulator.DEFAULT_CLOCK_SPEED)
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatText)
+	w.Filter.Addrs = []Range{{0x202, 0x208}}
+	Attach(emu, w)
+
+	for range 6 {
+		assert.Nil(emu.Step())
+	}
+	assert.Nil(w.Close())
+
+	// Writes after closing are dropped
+	assert.Nil(emu.Step())
+
+	r := NewReader(&buf)
+	var recs []Record
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.Nil(err)
+		recs = append(recs, rec)
+	}
+
+	assert.Len(recs, 5)
+	assert.Equal(Record{Frame: 1, Cycle: 1, PC: 0x202, Opcode: 0x2206, Mnemonic: "CALL 0x206", V: [16]byte{5}}, recs[0])
+	assert.Equal(1, recs[2].SP)
+	assert.Equal(uint16(0x300), recs[3].I)
+	assert.Equal("JMP 0x204", recs[4].Mnemonic)
+	assert.Equal(uint64(5), recs[4].Cycle)
+}
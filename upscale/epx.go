@@ -0,0 +1,98 @@
+package upscale
+
+import "github.com/aricodes-oss/gr8/emulator"
+
+// epx scales b with Scale2x and Scale3x, as many times as factor allows.
+func epx(b emulator.Bitplane, factor int) emulator.Bitplane {
+	for factor > 1 {
+		switch {
+		case factor%2 == 0:
+			b = scale2x(b)
+			factor /= 2
+		case factor%3 == 0:
+			b = scale3x(b)
+			factor /= 3
+		default:
+			return nearest(b, factor)
+		}
+	}
+
+	return b
+}
+
+// scale2x doubles b. Each pixel E becomes four, and each of them takes the
+// colour of the two neighbours on its side when they match and the other two
+// don't:
+//
+//	  B         E0 E1
+//	D E F  =>   E2 E3
+//	  H
+func scale2x(b emulator.Bitplane) emulator.Bitplane {
+	out := emulator.NewBitplane(b.Width*2, b.Height*2)
+	for y := range b.Height {
+		for x := range b.Width {
+			B, D, E := at(b, x, y-1), at(b, x-1, y), at(b, x, y)
+			F, H := at(b, x+1, y), at(b, x, y+1)
+
+			e := [4]bool{E, E, E, E}
+			if B != H && D != F {
+				e[0] = cond(D == B, D, E)
+				e[1] = cond(B == F, F, E)
+				e[2] = cond(D == H, D, E)
+				e[3] = cond(H == F, F, E)
+			}
+
+			for idx, lit := range e {
+				out.Set(x*2+idx%2, y*2+idx/2, lit)
+			}
+		}
+	}
+
+	return out
+}
+
+// scale3x triples b. The corners of each pixel E follow the same rule as
+// Scale2x, and its edges take the colour of the neighbour beside them when
+// one of the corners next to them would and the corner pixel beyond doesn't
+// already match E:
+//
+//	A B C      E0 E1 E2
+//	D E F  =>  E3 E4 E5
+//	G H I      E6 E7 E8
+func scale3x(b emulator.Bitplane) emulator.Bitplane {
+	out := emulator.NewBitplane(b.Width*3, b.Height*3)
+	for y := range b.Height {
+		for x := range b.Width {
+			A, B, C := at(b, x-1, y-1), at(b, x, y-1), at(b, x+1, y-1)
+			D, E, F := at(b, x-1, y), at(b, x, y), at(b, x+1, y)
+			G, H, I := at(b, x-1, y+1), at(b, x, y+1), at(b, x+1, y+1)
+
+			e := [9]bool{E, E, E, E, E, E, E, E, E}
+			if B != H && D != F {
+				e[0] = cond(D == B, D, E)
+				e[1] = cond((D == B && E != C) || (B == F && E != A), B, E)
+				e[2] = cond(B == F, F, E)
+				e[3] = cond((D == B && E != G) || (D == H && E != A), D, E)
+				e[5] = cond((B == F && E != I) || (H == F && E != C), F, E)
+				e[6] = cond(D == H, D, E)
+				e[7] = cond((D == H && E != I) || (H == F && E != G), H, E)
+				e[8] = cond(H == F, F, E)
+			}
+
+			for idx, lit := range e {
+				out.Set(x*3+idx%3, y*3+idx/3, lit)
+			}
+		}
+	}
+
+	return out
+}
+
+// cond returns a if c is set, or b otherwise.
+func cond(c, a, b bool) bool {
+	if c {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,101 @@
+// Package upscale enlarges the display for screenshots and recordings.
+//
+// Besides repeating every pixel, it has two pixel art filters that smooth
+// the staircases along diagonal edges: EPX, as Scale2x and Scale3x, and a
+// take on xBR for one bit per pixel. Filters work on bitplanes and give
+// bitplanes back, so that the result can be coloured with any palette.
+package upscale
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aricodes-oss/gr8/emulator"
+)
+
+// Filter is a way of enlarging a bitplane.
+type Filter uint8
+
+const (
+	// Repeats every pixel
+	Nearest Filter = iota
+
+	// Andrea Mazzoleni's Scale2x and Scale3x, the EPX algorithm, which round
+	// off corners where two edges meet at a pixel
+	EPX
+
+	// Cuts the corners of pixels that lie across diagonal edges, found by
+	// weighing up the pixels around them like Hyllian's xBR
+	XBR
+)
+
+// Filters names the filters.
+var Filters = map[string]Filter{
+	"nearest": Nearest,
+	"epx":     EPX,
+	"xbr":     XBR,
+}
+
+func (f Filter) String() string {
+	for name, filter := range Filters {
+		if filter == f {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Filter(%d)", uint8(f))
+}
+
+// Parse returns the filter with the given name.
+func Parse(name string) (Filter, error) {
+	f, ok := Filters[strings.ToLower(name)]
+	if !ok {
+		return f, fmt.Errorf("unknown filter %q, expected nearest, epx or xbr", name)
+	}
+
+	return f, nil
+}
+
+// Scale returns b enlarged factor times in each direction with filter. EPX
+// works in steps of 2x and 3x, so factors that aren't made of them are made
+// up with nearest. Factors below 2 return a copy of b.
+func Scale(b emulator.Bitplane, filter Filter, factor int) emulator.Bitplane {
+	if factor < 2 {
+		return b.Clone()
+	}
+
+	switch filter {
+	case EPX:
+		return epx(b, factor)
+	case XBR:
+		return xbr(b, factor)
+	}
+
+	return nearest(b, factor)
+}
+
+// at returns whether the pixel at (x, y) is lit, extending the edges of b
+// out past them.
+func at(b emulator.Bitplane, x, y int) bool {
+	return b.Pixel(min(max(x, 0), b.Width-1), min(max(y, 0), b.Height-1))
+}
+
+// nearest repeats every pixel of b factor times in each direction.
+func nearest(b emulator.Bitplane, factor int) emulator.Bitplane {
+	out := emulator.NewBitplane(b.Width*factor, b.Height*factor)
+	for y := range b.Height {
+		for x := range b.Width {
+			if !b.Pixel(x, y) {
+				continue
+			}
+
+			for dy := range factor {
+				for dx := range factor {
+					out.Set(x*factor+dx, y*factor+dy, true)
+				}
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,172 @@
+package upscale
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aricodes-oss/gr8/emulator"
+	"github.com/aricodes-oss/gr8/roms"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images")
+
+// draw shows b as text, one line per row, with # for lit pixels and . for
+// unlit ones.
+func draw(b emulator.Bitplane) string {
+	var s strings.Builder
+	for y := range b.Height {
+		for x := range b.Width {
+			if b.Pixel(x, y) {
+				s.WriteByte('#')
+			} else {
+				s.WriteByte('.')
+			}
+		}
+		s.WriteByte('\n')
+	}
+
+	return s.String()
+}
+
+// parse reads a bitplane drawn by draw.
+func parse(text string) emulator.Bitplane {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	b := emulator.NewBitplane(len(lines[0]), len(lines))
+	for y, line := range lines {
+		for x, ch := range line {
+			b.Set(x, y, ch == '#')
+		}
+	}
+
+	return b
+}
+
+// inputs are the bitplanes scaled by golden tests.
+func inputs(t *testing.T) map[string]emulator.Bitplane {
+	shapes, err := os.ReadFile(filepath.Join("testdata", "shapes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emu, err := emulator.NewEmulatorFromBuf(bytes.NewReader(roms.IBMLogo), emulator.DEFAULT_CLOCK_SPEED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 30 {
+		if err := emu.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return map[string]emulator.Bitplane{
+		"shapes":   parse(string(shapes)),
+		"ibm-logo": emu.Display(),
+	}
+}
+
+func TestScaleGolden(t *testing.T) {
+	for name, b := range inputs(t) {
+		for filter := range Filters {
+			for _, factor := range []int{2, 3} {
+				t.Run(fmt.Sprintf("%s/%s/%dx", name, filter, factor), func(t *testing.T) {
+					assert := assert.New(t)
+
+					f, _ := Parse(filter)
+					got := draw(Scale(b, f, factor))
+
+					path := filepath.Join("testdata", fmt.Sprintf("%s.%s.%dx.txt", name, filter, factor))
+					if *update {
+						if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+							t.Fatal(err)
+						}
+					}
+
+					want, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.Equal(string(want), got, "scaled image differs from %s", path)
+				})
+			}
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	assert := assert.New(t)
+	b := inputs(t)["shapes"]
+
+	for _, filter := range Filters {
+		for _, factor := range []int{1, 2, 3, 4, 5, 6} {
+			out := Scale(b, filter, factor)
+			assert.Equal(b.Width*factor, out.Width, "%s %dx", filter, factor)
+			assert.Equal(b.Height*factor, out.Height, "%s %dx", filter, factor)
+
+			// Only the edges of pixels are smoothed, never the middle
+			if factor%2 == 1 {
+				for y := range b.Height {
+					for x := range b.Width {
+						middle := out.Pixel(x*factor+factor/2, y*factor+factor/2)
+						assert.Equal(b.Pixel(x, y), middle, "%s %dx at (%d, %d)", filter, factor, x, y)
+					}
+				}
+			}
+		}
+	}
+
+	// Scaling doesn't change the original
+	before := draw(b)
+	Scale(b, XBR, 3)
+	assert.Equal(before, draw(b))
+}
+
+func TestScaleNearest(t *testing.T) {
+	assert := assert.New(t)
+	b := inputs(t)["shapes"]
+
+	out := Scale(b, Nearest, 3)
+	for y := range out.Height {
+		for x := range out.Width {
+			assert.Equal(b.Pixel(x/3, y/3), out.Pixel(x, y), "(%d, %d)", x, y)
+		}
+	}
+}
+
+func TestScale2x(t *testing.T) {
+	assert := assert.New(t)
+
+	// The inner corner of a staircase is filled in and its outer corner cut
+	// off, but the step sticking out at the top is left alone
+	stairs := parse("" +
+		"....\n" +
+		".#..\n" +
+		".##.\n" +
+		"....\n")
+	assert.Equal(""+
+		"........\n"+
+		"........\n"+
+		"..##....\n"+
+		"..###...\n"+
+		"..####..\n"+
+		"...###..\n"+
+		"........\n"+
+		"........\n", draw(Scale(stairs, EPX, 2)))
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := Parse("XBR")
+	assert.NoError(err)
+	assert.Equal(XBR, f)
+	assert.Equal("xbr", f.String())
+
+	_, err = Parse("hq4x")
+	assert.ErrorContains(err, `unknown filter "hq4x"`)
+}
@@ -0,0 +1,88 @@
+package upscale
+
+import "github.com/aricodes-oss/gr8/emulator"
+
+// xbr scales b by repeating every pixel, then cuts the corners of the pixels
+// that lie across diagonal edges. With one bit per pixel there's nothing to
+// blend, so a corner is either cut or not, along a line through the middle
+// of the pixel's sides, or its shallow or steep neighbours where the edge
+// runs on at half the slope.
+func xbr(b emulator.Bitplane, factor int) emulator.Bitplane {
+	out := nearest(b, factor)
+	for y := range b.Height {
+		for x := range b.Width {
+			for _, corner := range [4][2]int{{1, 1}, {-1, 1}, {1, -1}, {-1, -1}} {
+				cutCorner(out, b, x, y, corner[0], corner[1], factor)
+			}
+		}
+	}
+
+	return out
+}
+
+// cutCorner looks for an edge across the corner of the pixel at (x, y) in
+// the direction of (cx, cy), and flips the pixels of out past it. The
+// neighbourhood is mirrored so that the corner is always at the bottom
+// right:
+//
+//	   A1 B1 C1
+//	A0 A  B  C  C4
+//	D0 D  E  F  F4
+//	G0 G  H  I  I4
+//	   G5 H5 I5
+func cutCorner(out, b emulator.Bitplane, x, y, cx, cy, factor int) {
+	n := func(u, v int) bool {
+		return at(b, x+u*cx, y+v*cy)
+	}
+	B, C := n(0, -1), n(1, -1)
+	D, E, F := n(-1, 0), n(0, 0), n(1, 0)
+	G, H, I := n(-1, 1), n(0, 1), n(1, 1)
+
+	// Both neighbours beside the corner have to differ from E, which leaves
+	// them the same colour as each other
+	if E == F || E == H {
+		return
+	}
+
+	// The edge runs along H-F when E and I are more alike across it than
+	// the pixels along it are
+	along := diff(E, C) + diff(E, G) + diff(I, n(2, 0)) + diff(I, n(0, 2)) + 4*diff(H, F)
+	across := diff(H, D) + diff(H, n(1, 2)) + diff(F, n(2, 1)) + diff(F, B) + 4*diff(E, I)
+	if along >= across {
+		return
+	}
+
+	// An edge that carries on past G or C is shallow or steep
+	shallow := F == G && E != G && D != G
+	steep := H == C && E != C && B != C
+
+	for sy := range factor {
+		for sx := range factor {
+			// The centre of the pixel, in half pixels from the sides of the
+			// block away from the corner
+			u, v := 2*sx+1, 2*sy+1
+			if cx < 0 {
+				u = 2*factor - u
+			}
+			if cy < 0 {
+				v = 2*factor - v
+			}
+
+			cut := u+v >= 3*factor ||
+				shallow && u+2*v >= 4*factor ||
+				steep && 2*u+v >= 4*factor
+			if cut {
+				out.Set(x*factor+sx, y*factor+sy, F)
+			}
+		}
+	}
+}
+
+// diff returns 1 if a and b differ, or 0 otherwise.
+func diff(a, b bool) int {
+	if a != b {
+		return 1
+	}
+
+	return 0
+}